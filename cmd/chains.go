@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/checkpoint"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/server"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/txstorage"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/verify"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/pkg/parser"
+)
+
+const (
+	DefaultChainName    = "default"
+	ChainIdCheckTimeout = 10 * time.Second
+)
+
+// ChainsConfig is the content of file given by CHAINS_CONFIG
+type ChainsConfig struct {
+	Chains []ChainConfig `json:"chains"`
+}
+
+// ChainConfig is a configuration of a chain to index
+type ChainConfig struct {
+	Name string `json:"name"`
+	// ChainId is compared with eth_chainId of RPC endpoints at startup, it's not checked if zero
+	ChainId uint64   `json:"chainId"`
+	RpcUrls []string `json:"rpcUrls,omitempty"`
+	// RpcEndpoints are used instead of RpcUrls to set rate limit of each endpoint
+	RpcEndpoints    []RpcEndpointConfig `json:"rpcEndpoints,omitempty"`
+	BeginningHeight string              `json:"beginningHeight,omitempty"` // decimal or hex
+	VerifyBlocks    bool                `json:"verifyBlocks,omitempty"`
+	// SkippedTxTypes are chain-specific transaction types which can't be verified, e.g. "0x7e" of OP Stack chains
+	SkippedTxTypes []string `json:"skippedTxTypes,omitempty"`
+	WatchMempool   bool     `json:"watchMempool,omitempty"`
+	TrackBalances  bool     `json:"trackBalances,omitempty"`
+	// Retry overrides the default retry policy of block fetching
+	Retry *RetryConfig `json:"retry,omitempty"`
+}
+
+// RetryConfig is a retry policy of block fetching for transient errors
+type RetryConfig struct {
+	// MaxAttempts is the number of failures to give up at, retries infinitely if zero (default: 10)
+	MaxAttempts *int `json:"maxAttempts,omitempty"`
+	// BaseDelay is the first delay doubled at every failure (default: 1s)
+	BaseDelay string `json:"baseDelay,omitempty"`
+	// MaxDelay caps the delay (default: no cap)
+	MaxDelay string `json:"maxDelay,omitempty"`
+	// Jitter is the ratio of delay randomly subtracted in [0, 1]
+	Jitter float64 `json:"jitter,omitempty"`
+	// CircuitBreakerCooldown enables degraded state instead of termination, the node is probed at this interval
+	CircuitBreakerCooldown string `json:"circuitBreakerCooldown,omitempty"`
+	// CircuitBreakerThreshold opens the breaker after consecutive failures, opens only when retry gives up if zero
+	CircuitBreakerThreshold int `json:"circuitBreakerThreshold,omitempty"`
+}
+
+// options returns parser options for the retry config
+func (c *RetryConfig) options() ([]parser.Option, error) {
+	backoff := &parser.ExponentialBackoff{
+		Base:        parser.DefaultBackoffTime,
+		Jitter:      c.Jitter,
+		MaxAttempts: parser.MaxRetry,
+	}
+
+	if c.MaxAttempts != nil {
+		if *c.MaxAttempts < 0 {
+			return nil, errors.New("maxAttempts must not be negative")
+		}
+
+		backoff.MaxAttempts = *c.MaxAttempts
+	}
+
+	if c.Jitter < 0 || c.Jitter > 1 {
+		return nil, errors.New("jitter must be in [0, 1]")
+	}
+
+	var err error
+	if backoff.Base, err = parseDuration(c.BaseDelay, parser.DefaultBackoffTime); err != nil {
+		return nil, fmt.Errorf("failed to parse baseDelay: %w", err)
+	}
+	if backoff.Max, err = parseDuration(c.MaxDelay, 0); err != nil {
+		return nil, fmt.Errorf("failed to parse maxDelay: %w", err)
+	}
+
+	policy := parser.DefaultRetryPolicy().(parser.PerClassPolicy)
+	policy[parser.ErrorClassTransient] = backoff
+
+	opts := []parser.Option{parser.WithRetryPolicy(policy)}
+
+	if c.CircuitBreakerCooldown != "" {
+		cooldown, err := parseDuration(c.CircuitBreakerCooldown, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse circuitBreakerCooldown: %w", err)
+		}
+
+		opts = append(opts, parser.WithCircuitBreaker(parser.NewCircuitBreaker(c.CircuitBreakerThreshold, cooldown)))
+	}
+
+	return opts, nil
+}
+
+// parseDuration parses duration like "1s", returns defaultValue if empty
+func parseDuration(raw string, defaultValue time.Duration) (time.Duration, error) {
+	if raw == "" {
+		return defaultValue, nil
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	if d < 0 {
+		return 0, fmt.Errorf("duration must not be negative: %s", raw)
+	}
+
+	return d, nil
+}
+
+// RpcEndpointConfig is a JSON-RPC endpoint with its rate limit
+type RpcEndpointConfig struct {
+	Url string `json:"url"`
+	// RequestsPerSecond is the maximum rate of requests, unlimited if zero
+	RequestsPerSecond float64 `json:"requestsPerSecond,omitempty"`
+	Burst             int     `json:"burst,omitempty"`
+}
+
+// endpoints returns RPC endpoints given by either RpcEndpoints or RpcUrls
+func (c *ChainConfig) endpoints() []jsonrpc.Endpoint {
+	endpoints := make([]jsonrpc.Endpoint, 0, len(c.RpcEndpoints)+len(c.RpcUrls))
+	for _, ep := range c.RpcEndpoints {
+		endpoints = append(endpoints, jsonrpc.Endpoint{
+			Url:               ep.Url,
+			RequestsPerSecond: ep.RequestsPerSecond,
+			Burst:             ep.Burst,
+		})
+	}
+
+	for _, url := range c.RpcUrls {
+		endpoints = append(endpoints, jsonrpc.Endpoint{Url: url})
+	}
+
+	return endpoints
+}
+
+// skippedTxTypes parses transaction types not verified, which are decimal or hex like "0x7e"
+func (c *ChainConfig) skippedTxTypes() ([]uint64, error) {
+	txTypes := make([]uint64, 0, len(c.SkippedTxTypes))
+	for _, raw := range c.SkippedTxTypes {
+		// EIP-2718 transaction types are in [0, 0x7f]
+		txType, err := strconv.ParseUint(strings.TrimSpace(raw), 0, 7)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse transaction type %q: %w", raw, err)
+		}
+
+		txTypes = append(txTypes, txType)
+	}
+
+	return txTypes, nil
+}
+
+// ChainService is a set of modules running for a chain
+type ChainService struct {
+	Config ChainConfig
+	// ChainId is the one returned by RPC endpoint
+	ChainId         uint64
+	BeginningHeight *big.Int
+	Parser          *parser.Parser
+	Mempool         *parser.MempoolWatcher // nil unless mempool watching is enabled
+}
+
+// loadChainConfigs reads chains from CHAINS_CONFIG file, or builds single chain from envs if the file is not given
+func loadChainConfigs(envs *Env) ([]ChainConfig, error) {
+	if envs.ChainsConfigPath == "" {
+		if envs.JsonRpcUrl == "" {
+			return nil, fmt.Errorf("either %s or %s is required", EnvKeyJsonRpcUrl, EnvKeyChainsConfig)
+		}
+
+		beginningHeight := ""
+		if envs.BeginningHeight != nil {
+			beginningHeight = envs.BeginningHeight.String()
+		}
+
+		return []ChainConfig{{
+			Name: DefaultChainName,
+			RpcEndpoints: []RpcEndpointConfig{{
+				Url:               envs.JsonRpcUrl,
+				RequestsPerSecond: envs.JsonRpcRateLimit,
+				Burst:             int(envs.JsonRpcRateLimit),
+			}},
+			BeginningHeight: beginningHeight,
+			VerifyBlocks:    envs.VerifyBlocks,
+			SkippedTxTypes:  envs.SkippedTxTypes,
+			WatchMempool:    envs.WatchMempool,
+			TrackBalances:   envs.TrackBalances,
+			Retry:           envs.Retry,
+		}}, nil
+	}
+
+	data, err := os.ReadFile(envs.ChainsConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", envs.ChainsConfigPath, err)
+	}
+
+	config := &ChainsConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", envs.ChainsConfigPath, err)
+	}
+
+	if len(config.Chains) == 0 {
+		return nil, fmt.Errorf("no chain is configured in %s", envs.ChainsConfigPath)
+	}
+
+	names := make(map[string]bool, len(config.Chains))
+	for _, chain := range config.Chains {
+		if chain.Name == "" {
+			return nil, errors.New("chain name is required")
+		}
+		if names[chain.Name] {
+			return nil, fmt.Errorf("chain name %s is duplicated", chain.Name)
+		}
+		if len(chain.RpcUrls) == 0 && len(chain.RpcEndpoints) == 0 {
+			return nil, fmt.Errorf("rpcUrls or rpcEndpoints of chain %s is required", chain.Name)
+		}
+		for _, ep := range chain.RpcEndpoints {
+			if ep.Url == "" || ep.RequestsPerSecond < 0 {
+				return nil, fmt.Errorf("rpcEndpoints of chain %s has invalid endpoint", chain.Name)
+			}
+		}
+
+		names[chain.Name] = true
+	}
+
+	return config.Chains, nil
+}
+
+// setupChain creates modules for a chain and verifies its chain id
+func setupChain(
+	config ChainConfig,
+	storage *txstorage.MultiChainStorage,
+	checkpointDir string,
+	transport http.RoundTripper,
+) (*ChainService, error) {
+	var beginningHeight *big.Int
+	if config.BeginningHeight != "" {
+		height, ok := (&big.Int{}).SetString(config.BeginningHeight, 0)
+		if !ok {
+			return nil, fmt.Errorf("failed to parse beginning height of chain %s", config.Name)
+		}
+
+		beginningHeight = height
+	}
+
+	ethClient := jsonrpc.NewWithEndpoints(&http.Client{Transport: transport}, config.endpoints())
+
+	// make sure that every endpoint, including fallbacks, serves the expected chain
+	ctx, cancel := context.WithTimeout(context.Background(), ChainIdCheckTimeout)
+	defer cancel()
+
+	chainIds, err := ethClient.GetEndpointChainIds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch chain id of chain %s: %w", config.Name, err)
+	}
+
+	chainId := chainIds[0]
+	for i, id := range chainIds {
+		if config.ChainId != 0 && id.Uint64() != config.ChainId {
+			return nil, fmt.Errorf("chain %s expects chain id %d, but RPC endpoint #%d returns %d", config.Name, config.ChainId, i, id.Uint64())
+		}
+
+		if id.Cmp(chainId) != 0 {
+			return nil, fmt.Errorf("RPC endpoints of chain %s serve different chains, endpoint #0 returns %d, but endpoint #%d returns %d", config.Name, chainId.Uint64(), i, id.Uint64())
+		}
+	}
+
+	log.Printf("chain %s is connected, chain id=%d", config.Name, chainId.Uint64())
+
+	// parser saves checkpoint in file if directory is given, otherwise keeps in memory
+	var store parser.CheckpointStore = checkpoint.NewMemoryStore()
+	if checkpointDir != "" {
+		store = checkpoint.NewFileStore(checkpointDir, config.Name)
+	}
+
+	logger := log.New(os.Stderr, fmt.Sprintf("[%s] ", config.Name), log.LstdFlags|log.Lmsgprefix)
+
+	opts := []parser.Option{
+		parser.WithCheckpointStore(store),
+		parser.WithLogger(logger),
+	}
+	if config.VerifyBlocks {
+		skippedTxTypes, err := config.skippedTxTypes()
+		if err != nil {
+			return nil, fmt.Errorf("invalid skipped transaction types of chain %s: %w", config.Name, err)
+		}
+
+		opts = append(opts, parser.WithBlockVerifier(verify.New(
+			verify.WithLogger(logger),
+			verify.WithSkippedTxTypes(skippedTxTypes...),
+		)))
+	}
+	if config.TrackBalances {
+		opts = append(opts, parser.WithBalanceTracking(ethClient))
+	}
+	if config.Retry != nil {
+		retryOpts, err := config.Retry.options()
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry config of chain %s: %w", config.Name, err)
+		}
+
+		opts = append(opts, retryOpts...)
+	}
+
+	chainStorage := storage.ForChain(chainId.Uint64())
+	prs := parser.New(ethClient, chainStorage, opts...)
+
+	var mempool *parser.MempoolWatcher
+	if config.WatchMempool {
+		mempool = parser.NewMempoolWatcher(ethClient, prs, chainStorage)
+	}
+
+	return &ChainService{
+		Config:          config,
+		ChainId:         chainId.Uint64(),
+		BeginningHeight: beginningHeight,
+		Parser:          prs,
+		Mempool:         mempool,
+	}, nil
+}
+
+// toServerChains converts chain services to chains served by API
+func toServerChains(services []*ChainService) []server.Chain {
+	chains := make([]server.Chain, len(services))
+	for i, svc := range services {
+		chains[i] = server.Chain{
+			Name:    svc.Config.Name,
+			ChainId: svc.ChainId,
+			Parser:  svc.Parser,
+		}
+	}
+
+	return chains
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/testchain"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	CommandDev = "dev"
+
+	DefaultDevBlockTime     = 2 * time.Second
+	DefaultDevInitialHeight = 32
+)
+
+// startDevNode starts a deterministic fake Ethereum node and points JSON_RPC_URL to it
+// The aggregator then starts as usual, so it can run without network access
+func startDevNode(args []string) (*testchain.Server, error) {
+	flags := flag.NewFlagSet(CommandDev, flag.ContinueOnError)
+
+	addr := flags.String("addr", "127.0.0.1:8545", "address of JSON-RPC server of the fake node")
+	seed := flags.Uint64("seed", 1, "seed of generated chain, the same seed generates the same chain")
+	chainId := flags.Uint64("chain-id", testchain.DefaultChainId, "chain id of the fake node")
+	blockTime := flags.Duration("block-time", DefaultDevBlockTime, "interval of mining blocks")
+	initialHeight := flags.Uint64("initial-height", DefaultDevInitialHeight, "number of blocks mined before start")
+	txsPerBlock := flags.Int("txs", testchain.DefaultTxsPerBlock, "number of random transactions in each block, negative means none")
+	withdrawals := flags.Int("withdrawals", testchain.DefaultWithdrawalsPerBlock, "number of random withdrawals in each block, negative means none")
+	reorgEvery := flags.Uint64("reorg-every", 0, "replace the last blocks every n blocks, 0 disables reorgs")
+	reorgDepth := flags.Uint64("reorg-depth", 1, "number of blocks replaced by a reorg")
+	latency := flags.Duration("latency", 0, "delay of every JSON-RPC response")
+	errorRate := flags.Float64("error-rate", 0, "ratio of JSON-RPC requests failing with internal error")
+	rateLimitRate := flags.Float64("rate-limit-rate", 0, "ratio of JSON-RPC requests failing with 429 status")
+
+	var addresses addressesFlag
+	flags.Var(&addresses, "address", "address receiving random transactions and withdrawals, can be repeated")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *blockTime < time.Second {
+		return nil, fmt.Errorf("block time must be at least 1s")
+	}
+
+	if *errorRate < 0 || *rateLimitRate < 0 || *errorRate+*rateLimitRate > 1 {
+		return nil, fmt.Errorf("error rate and rate limit rate must be between 0 and 1 in total")
+	}
+
+	// timestamps of mined blocks follow wall clock
+	chain := testchain.NewChain(testchain.Config{
+		ChainId:             *chainId,
+		Seed:                *seed,
+		GenesisTime:         time.Now().Add(-time.Duration(*initialHeight) * *blockTime),
+		BlockTime:           *blockTime,
+		InitialHeight:       *initialHeight,
+		Addresses:           addresses,
+		TxsPerBlock:         *txsPerBlock,
+		WithdrawalsPerBlock: *withdrawals,
+		ReorgEvery:          *reorgEvery,
+		ReorgDepth:          *reorgDepth,
+	})
+
+	node := testchain.NewServer(
+		chain,
+		testchain.WithMiningInterval(*blockTime),
+		testchain.WithFaults(testchain.Faults{
+			Latency:       *latency,
+			ErrorRate:     *errorRate,
+			RateLimitRate: *rateLimitRate,
+			RetryAfter:    time.Second,
+		}),
+	)
+
+	if err := node.Start(*addr); err != nil {
+		return nil, err
+	}
+
+	log.Printf("fake node has started, url=%s, chainId=%d, height=%d", node.URL(), chain.ChainId(), chain.Height())
+	for _, account := range chain.Accounts() {
+		log.Printf("fake node account, address=%s, balance=%s", account, testchain.InitialBalance)
+	}
+
+	// chains given by CHAINS_CONFIG are used as they are
+	if os.Getenv(EnvKeyChainsConfig) == "" {
+		if err := os.Setenv(EnvKeyJsonRpcUrl, node.URL()); err != nil {
+			return nil, fmt.Errorf("failed to set %s: %w", EnvKeyJsonRpcUrl, err)
+		}
+	}
+
+	return node, nil
+}
+
+// addressesFlag is a repeatable flag of addresses
+type addressesFlag []types.Address
+
+func (f *addressesFlag) String() string {
+	return fmt.Sprint([]types.Address(*f))
+}
+
+func (f *addressesFlag) Set(value string) error {
+	address, err := types.ParseAddress(value)
+	if err != nil {
+		return err
+	}
+
+	*f = append(*f, address)
+
+	return nil
+}
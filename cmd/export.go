@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/export"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/server"
+)
+
+const (
+	CommandExport = "export"
+
+	DefaultApiUrl = "http://localhost:8000"
+
+	// environment variable of API key used by export command
+	EnvKeyApiKey = "API_KEY"
+)
+
+// runExport downloads transactions of an address from running API server and writes them to file or stdout
+func runExport(args []string) error {
+	flags := flag.NewFlagSet(CommandExport, flag.ContinueOnError)
+
+	apiUrl := flags.String("api", DefaultApiUrl, "URL of running API server")
+	address := flags.String("address", "", "address to export transactions for (required)")
+	rawFormat := flags.String("format", string(export.FormatCSV), "csv, ndjson, koinly or cointracker")
+	output := flags.String("out", "", "output file path (default: stdout)")
+	checksum := flags.Bool("checksum", false, "write addresses in EIP-55 checksum encoding")
+	chain := flags.String("chain", "", "name or id of chain (default: first chain)")
+	apiKey := flags.String("api-key", os.Getenv(EnvKeyApiKey), "API key if the server requires it (default: $API_KEY)")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *address == "" {
+		return fmt.Errorf("-address is required")
+	}
+
+	format, err := export.ParseFormat(*rawFormat)
+	if err != nil {
+		return err
+	}
+
+	// build request
+	path := "/transactions"
+	if *chain != "" {
+		path = "/chains/" + url.PathEscape(*chain) + path
+	}
+
+	endpoint, err := url.JoinPath(*apiUrl, path)
+	if err != nil {
+		return fmt.Errorf("failed to build API url: %w", err)
+	}
+
+	body, err := json.Marshal(&server.PostGetTransactionsRequest{Address: *address})
+	if err != nil {
+		return fmt.Errorf("failed to serialize request: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("format", string(format))
+	query.Set("checksum", strconv.FormatBool(*checksum))
+
+	req, err := http.NewRequest(http.MethodPost, endpoint+"?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if *apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+*apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call API: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API returns %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	// copy response stream to output as it arrives
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+
+		defer file.Close()
+
+		out = file
+	}
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return fmt.Errorf("failed to write exported transactions: %w", err)
+	}
+
+	return nil
+}
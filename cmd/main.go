@@ -6,55 +6,191 @@ import (
 	"fmt"
 	"log"
 	"math/big"
-	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
-	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/abi"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/auth"
 	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/server"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/testchain"
 	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/txstorage"
 	"github.com/Kourin1996/simple-go-eth-block-aggregator/pkg/parser"
 )
 
 const (
-	EnvKeyApiPort         = "API_PORT"
-	EnvKeyBeginningHeight = "BEGINNING_HEIGHT"
-	EnvKeyJsonRpcUrl      = "JSON_RPC_URL"
+	EnvKeyAbiDir                 = "ABI_DIR"
+	EnvKeyAdminApiKey            = "ADMIN_API_KEY"
+	EnvKeyApiPort                = "API_PORT"
+	EnvKeyApiRateLimitsConfig    = "API_RATE_LIMITS_CONFIG"
+	EnvKeyBeginningHeight        = "BEGINNING_HEIGHT"
+	EnvKeyChainsConfig           = "CHAINS_CONFIG"
+	EnvKeyCircuitBreakerCooldown = "CIRCUIT_BREAKER_COOLDOWN"
+	EnvKeyCheckpointDir          = "CHECKPOINT_DIR"
+	EnvKeyCrashLoopThreshold     = "CRASH_LOOP_THRESHOLD"
+	EnvKeyCrashLoopWindow        = "CRASH_LOOP_WINDOW"
+	EnvKeyJsonRpcUrl             = "JSON_RPC_URL"
+	EnvKeyJsonRpcRateLimit       = "JSON_RPC_RATE_LIMIT"
+	EnvKeyRestartDelay           = "RESTART_DELAY"
+	EnvKeyRetryMaxAttempts       = "RETRY_MAX_ATTEMPTS"
+	EnvKeyRpcRecordDir           = "RPC_RECORD_DIR"
+	EnvKeyRpcReplayDir           = "RPC_REPLAY_DIR"
+	EnvKeySkippedTxTypes         = "SKIPPED_TX_TYPES"
+	EnvKeyTrackBalances          = "TRACK_BALANCES"
+	EnvKeyVerifyBlocks           = "VERIFY_BLOCKS"
+	EnvKeyWatchMempool           = "WATCH_MEMPOOL"
 
 	DefaultApiPort uint = 8000
+
+	// tenant of API key given by ADMIN_API_KEY
+	AdminTenant = "admin"
 )
 
 func main() {
+	// run sub command if given
+	if len(os.Args) > 1 && os.Args[1] == CommandExport {
+		if err := runExport(os.Args[2:]); err != nil {
+			log.Fatalf("failed to export transactions: %v", err)
+		}
+
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == CommandOpenAPI {
+		if err := runOpenAPI(os.Args[2:]); err != nil {
+			log.Fatalf("failed to run openapi command: %v", err)
+		}
+
+		return
+	}
+
+	// start fake node before reading envs, it sets JSON_RPC_URL
+	var devNode *testchain.Server
+	if len(os.Args) > 1 && os.Args[1] == CommandDev {
+		node, err := startDevNode(os.Args[2:])
+		if err != nil {
+			log.Fatalf("failed to start fake node: %v", err)
+		}
+
+		devNode = node
+	}
+
 	// read environment variables
 	envs, err := readEnvs()
 	if err != nil {
 		log.Fatalf("failed to read some envs: %+v", err)
 	}
 
+	chainConfigs, err := loadChainConfigs(envs)
+	if err != nil {
+		log.Fatalf("failed to load chain configs: %v", err)
+	}
+
 	// create modules
-	client := &http.Client{}
-	ethClient := jsonrpc.New(client, envs.JsonRpcUrl)
+	store := txstorage.NewMultiChain()
+
+	chains := make([]*ChainService, 0, len(chainConfigs))
+	chainNames := make(map[uint64]string, len(chainConfigs))
+	recorders := make([]Stoppable, 0)
+	for _, config := range chainConfigs {
+		transport, recorder, err := rpcTransport(envs, config.Name)
+		if err != nil {
+			log.Fatalf("failed to set up JSON RPC transport: %v", err)
+		}
+
+		if recorder != nil {
+			recorders = append(recorders, recorder)
+		}
+
+		chain, err := setupChain(config, store, envs.CheckpointDir, transport)
+		if err != nil {
+			log.Fatalf("failed to set up chain: %v", err)
+		}
+
+		// storage is keyed by chain id, so two parsers must not index the same chain
+		if name, ok := chainNames[chain.ChainId]; ok {
+			log.Fatalf("chain %s and %s have the same chain id %d", name, config.Name, chain.ChainId)
+		}
+
+		chainNames[chain.ChainId] = config.Name
+		chains = append(chains, chain)
+	}
+
+	abiRegistry := abi.NewRegistry()
+	if envs.AbiDir != "" {
+		if err := abiRegistry.LoadDir(envs.AbiDir); err != nil {
+			log.Fatalf("failed to load ABIs: %v", err)
+		}
+	}
+
+	// parsers are restarted from their checkpoints on errors, the process exits only on crash loop
+	supervisor := parser.NewSupervisor(
+		parser.WithRestartDelay(envs.RestartDelay, max(envs.RestartDelay, parser.DefaultMaxRestartDelay)),
+		parser.WithCrashLoopThreshold(envs.CrashLoopThreshold, envs.CrashLoopWindow),
+	)
+	for _, chain := range chains {
+		supervisor.Add("parser/"+chain.Config.Name, chain.Parser.Supervised(chain.BeginningHeight))
+	}
+
+	serverOpts := []server.Option{
+		server.WithABIRegistry(abiRegistry),
+		server.WithChains(toServerChains(chains)...),
+		server.WithSupervisor(supervisor),
+	}
+
+	// require API keys if admin key is given, other keys are issued by admin API
+	if envs.AdminApiKey != "" {
+		keys := auth.NewStore()
+		if _, err := keys.Add(envs.AdminApiKey, AdminTenant, auth.RoleAdmin); err != nil {
+			log.Fatalf("failed to register admin API key: %v", err)
+		}
 
-	store := txstorage.New()
-	prs := parser.New(ethClient, store)
-	srv := server.New(prs, envs.ApiPort)
+		serverOpts = append(serverOpts, server.WithAuth(keys))
+	}
+
+	rateLimits, err := loadRateLimits(envs.RateLimitsPath)
+	if err != nil {
+		log.Fatalf("failed to load API rate limits: %v", err)
+	}
+
+	if rateLimits != nil {
+		serverOpts = append(serverOpts, server.WithRateLimits(*rateLimits))
+	}
+
+	// the first chain is served by routes without chain
+	srv := server.New(chains[0].Parser, envs.ApiPort, serverOpts...)
 
 	// start services
-	if err := prs.Start(envs.BeginningHeight); err != nil {
-		log.Fatalf("failed to start parser: %v", err)
+	if err := supervisor.Start(); err != nil {
+		log.Fatalf("failed to start parsers: %v", err)
+	}
+
+	ingestion := make([]Stoppable, 0, len(chains)+1)
+	ingestion = append(ingestion, supervisor)
+	for _, chain := range chains {
+		if chain.Mempool != nil {
+			chain.Mempool.Start()
+			ingestion = append(ingestion, chain.Mempool)
+		}
 	}
 
 	srv.Start()
 
 	// wait until error occurs or terminate signal is sent
-	waitForErrorOrTerminateSignal(prs, srv)
+	waitForErrorOrTerminateSignal(supervisor, srv)
 
-	// terminate services
-	if err := terminateServices([]Stoppable{prs, srv}); err != nil {
+	// terminate services in order, API stops first so that no request is served while storage is closing,
+	// parsers store blocks fetched already, then storage and JSON RPC connections are closed
+	backends := append([]Stoppable{stopFunc(func(context.Context) error { return store.Close() })}, recorders...)
+	if devNode != nil {
+		backends = append(backends, devNode)
+	}
+
+	if err := terminateServices([]Stoppable{srv}, ingestion, backends); err != nil {
 		log.Fatalf("some services failed to stop by timeout, err=%+v", err)
 	}
 
@@ -62,9 +198,26 @@ func main() {
 }
 
 type Env struct {
-	ApiPort         uint
-	BeginningHeight *big.Int
-	JsonRpcUrl      string
+	AbiDir           string
+	AdminApiKey      string // API keys are required if given
+	ApiPort          uint
+	RateLimitsPath   string // path to rate limits of API, optional
+	BeginningHeight  *big.Int
+	ChainsConfigPath string
+	CheckpointDir    string
+	// CrashLoopThreshold is the number of crashes of a parser within CrashLoopWindow to exit at, never exits if zero
+	CrashLoopThreshold int
+	CrashLoopWindow    time.Duration
+	JsonRpcUrl         string
+	JsonRpcRateLimit   float64
+	RestartDelay       time.Duration // delay before restarting a crashed parser, doubled on every crash
+	Retry              *RetryConfig  // nil unless retry envs are given
+	RpcRecordDir       string        // directory to record JSON RPC interactions, optional
+	RpcReplayDir       string        // directory to replay JSON RPC interactions from, optional
+	SkippedTxTypes     []string      // chain-specific transaction types not verified, e.g. 0x7e
+	TrackBalances      bool
+	VerifyBlocks       bool
+	WatchMempool       bool
 }
 
 // readEnvs reads environment variables, parses, and returns Env
@@ -73,6 +226,14 @@ func readEnvs() (*Env, error) {
 		port            = DefaultApiPort
 		beginningHeight *big.Int
 		jsonRpcUrl      string
+		rateLimit       float64
+		retry           *RetryConfig
+		crashLoopLimit  = parser.DefaultCrashLoopThreshold
+		crashLoopWindow = parser.DefaultCrashLoopWindow
+		restartDelay    = parser.DefaultRestartDelay
+		trackBalances   bool
+		verifyBlocks    bool
+		watchMempool    bool
 	)
 
 	// API port
@@ -97,22 +258,128 @@ func readEnvs() (*Env, error) {
 		beginningHeight = height
 	}
 
-	// JSON RPC url
+	// JSON RPC url, required unless chains are given by CHAINS_CONFIG
 	jsonRpcUrl = os.Getenv(EnvKeyJsonRpcUrl)
-	if jsonRpcUrl == "" {
-		return nil, fmt.Errorf("%s is required", EnvKeyJsonRpcUrl)
+
+	// rate limit of JSON RPC requests per second
+	rawRateLimit := os.Getenv(EnvKeyJsonRpcRateLimit)
+	if rawRateLimit != "" {
+		parsed, err := strconv.ParseFloat(rawRateLimit, 64)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("failed to parse %s: %s", EnvKeyJsonRpcRateLimit, rawRateLimit)
+		}
+
+		rateLimit = parsed
+	}
+
+	// retry policy of block fetching
+	rawMaxAttempts := os.Getenv(EnvKeyRetryMaxAttempts)
+	rawCooldown := os.Getenv(EnvKeyCircuitBreakerCooldown)
+	if rawMaxAttempts != "" || rawCooldown != "" {
+		retry = &RetryConfig{CircuitBreakerCooldown: rawCooldown}
+
+		if rawMaxAttempts != "" {
+			parsed, err := strconv.Atoi(rawMaxAttempts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", EnvKeyRetryMaxAttempts, err)
+			}
+
+			retry.MaxAttempts = &parsed
+		}
+	}
+
+	// restarts of crashed parsers
+	rawCrashLoopThreshold := os.Getenv(EnvKeyCrashLoopThreshold)
+	if rawCrashLoopThreshold != "" {
+		parsed, err := strconv.Atoi(rawCrashLoopThreshold)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("failed to parse %s: %s", EnvKeyCrashLoopThreshold, rawCrashLoopThreshold)
+		}
+
+		crashLoopLimit = parsed
+	}
+
+	var err error
+	if crashLoopWindow, err = parseDuration(os.Getenv(EnvKeyCrashLoopWindow), crashLoopWindow); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", EnvKeyCrashLoopWindow, err)
+	}
+	if restartDelay, err = parseDuration(os.Getenv(EnvKeyRestartDelay), restartDelay); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", EnvKeyRestartDelay, err)
+	}
+
+	// JSON RPC fixtures, requests are sent to network unless replayed
+	rpcRecordDir := os.Getenv(EnvKeyRpcRecordDir)
+	rpcReplayDir := os.Getenv(EnvKeyRpcReplayDir)
+	if rpcRecordDir != "" && rpcReplayDir != "" {
+		return nil, fmt.Errorf("%s and %s can't be given at the same time", EnvKeyRpcRecordDir, EnvKeyRpcReplayDir)
+	}
+
+	// balance tracking
+	rawTrackBalances := os.Getenv(EnvKeyTrackBalances)
+	if rawTrackBalances != "" {
+		parsed, err := strconv.ParseBool(rawTrackBalances)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", EnvKeyTrackBalances, err)
+		}
+
+		trackBalances = parsed
+	}
+
+	// block verification
+	rawVerifyBlocks := os.Getenv(EnvKeyVerifyBlocks)
+	if rawVerifyBlocks != "" {
+		parsed, err := strconv.ParseBool(rawVerifyBlocks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", EnvKeyVerifyBlocks, err)
+		}
+
+		verifyBlocks = parsed
+	}
+
+	// chain-specific transaction types which can't be verified
+	var skippedTxTypes []string
+	if rawSkippedTxTypes := os.Getenv(EnvKeySkippedTxTypes); rawSkippedTxTypes != "" {
+		skippedTxTypes = strings.Split(rawSkippedTxTypes, ",")
+	}
+
+	// mempool watching
+	rawWatchMempool := os.Getenv(EnvKeyWatchMempool)
+	if rawWatchMempool != "" {
+		parsed, err := strconv.ParseBool(rawWatchMempool)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", EnvKeyWatchMempool, err)
+		}
+
+		watchMempool = parsed
 	}
 
 	return &Env{
-		ApiPort:         port,
-		BeginningHeight: beginningHeight,
-		JsonRpcUrl:      jsonRpcUrl,
+		AbiDir:             os.Getenv(EnvKeyAbiDir),
+		AdminApiKey:        os.Getenv(EnvKeyAdminApiKey),
+		ApiPort:            port,
+		RateLimitsPath:     os.Getenv(EnvKeyApiRateLimitsConfig),
+		BeginningHeight:    beginningHeight,
+		ChainsConfigPath:   os.Getenv(EnvKeyChainsConfig),
+		CheckpointDir:      os.Getenv(EnvKeyCheckpointDir),
+		CrashLoopThreshold: crashLoopLimit,
+		CrashLoopWindow:    crashLoopWindow,
+		JsonRpcUrl:         jsonRpcUrl,
+		JsonRpcRateLimit:   rateLimit,
+		RestartDelay:       restartDelay,
+		Retry:              retry,
+		RpcRecordDir:       rpcRecordDir,
+		RpcReplayDir:       rpcReplayDir,
+		SkippedTxTypes:     skippedTxTypes,
+		TrackBalances:      trackBalances,
+		VerifyBlocks:       verifyBlocks,
+		WatchMempool:       watchMempool,
 	}, nil
 }
 
 // waitForErrorOrTerminateSignal waits for SIGINT (Ctrl + c), or errors from services running as a background task
+// Errors of parsers are handled by supervisor, which reports an error only when a parser is in crash loop
 func waitForErrorOrTerminateSignal(
-	p *parser.Parser,
+	supervisor *parser.Supervisor,
 	s *server.EthTransactionsServer,
 ) {
 	signalCh := make(chan os.Signal, 1)
@@ -121,7 +388,7 @@ func waitForErrorOrTerminateSignal(
 	log.Printf("awaiting termination signals")
 
 	select {
-	case err := <-p.ErrCh():
+	case err := <-supervisor.ErrCh():
 		log.Printf("parser was terminated with error: %v", err)
 	case err := <-s.ErrCh():
 		log.Printf("server was terminated with error: %v", err)
@@ -134,11 +401,32 @@ type Stoppable interface {
 	Stop(context.Context) error
 }
 
-// terminateServices calls Stop method of each service
-// and wait for them to shutdown gracefully
-func terminateServices(services []Stoppable) error {
+// stopFunc adapts a function to Stoppable
+type stopFunc func(context.Context) error
+
+func (f stopFunc) Stop(ctx context.Context) error {
+	return f(ctx)
+}
+
+// terminateServices stops stages of services in order within the timeout
+// Services in the same stage are stopped concurrently, and the next stage starts after all of them stop
+func terminateServices(stages ...[]Stoppable) error {
 	log.Printf("terminating services...")
 
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	errs := make([]error, 0)
+	for _, services := range stages {
+		errs = append(errs, stopServices(ctx, services)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// stopServices calls Stop method of each service
+// and wait for them to shutdown gracefully
+func stopServices(ctx context.Context, services []Stoppable) []error {
 	num := len(services)
 
 	var wg sync.WaitGroup
@@ -146,9 +434,6 @@ func terminateServices(services []Stoppable) error {
 
 	errCh := make(chan error, num)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
 	for _, srv := range services {
 		srv := srv
 		go func() {
@@ -162,12 +447,10 @@ func terminateServices(services []Stoppable) error {
 
 	errs := make([]error, 0, num)
 	for err := range errCh {
-		errs = append(errs, err)
-	}
-
-	if len(errs) > 0 {
-		return errors.Join(errs...)
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	return nil
+	return errs
 }
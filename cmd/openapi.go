@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/server"
+)
+
+const (
+	CommandOpenAPI = "openapi"
+)
+
+// runOpenAPI generates OpenAPI document of the server API
+func runOpenAPI(args []string) error {
+	flags := flag.NewFlagSet(CommandOpenAPI, flag.ContinueOnError)
+
+	output := flags.String("o", "", "file to write the document, stdout if empty")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	document, err := server.GenerateOpenAPIDocument()
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(document)
+		return err
+	}
+
+	return os.WriteFile(*output, document, 0o644)
+}
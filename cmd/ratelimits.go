@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/server"
+)
+
+const (
+	DefaultRateLimitWindow = time.Minute
+)
+
+// RateLimitsConfig is the content of file given by API_RATE_LIMITS_CONFIG
+type RateLimitsConfig struct {
+	// Groups maps route group (default, transactions or subscriptions) to its limit
+	Groups                 map[string]RateLimitConfig `json:"groups"`
+	DailySubscriptionQuota int                        `json:"dailySubscriptionQuota"`
+	// PerIP limits requests of each IP address before authentication, optional
+	PerIP *RateLimitConfig `json:"perIp,omitempty"`
+}
+
+// RateLimitConfig is a limit of requests per client in a window
+type RateLimitConfig struct {
+	Requests int    `json:"requests"`
+	Window   string `json:"window"` // e.g. 1m (default: 1m)
+}
+
+// loadRateLimits reads API rate limits from file, nil is returned if path is empty
+func loadRateLimits(path string) (*server.RateLimitConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	config := &RateLimitsConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if config.DailySubscriptionQuota < 0 {
+		return nil, fmt.Errorf("dailySubscriptionQuota must not be negative")
+	}
+
+	limits := &server.RateLimitConfig{
+		Groups:                 make(map[string]server.RateLimit, len(config.Groups)),
+		DailySubscriptionQuota: config.DailySubscriptionQuota,
+	}
+
+	for group, limit := range config.Groups {
+		switch group {
+		case server.RateLimitGroupDefault, server.RateLimitGroupTransactions, server.RateLimitGroupSubscriptions:
+		default:
+			return nil, fmt.Errorf("unknown rate limit group: %s", group)
+		}
+
+		if limits.Groups[group], err = limit.parse(group); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.PerIP != nil {
+		if limits.PerIP, err = config.PerIP.parse("perIp"); err != nil {
+			return nil, err
+		}
+	}
+
+	return limits, nil
+}
+
+// parse validates the limit of given name and returns it
+func (c *RateLimitConfig) parse(name string) (server.RateLimit, error) {
+	window, err := parseDuration(c.Window, DefaultRateLimitWindow)
+	if err != nil {
+		return server.RateLimit{}, fmt.Errorf("failed to parse window of %s: %w", name, err)
+	}
+
+	if c.Requests <= 0 || window < time.Second {
+		return server.RateLimit{}, fmt.Errorf("requests of %s must be positive and window must be at least 1s", name)
+	}
+
+	return server.RateLimit{
+		Requests: c.Requests,
+		Window:   window,
+	}, nil
+}
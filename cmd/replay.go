@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/rpcfixture"
+)
+
+const (
+	// extension of fixture files in RPC_RECORD_DIR and RPC_REPLAY_DIR
+	FixtureFileExt = ".ndjson"
+)
+
+// rpcTransport returns transport of JSON-RPC client of the chain
+// Requests are recorded to or replayed from <dir>/<chain>.ndjson if RPC_RECORD_DIR or RPC_REPLAY_DIR is given
+// Recorder is returned to be closed on termination, it's nil unless requests are recorded
+func rpcTransport(envs *Env, chain string) (http.RoundTripper, *rpcfixture.Recorder, error) {
+	switch {
+	case envs.RpcRecordDir != "":
+		recorder, err := rpcfixture.NewRecorder(filepath.Join(envs.RpcRecordDir, chain+FixtureFileExt), nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create recorder of chain %s: %w", chain, err)
+		}
+
+		return recorder, recorder, nil
+	case envs.RpcReplayDir != "":
+		replayer, err := rpcfixture.LoadReplayer(filepath.Join(envs.RpcReplayDir, chain+FixtureFileExt))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load fixture of chain %s: %w", chain, err)
+		}
+
+		return replayer, nil, nil
+	default:
+		return http.DefaultTransport, nil, nil
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/Kourin1996/simple-go-eth-block-aggregator
 
-go 1.21
+go 1.22
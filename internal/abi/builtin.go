@@ -0,0 +1,62 @@
+package abi
+
+// builtinSignatures are signatures of commonly used methods, used when ABI of the contract isn't registered
+var builtinSignatures = []string{
+	// ERC-20
+	"transfer(address to,uint256 amount)",
+	"approve(address spender,uint256 amount)",
+	"transferFrom(address from,address to,uint256 amount)",
+	"increaseAllowance(address spender,uint256 addedValue)",
+	"decreaseAllowance(address spender,uint256 subtractedValue)",
+
+	// ERC-721 and ERC-1155
+	"safeTransferFrom(address from,address to,uint256 tokenId)",
+	"safeTransferFrom(address from,address to,uint256 tokenId,bytes data)",
+	"safeTransferFrom(address from,address to,uint256 id,uint256 amount,bytes data)",
+	"safeBatchTransferFrom(address from,address to,uint256[] ids,uint256[] amounts,bytes data)",
+	"setApprovalForAll(address operator,bool approved)",
+
+	// WETH
+	"deposit()",
+	"withdraw(uint256 amount)",
+
+	// Uniswap V2 router
+	"swapExactTokensForTokens(uint256 amountIn,uint256 amountOutMin,address[] path,address to,uint256 deadline)",
+	"swapTokensForExactTokens(uint256 amountOut,uint256 amountInMax,address[] path,address to,uint256 deadline)",
+	"swapExactETHForTokens(uint256 amountOutMin,address[] path,address to,uint256 deadline)",
+	"swapTokensForExactETH(uint256 amountOut,uint256 amountInMax,address[] path,address to,uint256 deadline)",
+	"swapExactTokensForETH(uint256 amountIn,uint256 amountOutMin,address[] path,address to,uint256 deadline)",
+	"swapETHForExactTokens(uint256 amountOut,address[] path,address to,uint256 deadline)",
+	"addLiquidity(address tokenA,address tokenB,uint256 amountADesired,uint256 amountBDesired,uint256 amountAMin,uint256 amountBMin,address to,uint256 deadline)",
+	"addLiquidityETH(address token,uint256 amountTokenDesired,uint256 amountTokenMin,uint256 amountETHMin,address to,uint256 deadline)",
+	"removeLiquidity(address tokenA,address tokenB,uint256 liquidity,uint256 amountAMin,uint256 amountBMin,address to,uint256 deadline)",
+	"removeLiquidityETH(address token,uint256 liquidity,uint256 amountTokenMin,uint256 amountETHMin,address to,uint256 deadline)",
+
+	// Uniswap V3 router
+	"exactInputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160) params)",
+	"exactInput((bytes,address,uint256,uint256,uint256) params)",
+	"exactOutputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160) params)",
+	"exactOutput((bytes,address,uint256,uint256,uint256) params)",
+	"multicall(bytes[] data)",
+	"multicall(uint256 deadline,bytes[] data)",
+
+	// Uniswap universal router
+	"execute(bytes commands,bytes[] inputs)",
+	"execute(bytes commands,bytes[] inputs,uint256 deadline)",
+}
+
+// builtinMethods returns methods of builtin signatures keyed by selector
+func builtinMethods() map[Selector]*Method {
+	methods := make(map[Selector]*Method, len(builtinSignatures))
+	for _, signature := range builtinSignatures {
+		method, err := ParseSignature(signature)
+		if err != nil {
+			// signatures are constant, so it never happens unless the list is broken
+			panic(err)
+		}
+
+		methods[method.Selector] = method
+	}
+
+	return methods
+}
@@ -0,0 +1,69 @@
+package abi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/crypto"
+)
+
+// builtinSelectors are well known selectors of builtin signatures in canonical form
+var builtinSelectors = map[string]string{
+	"transfer(address,uint256)":                                                           "a9059cbb",
+	"approve(address,uint256)":                                                            "095ea7b3",
+	"transferFrom(address,address,uint256)":                                               "23b872dd",
+	"increaseAllowance(address,uint256)":                                                  "39509351",
+	"decreaseAllowance(address,uint256)":                                                  "a457c2d7",
+	"safeTransferFrom(address,address,uint256)":                                           "42842e0e",
+	"safeTransferFrom(address,address,uint256,bytes)":                                     "b88d4fde",
+	"safeTransferFrom(address,address,uint256,uint256,bytes)":                             "f242432a",
+	"safeBatchTransferFrom(address,address,uint256[],uint256[],bytes)":                    "2eb2c2d6",
+	"setApprovalForAll(address,bool)":                                                     "a22cb465",
+	"deposit()":                                                                           "d0e30db0",
+	"withdraw(uint256)":                                                                   "2e1a7d4d",
+	"swapExactTokensForTokens(uint256,uint256,address[],address,uint256)":                 "38ed1739",
+	"swapTokensForExactTokens(uint256,uint256,address[],address,uint256)":                 "8803dbee",
+	"swapExactETHForTokens(uint256,address[],address,uint256)":                            "7ff36ab5",
+	"swapTokensForExactETH(uint256,uint256,address[],address,uint256)":                    "4a25d94a",
+	"swapExactTokensForETH(uint256,uint256,address[],address,uint256)":                    "18cbafe5",
+	"swapETHForExactTokens(uint256,address[],address,uint256)":                            "fb3bdb41",
+	"addLiquidity(address,address,uint256,uint256,uint256,uint256,address,uint256)":       "e8e33700",
+	"addLiquidityETH(address,uint256,uint256,uint256,address,uint256)":                    "f305d719",
+	"removeLiquidity(address,address,uint256,uint256,uint256,address,uint256)":            "baa2abde",
+	"removeLiquidityETH(address,uint256,uint256,uint256,address,uint256)":                 "02751cec",
+	"exactInputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160))":  "414bf389",
+	"exactInput((bytes,address,uint256,uint256,uint256))":                                 "c04b8d59",
+	"exactOutputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160))": "db3e2198",
+	"exactOutput((bytes,address,uint256,uint256,uint256))":                                "f28c0498",
+	"multicall(bytes[])":                                                                  "ac9650d8",
+	"multicall(uint256,bytes[])":                                                          "5ae401dc",
+	"execute(bytes,bytes[])":                                                              "24856bc3",
+	"execute(bytes,bytes[],uint256)":                                                      "3593564c",
+}
+
+func TestBuiltinSelectors(t *testing.T) {
+	methods := builtinMethods()
+	if len(methods) != len(builtinSignatures) {
+		t.Fatalf("expected %d builtin methods, but got %d, selectors may collide", len(builtinSignatures), len(methods))
+	}
+
+	for selector, method := range methods {
+		signature := method.Signature()
+
+		// selector is the first 4 bytes of keccak256 of canonical signature
+		if hash := crypto.Keccak256([]byte(signature)); !bytes.Equal(selector[:], hash[:SelectorLength]) {
+			t.Errorf("expected selector of %s to be %x, but got %s", signature, hash[:SelectorLength], selector)
+		}
+
+		expected, ok := builtinSelectors[signature]
+		if !ok {
+			t.Errorf("unexpected builtin signature %s", signature)
+			continue
+		}
+
+		if actual := hex.EncodeToString(selector[:]); actual != expected {
+			t.Errorf("expected selector of %s to be %s, but got %s", signature, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package abi
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+var ErrMalformedData = errors.New("malformed ABI encoded data")
+
+// DecodedArgument is a decoded argument of method call
+type DecodedArgument struct {
+	Name  string      `json:"name,omitempty"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// DecodeInputs decodes call data following the selector
+func (m *Method) DecodeInputs(data []byte) ([]DecodedArgument, error) {
+	return decodeTuple(m.Inputs, data)
+}
+
+// decodeTuple decodes values encoded as tuple, data starts from the head of the tuple
+func decodeTuple(args []Argument, data []byte) ([]DecodedArgument, error) {
+	decoded := make([]DecodedArgument, len(args))
+
+	head := 0
+	for i, arg := range args {
+		var (
+			value interface{}
+			err   error
+		)
+
+		if arg.Type.IsDynamic() {
+			offset, offsetErr := readLength(data, head)
+			if offsetErr != nil {
+				return nil, offsetErr
+			}
+
+			value, err = decodeValue(arg.Type, data, offset)
+		} else {
+			value, err = decodeValue(arg.Type, data, head)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", arg.Type, err)
+		}
+
+		decoded[i] = DecodedArgument{Name: arg.Name, Type: arg.Type.String(), Value: value}
+		head += arg.Type.headSize()
+	}
+
+	return decoded, nil
+}
+
+// decodeValue decodes a value of given type at given position of data
+func decodeValue(t *Type, data []byte, pos int) (interface{}, error) {
+	switch t.Kind {
+	case KindUint, KindInt, KindAddress, KindBool, KindFixedBytes:
+		word, err := readWord(data, pos)
+		if err != nil {
+			return nil, err
+		}
+
+		return decodeStatic(t, word), nil
+	case KindBytes, KindString:
+		length, err := readLength(data, pos)
+		if err != nil {
+			return nil, err
+		}
+
+		start := pos + wordSize
+		if length > len(data)-start {
+			return nil, ErrMalformedData
+		}
+
+		content := data[start : start+length]
+		if t.Kind == KindString {
+			return string(content), nil
+		}
+
+		return "0x" + hex.EncodeToString(content), nil
+	case KindSlice:
+		length, err := readLength(data, pos)
+		if err != nil {
+			return nil, err
+		}
+
+		// each element takes its head size at least, so the length is bounded by data size
+		start := pos + wordSize
+		elemSize := max(t.Elem.headSize(), 1)
+		if start > len(data) || length > (len(data)-start)/elemSize {
+			return nil, ErrMalformedData
+		}
+
+		return decodeList(t.Elem, length, data[start:])
+	case KindArray:
+		return decodeList(t.Elem, t.Size, data[pos:])
+	case KindTuple:
+		args, err := decodeTuple(t.Components, data[pos:])
+		if err != nil {
+			return nil, err
+		}
+
+		return tupleValue(args), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+// decodeList decodes elements of array, which are encoded as tuple
+func decodeList(elem *Type, length int, data []byte) ([]interface{}, error) {
+	args := make([]Argument, length)
+	for i := range args {
+		args[i] = Argument{Type: elem}
+	}
+
+	decoded, err := decodeTuple(args, data)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, length)
+	for i, d := range decoded {
+		values[i] = d.Value
+	}
+
+	return values, nil
+}
+
+// decodeStatic decodes a value fitting into one word
+func decodeStatic(t *Type, word []byte) interface{} {
+	switch t.Kind {
+	case KindUint:
+		return new(big.Int).SetBytes(word).String()
+	case KindInt:
+		value := new(big.Int).SetBytes(word)
+		if word[0]&0x80 != 0 {
+			// two's complement
+			value.Sub(value, new(big.Int).Lsh(big.NewInt(1), wordSize*8))
+		}
+
+		return value.String()
+	case KindAddress:
+		return types.Address("0x" + hex.EncodeToString(word[wordSize-types.AddressLength:]))
+	case KindBool:
+		return word[wordSize-1] != 0
+	default:
+		return "0x" + hex.EncodeToString(word[:t.Size])
+	}
+}
+
+// tupleValue returns tuple as object if all components are named, otherwise as list
+func tupleValue(args []DecodedArgument) interface{} {
+	named := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		if arg.Name == "" {
+			return args
+		}
+
+		named[arg.Name] = arg.Value
+	}
+
+	return named
+}
+
+// readWord returns 32 bytes at given position
+func readWord(data []byte, pos int) ([]byte, error) {
+	if pos < 0 || pos > len(data)-wordSize {
+		return nil, ErrMalformedData
+	}
+
+	return data[pos : pos+wordSize], nil
+}
+
+// readLength reads a word at given position as length or offset
+func readLength(data []byte, pos int) (int, error) {
+	word, err := readWord(data, pos)
+	if err != nil {
+		return 0, err
+	}
+
+	value := new(big.Int).SetBytes(word)
+	if !value.IsInt64() || value.Int64() > int64(len(data)) {
+		return 0, ErrMalformedData
+	}
+
+	return int(value.Int64()), nil
+}
@@ -0,0 +1,163 @@
+package abi
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	usdt    = types.Address("0xdac17f958d2ee523a2206206994597c13d831ec7")
+	binance = types.Address("0x28c6c06298d514db089934071355e5743bf21d60")
+	holder  = types.Address("0x5041ed759dd4afc3a72b8192c143f72f4724081a")
+	router  = types.Address("0x7a250d5630b4cf539739df2c5dacb4c659f2488d")
+	weth    = types.Address("0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2")
+	usdc    = types.Address("0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48")
+
+	maxUint256 = "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+)
+
+// word returns hex of 32 bytes word padding given hex on the left
+func word(s string) string {
+	s = strings.TrimPrefix(s, "0x")
+
+	return strings.Repeat("0", 2*wordSize-len(s)) + s
+}
+
+// calldata returns transaction input from selector and words in hex
+func calldata(selector string, words ...string) types.Data {
+	for i, w := range words {
+		words[i] = word(w)
+	}
+
+	return types.Data("0x" + selector + strings.Join(words, ""))
+}
+
+// mainnetCalls are inputs of ERC-20 and Uniswap V2 calls as sent on Ethereum mainnet
+var mainnetCalls = []struct {
+	name      string
+	to        types.Address
+	input     types.Data
+	signature string
+	args      []DecodedArgument
+}{
+	{
+		name:      "transfer",
+		to:        usdt,
+		input:     calldata("a9059cbb", string(binance), "f4240"),
+		signature: "transfer(address,uint256)",
+		args: []DecodedArgument{
+			{Name: "to", Type: "address", Value: binance},
+			{Name: "amount", Type: "uint256", Value: "1000000"},
+		},
+	},
+	{
+		name:      "transferFrom",
+		to:        usdt,
+		input:     calldata("23b872dd", string(holder), string(binance), "2540be400"),
+		signature: "transferFrom(address,address,uint256)",
+		args: []DecodedArgument{
+			{Name: "from", Type: "address", Value: holder},
+			{Name: "to", Type: "address", Value: binance},
+			{Name: "amount", Type: "uint256", Value: "10000000000"},
+		},
+	},
+	{
+		name:      "approve",
+		to:        usdt,
+		input:     calldata("095ea7b3", string(router), strings.Repeat("f", 64)),
+		signature: "approve(address,uint256)",
+		args: []DecodedArgument{
+			{Name: "spender", Type: "address", Value: router},
+			{Name: "amount", Type: "uint256", Value: maxUint256},
+		},
+	},
+	{
+		name:      "swapExactETHForTokens",
+		to:        router,
+		input:     calldata("7ff36ab5", "3b9aca00", "80", string(holder), "665f3c00", "2", string(weth), string(usdc)),
+		signature: "swapExactETHForTokens(uint256,address[],address,uint256)",
+		args: []DecodedArgument{
+			{Name: "amountOutMin", Type: "uint256", Value: "1000000000"},
+			{Name: "path", Type: "address[]", Value: []interface{}{weth, usdc}},
+			{Name: "to", Type: "address", Value: holder},
+			{Name: "deadline", Type: "uint256", Value: "1717517312"},
+		},
+	},
+}
+
+func TestDecodeMainnetCalls(t *testing.T) {
+	registry := NewRegistry()
+
+	for _, tt := range mainnetCalls {
+		t.Run(tt.name, func(t *testing.T) {
+			call := registry.Decode(tt.to, tt.input)
+			if call == nil {
+				t.Fatalf("expected %s to be decoded", tt.input)
+			}
+
+			if call.Method != tt.name || call.Signature != tt.signature || call.Source != SourceBuiltin ||
+				call.Selector != string(tt.input[:2+2*SelectorLength]) {
+				t.Fatalf("expected %s by builtin selector, but got %+v", tt.signature, call)
+			}
+
+			if !reflect.DeepEqual(call.Args, tt.args) {
+				t.Fatalf("expected args %+v, but got %+v", tt.args, call.Args)
+			}
+		})
+	}
+}
+
+func TestDecodeTruncatedCalls(t *testing.T) {
+	registry := NewRegistry()
+
+	for _, tt := range mainnetCalls {
+		data := tt.input.Bytes()
+
+		// every prefix is rejected without panic
+		for length := 0; length < len(data); length++ {
+			input := types.Data("0x" + hex.EncodeToString(data[:length]))
+			if call := registry.Decode(tt.to, input); call != nil {
+				t.Fatalf("expected %s truncated to %d bytes not to be decoded, but got %+v", tt.name, length, call)
+			}
+		}
+	}
+}
+
+func TestDecodeMalformedCalls(t *testing.T) {
+	swap, err := ParseSignature("swapExactETHForTokens(uint256 amountOutMin,address[] path,address to,uint256 deadline)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	execute, err := ParseSignature("execute(bytes commands,bytes[] inputs)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		method *Method
+		words  []string
+	}{
+		{"offset beyond data", swap, []string{"1", "1000", string(holder), "1", "0"}},
+		{"offset overflowing int64", swap, []string{"1", strings.Repeat("f", 64), string(holder), "1", "0"}},
+		{"misaligned offset", swap, []string{"1", "81", string(holder), "1", "2", string(weth), string(usdc)}},
+		{"length beyond data", swap, []string{"1", "80", string(holder), "1", "3", string(weth), string(usdc)}},
+		{"huge length", swap, []string{"1", "80", string(holder), "1", "8000000000000000"}},
+		{"bytes beyond data", execute, []string{"40", "80", "21", "0"}},
+		{"nested offset beyond data", execute, []string{"40", "60", "0", "1", "1000"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := calldata("", tt.words...).Bytes()
+
+			if args, err := tt.method.DecodeInputs(data); !errors.Is(err, ErrMalformedData) {
+				t.Fatalf("expected %v, but got args=%+v, err=%v", ErrMalformedData, args, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package abi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/crypto"
+)
+
+// SelectorLength is the length of method selector in bytes
+const SelectorLength = 4
+
+// Selector is the first 4 bytes of keccak256 hash of method signature
+type Selector [SelectorLength]byte
+
+// String returns selector in hex
+func (s Selector) String() string {
+	return fmt.Sprintf("0x%x", s[:])
+}
+
+// Method is a contract function which can be called by transaction
+type Method struct {
+	Name     string
+	Inputs   []Argument
+	Selector Selector
+}
+
+// jsonEntry is an entry of ABI JSON
+type jsonEntry struct {
+	Type   string         `json:"type"`
+	Name   string         `json:"name"`
+	Inputs []jsonArgument `json:"inputs"`
+}
+
+// NewMethod creates Method and computes its selector
+func NewMethod(name string, inputs []Argument) *Method {
+	m := &Method{Name: name, Inputs: inputs}
+	copy(m.Selector[:], crypto.Keccak256([]byte(m.Signature())))
+
+	return m
+}
+
+// Signature returns canonical method signature such as transfer(address,uint256)
+func (m *Method) Signature() string {
+	types := make([]string, len(m.Inputs))
+	for i, arg := range m.Inputs {
+		types[i] = arg.Type.String()
+	}
+
+	return m.Name + "(" + strings.Join(types, ",") + ")"
+}
+
+// ParseABI parses contract ABI in JSON and returns its functions
+// Both plain ABI array and artifact object having abi field (e.g. Hardhat or Truffle) are accepted
+func ParseABI(data []byte) ([]*Method, error) {
+	var entries []jsonEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var artifact struct {
+			ABI []jsonEntry `json:"abi"`
+		}
+		if artifactErr := json.Unmarshal(data, &artifact); artifactErr != nil || artifact.ABI == nil {
+			return nil, fmt.Errorf("failed to parse ABI JSON: %w", err)
+		}
+
+		entries = artifact.ABI
+	}
+
+	methods := make([]*Method, 0, len(entries))
+	for _, entry := range entries {
+		// type is function if omitted
+		if entry.Type != "" && entry.Type != "function" {
+			continue
+		}
+
+		inputs := make([]Argument, len(entry.Inputs))
+		for i, input := range entry.Inputs {
+			arg, err := parseJSONArgument(input)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse input of %s: %w", entry.Name, err)
+			}
+
+			inputs[i] = arg
+		}
+
+		methods = append(methods, NewMethod(entry.Name, inputs))
+	}
+
+	return methods, nil
+}
+
+// ParseSignature parses method signature such as transfer(address,uint256)
+// Parameter names can follow types, e.g. transfer(address to,uint256 amount)
+func ParseSignature(signature string) (*Method, error) {
+	open := strings.Index(signature, "(")
+	if open <= 0 || !strings.HasSuffix(signature, ")") {
+		return nil, fmt.Errorf("invalid method signature %q", signature)
+	}
+
+	params, err := splitTopLevel(signature[open+1 : len(signature)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([]Argument, len(params))
+	for i, param := range params {
+		param = strings.TrimSpace(param)
+
+		// split name after type, type may contain spaces only inside of tuple
+		name := ""
+		if idx := strings.LastIndex(param, " "); idx > strings.LastIndex(param, ")") {
+			param, name = param[:idx], param[idx+1:]
+		}
+
+		typ, err := ParseType(param)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %w", signature, err)
+		}
+
+		inputs[i] = Argument{Name: name, Type: typ}
+	}
+
+	return NewMethod(signature[:open], inputs), nil
+}
@@ -0,0 +1,135 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	SourceABI     = "abi"
+	SourceBuiltin = "builtin"
+)
+
+// DecodedCall is a decoded transaction input
+type DecodedCall struct {
+	Method    string            `json:"method"`
+	Signature string            `json:"signature"`
+	Selector  string            `json:"selector"`
+	Source    string            `json:"source"` // abi or builtin
+	Args      []DecodedArgument `json:"args"`
+}
+
+// Registry keeps contract ABIs registered for addresses and decodes transaction inputs
+type Registry struct {
+	contracts map[types.Address]map[Selector]*Method // Address in lower case -> Selector -> Method
+	builtin   map[Selector]*Method
+
+	mutex sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		contracts: make(map[types.Address]map[Selector]*Method),
+		builtin:   builtinMethods(),
+	}
+}
+
+// Register parses ABI JSON and associates its functions with given contract address
+// ABI registered for the same address before is replaced
+func (r *Registry) Register(address types.Address, abiJSON []byte) error {
+	methods, err := ParseABI(abiJSON)
+	if err != nil {
+		return err
+	}
+
+	bySelector := make(map[Selector]*Method, len(methods))
+	for _, m := range methods {
+		bySelector[m.Selector] = m
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.contracts[address.Lower()] = bySelector
+
+	return nil
+}
+
+// LoadDir registers ABI files in given directory, file name must be contract address, e.g. 0x1234...abcd.json
+func (r *Registry) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, 0)
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+		address, err := types.NormalizeAddress(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("file name of %s is not an address: %w", path, err))
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := r.Register(address, data); err != nil {
+			errs = append(errs, fmt.Errorf("failed to register %s: %w", path, err))
+			continue
+		}
+
+		log.Printf("registered ABI, address=%s, file=%s", address, path)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Decode decodes transaction input sent to given address
+// It uses ABI registered for the address and falls back to builtin selectors, returns nil if no method matches
+func (r *Registry) Decode(to types.Address, input types.Data) *DecodedCall {
+	data := input.Bytes()
+	if to.IsEmpty() || len(data) < SelectorLength {
+		return nil
+	}
+
+	var selector Selector
+	copy(selector[:], data)
+
+	r.mutex.RLock()
+	method, source := r.contracts[to.Lower()][selector], SourceABI
+	r.mutex.RUnlock()
+
+	if method == nil {
+		method, source = r.builtin[selector], SourceBuiltin
+	}
+
+	if method == nil {
+		return nil
+	}
+
+	args, err := method.DecodeInputs(data[SelectorLength:])
+	if err != nil {
+		// selector collision or broken input, leave it undecoded
+		return nil
+	}
+
+	return &DecodedCall{
+		Method:    method.Name,
+		Signature: method.Signature(),
+		Selector:  selector.String(),
+		Source:    source,
+		Args:      args,
+	}
+}
@@ -0,0 +1,309 @@
+package abi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kind is a kind of Solidity ABI type
+type Kind int
+
+const (
+	KindUint Kind = iota
+	KindInt
+	KindAddress
+	KindBool
+	KindFixedBytes
+	KindBytes
+	KindString
+	KindSlice
+	KindArray
+	KindTuple
+)
+
+// wordSize is the size of a slot in ABI encoding
+const wordSize = 32
+
+// Type is a parsed Solidity ABI type
+type Type struct {
+	Kind Kind
+	// Size is bit size for integers, byte size for fixed bytes, and length for fixed arrays
+	Size int
+	// Elem is the element type of arrays
+	Elem *Type
+	// Components are the fields of tuple
+	Components []Argument
+}
+
+// Argument is a named parameter of method or tuple
+type Argument struct {
+	Name string
+	Type *Type
+}
+
+// jsonArgument is an argument in ABI JSON
+type jsonArgument struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Components []jsonArgument `json:"components"`
+}
+
+// String returns canonical type name used in method signature
+func (t *Type) String() string {
+	switch t.Kind {
+	case KindUint:
+		return fmt.Sprintf("uint%d", t.Size)
+	case KindInt:
+		return fmt.Sprintf("int%d", t.Size)
+	case KindAddress:
+		return "address"
+	case KindBool:
+		return "bool"
+	case KindFixedBytes:
+		return fmt.Sprintf("bytes%d", t.Size)
+	case KindBytes:
+		return "bytes"
+	case KindString:
+		return "string"
+	case KindSlice:
+		return t.Elem.String() + "[]"
+	case KindArray:
+		return fmt.Sprintf("%s[%d]", t.Elem.String(), t.Size)
+	case KindTuple:
+		names := make([]string, len(t.Components))
+		for i, c := range t.Components {
+			names[i] = c.Type.String()
+		}
+
+		return "(" + strings.Join(names, ",") + ")"
+	default:
+		return "unknown"
+	}
+}
+
+// IsDynamic returns true if the value is encoded at tail and referred by offset
+func (t *Type) IsDynamic() bool {
+	switch t.Kind {
+	case KindBytes, KindString, KindSlice:
+		return true
+	case KindArray:
+		return t.Elem.IsDynamic()
+	case KindTuple:
+		for _, c := range t.Components {
+			if c.Type.IsDynamic() {
+				return true
+			}
+		}
+
+		return false
+	default:
+		return false
+	}
+}
+
+// headSize returns the number of bytes the value takes in head part of enclosing tuple
+func (t *Type) headSize() int {
+	if t.IsDynamic() {
+		return wordSize
+	}
+
+	switch t.Kind {
+	case KindArray:
+		return t.Size * t.Elem.headSize()
+	case KindTuple:
+		size := 0
+		for _, c := range t.Components {
+			size += c.Type.headSize()
+		}
+
+		return size
+	default:
+		return wordSize
+	}
+}
+
+// parseJSONArgument parses an argument in ABI JSON
+func parseJSONArgument(arg jsonArgument) (Argument, error) {
+	components := make([]Argument, len(arg.Components))
+	for i, c := range arg.Components {
+		parsed, err := parseJSONArgument(c)
+		if err != nil {
+			return Argument{}, err
+		}
+
+		components[i] = parsed
+	}
+
+	typ, err := parseType(arg.Type, components)
+	if err != nil {
+		return Argument{}, err
+	}
+
+	return Argument{Name: arg.Name, Type: typ}, nil
+}
+
+// ParseType parses type name in signature such as uint256, address[] or (address,uint256)[]
+func ParseType(s string) (*Type, error) {
+	s = strings.TrimSpace(s)
+
+	// inline tuple is converted to tuple type with unnamed components
+	if strings.HasPrefix(s, "(") {
+		end := matchingParen(s)
+		if end < 0 {
+			return nil, fmt.Errorf("unbalanced parenthesis in type %q", s)
+		}
+
+		elems, err := splitTopLevel(s[1:end])
+		if err != nil {
+			return nil, err
+		}
+
+		components := make([]Argument, len(elems))
+		for i, elem := range elems {
+			typ, err := ParseType(elem)
+			if err != nil {
+				return nil, err
+			}
+
+			components[i] = Argument{Type: typ}
+		}
+
+		return parseType("tuple"+s[end+1:], components)
+	}
+
+	return parseType(s, nil)
+}
+
+// parseType parses type name, components are used for tuple
+func parseType(s string, components []Argument) (*Type, error) {
+	// array
+	if strings.HasSuffix(s, "]") {
+		open := strings.LastIndex(s, "[")
+		if open < 0 {
+			return nil, fmt.Errorf("invalid array type %q", s)
+		}
+
+		elem, err := parseType(s[:open], components)
+		if err != nil {
+			return nil, err
+		}
+
+		length := s[open+1 : len(s)-1]
+		if length == "" {
+			return &Type{Kind: KindSlice, Elem: elem}, nil
+		}
+
+		n, err := strconv.Atoi(length)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid array length in type %q", s)
+		}
+
+		return &Type{Kind: KindArray, Size: n, Elem: elem}, nil
+	}
+
+	switch {
+	case s == "tuple":
+		return &Type{Kind: KindTuple, Components: components}, nil
+	case s == "address":
+		return &Type{Kind: KindAddress}, nil
+	case s == "bool":
+		return &Type{Kind: KindBool}, nil
+	case s == "string":
+		return &Type{Kind: KindString}, nil
+	case s == "bytes":
+		return &Type{Kind: KindBytes}, nil
+	case s == "function":
+		// address and selector
+		return &Type{Kind: KindFixedBytes, Size: 24}, nil
+	case strings.HasPrefix(s, "uint"):
+		size, err := parseSize(s, "uint", 256, 8, 256)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Type{Kind: KindUint, Size: size}, nil
+	case strings.HasPrefix(s, "int"):
+		size, err := parseSize(s, "int", 256, 8, 256)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Type{Kind: KindInt, Size: size}, nil
+	case strings.HasPrefix(s, "bytes"):
+		size, err := parseSize(s, "bytes", 0, 1, 32)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Type{Kind: KindFixedBytes, Size: size}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %q", s)
+	}
+}
+
+// parseSize parses size suffix of type name such as 256 in uint256
+func parseSize(s, prefix string, defaultSize, min, max int) (int, error) {
+	raw := strings.TrimPrefix(s, prefix)
+	if raw == "" && defaultSize > 0 {
+		return defaultSize, nil
+	}
+
+	size, err := strconv.Atoi(raw)
+	if err != nil || size < min || size > max {
+		return 0, fmt.Errorf("invalid size in type %q", s)
+	}
+
+	return size, nil
+}
+
+// matchingParen returns the index of parenthesis closing the first one
+func matchingParen(s string) int {
+	depth := 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// splitTopLevel splits comma separated list without splitting inside of parentheses
+func splitTopLevel(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var parts []string
+
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parenthesis in %q", s)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parenthesis in %q", s)
+	}
+
+	return append(parts, s[start:]), nil
+}
@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// KeyPrefix is the prefix of generated API keys, which makes leaked keys easy to find
+	KeyPrefix = "agk_"
+
+	// number of random bytes in generated API keys
+	keySecretLength = 32
+	// number of random bytes in key ids
+	keyIdLength = 8
+)
+
+var (
+	ErrInvalidKey   = errors.New("invalid API key")
+	ErrKeyNotFound  = errors.New("API key is not found")
+	ErrEmptyTenant  = errors.New("tenant must not be empty")
+	ErrInvalidRole  = errors.New("invalid role")
+	ErrDuplicateKey = errors.New("API key already exists")
+)
+
+// Role is a role granted to an API key
+type Role string
+
+const (
+	// RoleTenant can subscribe addresses and read transactions of addresses subscribed by its tenant
+	RoleTenant Role = "tenant"
+	// RoleAdmin can read all indexed data and manage API keys in addition to RoleTenant
+	RoleAdmin Role = "admin"
+)
+
+// ParseRole parses role given by user, empty string means RoleTenant
+func ParseRole(s string) (Role, error) {
+	switch r := Role(strings.ToLower(s)); r {
+	case "":
+		return RoleTenant, nil
+	case RoleTenant, RoleAdmin:
+		return r, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidRole, s)
+	}
+}
+
+// Key is an API key issued for a tenant, its secret is kept only as hash
+type Key struct {
+	Id        string     `json:"id"`
+	Tenant    string     `json:"tenant"`
+	Role      Role       `json:"role"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// IsAdmin returns true if the key has admin role
+func (k *Key) IsAdmin() bool {
+	return k.Role == RoleAdmin
+}
+
+// Store keeps API keys in memory
+type Store struct {
+	keysByHash map[string]*Key // sha256 of secret in hex -> key
+	keysById   map[string]*Key
+
+	mutex sync.RWMutex
+}
+
+func NewStore() *Store {
+	return &Store{
+		keysByHash: make(map[string]*Key),
+		keysById:   make(map[string]*Key),
+	}
+}
+
+// Create issues a new API key for the tenant and returns it with its secret
+// The secret can't be recovered later
+func (s *Store) Create(tenant string, role Role) (*Key, string, error) {
+	secret, err := randomHex(keySecretLength)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret = KeyPrefix + secret
+
+	key, err := s.Add(secret, tenant, role)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+// Add registers given secret as an API key, which is used for keys configured by operators
+func (s *Store) Add(secret string, tenant string, role Role) (*Key, error) {
+	if tenant == "" {
+		return nil, ErrEmptyTenant
+	}
+
+	if role != RoleTenant && role != RoleAdmin {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRole, role)
+	}
+
+	id, err := randomHex(keyIdLength)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{
+		Id:        id,
+		Tenant:    tenant,
+		Role:      role,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	hash := hashSecret(secret)
+	if _, ok := s.keysByHash[hash]; ok {
+		return nil, ErrDuplicateKey
+	}
+
+	s.keysByHash[hash] = key
+	s.keysById[id] = key
+
+	copied := *key
+
+	return &copied, nil
+}
+
+// Authenticate returns the key of given secret, revoked keys are rejected
+func (s *Store) Authenticate(secret string) (*Key, error) {
+	if secret == "" {
+		return nil, ErrInvalidKey
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	// secrets are looked up by hash so that timing doesn't depend on the content of secret
+	key, ok := s.keysByHash[hashSecret(secret)]
+	if !ok || key.RevokedAt != nil {
+		return nil, ErrInvalidKey
+	}
+
+	copied := *key
+
+	return &copied, nil
+}
+
+// Revoke disables the key of given id
+func (s *Store) Revoke(id string) (*Key, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key, ok := s.keysById[id]
+	if !ok || key.RevokedAt != nil {
+		return nil, ErrKeyNotFound
+	}
+
+	now := time.Now().UTC()
+	key.RevokedAt = &now
+
+	copied := *key
+
+	return &copied, nil
+}
+
+// List returns all keys including revoked ones in created order
+func (s *Store) List() []Key {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	keys := make([]Key, 0, len(s.keysById))
+	for _, key := range s.keysById {
+		keys = append(keys, *key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+
+		return keys[i].Id < keys[j].Id
+	})
+
+	return keys
+}
+
+// hashSecret returns sha256 hash of the secret in hex
+func hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// randomHex returns random bytes of given length in hex
+func randomHex(length int) (string, error) {
+	buf := make([]byte, length)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSecretIsStoredOnlyAsHash(t *testing.T) {
+	store := NewStore()
+
+	key, secret, err := store.Create("a", RoleTenant)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(secret, KeyPrefix) || len(secret) != len(KeyPrefix)+2*keySecretLength {
+		t.Fatalf("expected secret of %d random bytes with prefix %s, but got %q", keySecretLength, KeyPrefix, secret)
+	}
+
+	configured := "operator-secret"
+	if _, err := store.Add(configured, "b", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, plain := range []string{secret, configured} {
+		if _, ok := store.keysByHash[plain]; ok {
+			t.Fatalf("expected secret %q not to be stored in plain text", plain)
+		}
+
+		if hash := hashSecret(plain); hash == plain || len(hash) != 64 {
+			t.Fatalf("expected sha256 hash of secret, but got %q", hash)
+		}
+
+		if _, ok := store.keysByHash[hashSecret(plain)]; !ok {
+			t.Fatalf("expected key to be stored by hash of secret %q", plain)
+		}
+	}
+
+	// listed keys never include secrets
+	encoded, err := json.Marshal(store.List())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(encoded), secret) || strings.Contains(string(encoded), configured) ||
+		strings.Contains(string(encoded), hashSecret(secret)) {
+		t.Fatalf("expected listed keys not to include secrets, but got %s", encoded)
+	}
+
+	if authenticated, err := store.Authenticate(secret); err != nil || authenticated.Id != key.Id {
+		t.Fatalf("expected key %s to be authenticated, but got key=%+v, err=%v", key.Id, authenticated, err)
+	}
+}
+
+func TestAuthenticateRejectsInvalidKeys(t *testing.T) {
+	store := NewStore()
+
+	key, secret, err := store.Create("a", RoleTenant)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Add(secret, "b", RoleTenant); !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("expected %v, but got %v", ErrDuplicateKey, err)
+	}
+
+	if _, err := store.Revoke(key.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, invalid := range []string{"", secret, hashSecret(secret), KeyPrefix + "unknown"} {
+		if _, err := store.Authenticate(invalid); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("expected %v for %q, but got %v", ErrInvalidKey, invalid, err)
+		}
+	}
+
+	if _, err := store.Revoke(key.Id); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected revoked key not to be revoked again, but got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Checkpoint is the progress of block processing
+type Checkpoint struct {
+	// Height is the height of the last block whose transactions have been stored
+	Height    uint64    `json:"height"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FileStore saves checkpoint in a JSON file so that processing can be resumed after restart
+type FileStore struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewFileStore creates a store saving checkpoint of given name (e.g. chain name) in dir
+func NewFileStore(dir, name string) *FileStore {
+	return &FileStore{
+		path: filepath.Join(dir, name+".json"),
+	}
+}
+
+// Load returns saved height, ok is false if no checkpoint has been saved yet
+func (s *FileStore) Load() (uint64, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return 0, false, fmt.Errorf("failed to parse checkpoint %s: %w", s.path, err)
+	}
+
+	return cp.Height, true, nil
+}
+
+// Save writes height to file atomically by renaming temporary file
+func (s *FileStore) Save(height uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := json.Marshal(&Checkpoint{Height: height, UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// MemoryStore keeps checkpoint in memory, which survives restarts of parser but not of process
+type MemoryStore struct {
+	height uint64
+	saved  bool
+	mutex  sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Load returns saved height, ok is false if no checkpoint has been saved yet
+func (s *MemoryStore) Load() (uint64, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.height, s.saved, nil
+}
+
+// Save keeps height
+func (s *MemoryStore) Save(height uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.height, s.saved = height, true
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+const (
+	// Keccak256Size is the size of Keccak-256 digest in bytes
+	Keccak256Size = 32
+
+	// keccak256Rate is the number of bytes absorbed per permutation (1600 - 2 * 256 bits)
+	keccak256Rate = 136
+)
+
+// roundConstants are the constants of iota step in Keccak-f[1600]
+var roundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// rotationOffsets are the offsets of rho step indexed by x + 5y
+var rotationOffsets = [25]int{
+	0, 1, 62, 28, 27,
+	36, 44, 6, 55, 20,
+	3, 10, 43, 25, 39,
+	41, 45, 15, 21, 8,
+	18, 2, 61, 56, 14,
+}
+
+// Keccak256 returns Keccak-256 digest of given data, which is used in Ethereum
+// Note that this is the original Keccak padding, not SHA3-256 standardized in FIPS 202
+func Keccak256(data ...[]byte) []byte {
+	h := NewKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+
+	return h.Sum(nil)
+}
+
+// keccak256 is a sponge state of Keccak-256
+type keccak256 struct {
+	state  [25]uint64
+	buffer [keccak256Rate]byte
+	offset int
+}
+
+// NewKeccak256 creates hash.Hash computing Keccak-256
+func NewKeccak256() hash.Hash {
+	return &keccak256{}
+}
+
+func (k *keccak256) Write(p []byte) (int, error) {
+	written := len(p)
+
+	for len(p) > 0 {
+		n := copy(k.buffer[k.offset:], p)
+		k.offset += n
+		p = p[n:]
+
+		if k.offset == keccak256Rate {
+			k.absorb()
+		}
+	}
+
+	return written, nil
+}
+
+func (k *keccak256) Sum(b []byte) []byte {
+	// work on a copy so that caller can keep writing
+	dup := *k
+
+	// pad10*1 with Keccak domain bit
+	for i := dup.offset; i < keccak256Rate; i++ {
+		dup.buffer[i] = 0
+	}
+	dup.buffer[dup.offset] ^= 0x01
+	dup.buffer[keccak256Rate-1] ^= 0x80
+	dup.absorb()
+
+	var out [Keccak256Size]byte
+	for i := 0; i < Keccak256Size/8; i++ {
+		binary.LittleEndian.PutUint64(out[i*8:], dup.state[i])
+	}
+
+	return append(b, out[:]...)
+}
+
+func (k *keccak256) Reset() {
+	*k = keccak256{}
+}
+
+func (k *keccak256) Size() int {
+	return Keccak256Size
+}
+
+func (k *keccak256) BlockSize() int {
+	return keccak256Rate
+}
+
+// absorb xors buffered block into state and applies permutation
+func (k *keccak256) absorb() {
+	for i := 0; i < keccak256Rate/8; i++ {
+		k.state[i] ^= binary.LittleEndian.Uint64(k.buffer[i*8:])
+	}
+
+	keccakF1600(&k.state)
+	k.offset = 0
+}
+
+// keccakF1600 applies Keccak-f[1600] permutation to the state
+func keccakF1600(a *[25]uint64) {
+	var c, d [5]uint64
+	var b [25]uint64
+
+	for round := 0; round < 24; round++ {
+		// theta
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d[x] = c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+		}
+		for i := 0; i < 25; i++ {
+			a[i] ^= d[i%5]
+		}
+
+		// rho and pi
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(a[x+5*y], rotationOffsets[x+5*y])
+			}
+		}
+
+		// chi
+		for y := 0; y < 5; y++ {
+			for x := 0; x < 5; x++ {
+				a[x+5*y] = b[x+5*y] ^ (^b[(x+1)%5+5*y] & b[(x+2)%5+5*y])
+			}
+		}
+
+		// iota
+		a[0] ^= roundConstants[round]
+	}
+}
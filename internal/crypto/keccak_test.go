@@ -0,0 +1,109 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// mainnetGenesisHeader is RLP encoded header of Ethereum mainnet genesis block, which is longer than the rate
+var mainnetGenesisHeader = strings.Join([]string{
+	"f90214",
+	"a0" + strings.Repeat("00", 32), // parentHash
+	"a01dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347", // sha3Uncles
+	"94" + strings.Repeat("00", 20),                                      // miner
+	"a0d7f8974fb5ac78d9ac099b9ad5018bedc2ce0a72dad1827a1709da30580f0544", // stateRoot
+	"a056e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421", // transactionsRoot
+	"a056e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421", // receiptsRoot
+	"b90100" + strings.Repeat("00", 256),                                 // logsBloom
+	"850400000000",                                                       // difficulty
+	"80",                                                                 // number
+	"821388",                                                             // gasLimit
+	"80",                                                                 // gasUsed
+	"80",                                                                 // timestamp
+	"a011bbe8db4e347b4e8c937c1c8370e4b5ed33adb3db69cbdb7a38e1e50b1b82fa", // extraData
+	"a0" + strings.Repeat("00", 32),                                      // mixHash
+	"880000000000000042",                                                 // nonce
+}, "")
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestKeccak256(t *testing.T) {
+	genesis := decodeHex(t, mainnetGenesisHeader)
+	if len(genesis) <= 3*keccak256Rate {
+		t.Fatalf("expected header longer than 3 blocks, but got %d bytes", len(genesis))
+	}
+
+	for _, tt := range []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"empty", nil, "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", []byte("abc"), "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		{"empty list", []byte{0xc0}, "1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347"},
+		{"empty string", []byte{0x80}, "56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"},
+		{"quick brown fox", []byte("The quick brown fox jumps over the lazy dog"), "4d741b6f1eb29cb2a9b9911c82f56fa8d73b04959d3d9d222895df6c0b28aa15"},
+		{"mainnet genesis header", genesis, "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"},
+	} {
+		if actual := hex.EncodeToString(Keccak256(tt.data)); actual != tt.expected {
+			t.Errorf("expected hash of %s to be %s, but got %s", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestKeccak256OfSplitWrites(t *testing.T) {
+	genesis := decodeHex(t, mainnetGenesisHeader)
+	expected := Keccak256(genesis)
+
+	// writes split around the rate give the same digest as a single write
+	for _, size := range []int{1, 7, keccak256Rate - 1, keccak256Rate, keccak256Rate + 1, 2 * keccak256Rate} {
+		h := NewKeccak256()
+		for data := genesis; len(data) > 0; {
+			n := min(size, len(data))
+			h.Write(data[:n])
+			data = data[n:]
+		}
+
+		if actual := h.Sum(nil); !bytes.Equal(actual, expected) {
+			t.Errorf("expected %x with writes of %d bytes, but got %x", expected, size, actual)
+		}
+	}
+
+	// inputs of the exact rate and its multiple are padded in another block
+	for _, length := range []int{keccak256Rate - 1, keccak256Rate, keccak256Rate + 1, 2 * keccak256Rate} {
+		if bytes.Equal(Keccak256(genesis[:length]), Keccak256(genesis[:length+1])) {
+			t.Errorf("expected different digests of %d and %d bytes", length, length+1)
+		}
+	}
+}
+
+func TestKeccak256SumDoesNotChangeState(t *testing.T) {
+	h := NewKeccak256()
+	h.Write([]byte("a"))
+
+	first := h.Sum(nil)
+	if !bytes.Equal(h.Sum(nil), first) {
+		t.Fatal("expected Sum to return the same digest twice")
+	}
+
+	h.Write([]byte("bc"))
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45" {
+		t.Fatalf("expected digest of abc after Sum, but got %s", actual)
+	}
+
+	h.Reset()
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470" {
+		t.Fatalf("expected digest of empty input after Reset, but got %s", actual)
+	}
+}
@@ -0,0 +1,756 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/checkpoint"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/server"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/testchain"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/pkg/parser"
+)
+
+// quietChain is a chain without random transactions and withdrawals, so that scenarios control its contents
+var quietChain = testchain.Config{
+	Seed:                1,
+	Accounts:            4,
+	TxsPerBlock:         -1,
+	WithdrawalsPerBlock: -1,
+}
+
+// TestSubscribeBeforeBlock checks that a transaction of an address subscribed before its block is indexed
+func TestSubscribeBeforeBlock(t *testing.T) {
+	h := newHarness(t, Config{Chain: quietChain})
+
+	alice, bob := h.Chain.Accounts()[0], h.Chain.Accounts()[1]
+
+	if err := h.subscribe(alice); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.StartParser(0); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := h.Chain.Send(alice, bob, big.NewInt(1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := h.Chain.Mine()
+	if err := h.WaitForHeight(block.Number.Big().Uint64()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.expectTransactions(alice, tx.Hash); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSubscribeAfterBlock checks that subscriptions don't backfill blocks processed already
+func TestSubscribeAfterBlock(t *testing.T) {
+	h := newHarness(t, Config{Chain: quietChain})
+
+	alice, bob := h.Chain.Accounts()[0], h.Chain.Accounts()[1]
+
+	if err := h.StartParser(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.Chain.Send(alice, bob, big.NewInt(1), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	block := h.Chain.Mine()
+	if err := h.WaitForHeight(block.Number.Big().Uint64()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the block has been processed before subscription
+	if err := h.subscribe(bob); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.expectTransactions(bob); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := h.Chain.Send(alice, bob, big.NewInt(2), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block = h.Chain.Mine()
+	if err := h.WaitForHeight(block.Number.Big().Uint64()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.expectTransactions(bob, tx.Hash); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestStopWithInflightFetch checks that Stop cancels a slow block request instead of waiting for it
+func TestStopWithInflightFetch(t *testing.T) {
+	chain := quietChain
+	chain.InitialHeight = 3
+
+	h := newHarness(t, Config{Chain: chain})
+
+	h.Node.SetFaults(testchain.Faults{Latency: 5 * time.Second})
+
+	if err := h.StartParser(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WaitFor(DefaultWaitTimeout, func() bool {
+		return h.Node.Calls(jsonrpc.MethodEthGetBlockByNumber) > 0
+	}, "block request"); err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+	if err := h.StopParser(time.Second); err != nil {
+		t.Fatalf("parser didn't stop with in-flight request: %v", err)
+	}
+
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Fatalf("parser took %s to stop", elapsed)
+	}
+
+	select {
+	case err := <-h.Parser.ErrCh():
+		t.Fatalf("parser reported error on stop: %v", err)
+	default:
+	}
+
+	if height := h.Parser.GetCurrentBlock(); height != 0 {
+		t.Fatalf("no block should be processed, but current block is %d", height)
+	}
+}
+
+// TestStopDrainsFetchedBlocks checks that Stop stores the block being stored and the one waiting for it with checkpoint
+func TestStopDrainsFetchedBlocks(t *testing.T) {
+	store := checkpoint.NewMemoryStore()
+
+	h := newHarness(t, Config{
+		Chain:         quietChain,
+		TrackBalances: true,
+		ParserOptions: []parser.Option{parser.WithCheckpointStore(store)},
+	})
+
+	alice, bob := h.Chain.Accounts()[0], h.Chain.Accounts()[1]
+
+	if err := h.subscribe(alice); err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := make([]types.Hash, 0, 2)
+	for i := 0; i < 2; i++ {
+		tx, err := h.Chain.Send(alice, bob, big.NewInt(1), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hashes = append(hashes, tx.Hash)
+		h.Chain.Mine()
+	}
+
+	// storing a block takes a while because balances are refreshed slowly
+	h.Node.SetFaults(testchain.Faults{
+		Latency: 300 * time.Millisecond,
+		Methods: []string{jsonrpc.MethodEthGetBalance, jsonrpc.MethodEthGetTransactionCount},
+	})
+
+	if err := h.StartParser(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// block 1 is being stored, block 2 is waiting for it, and block 3 is not mined
+	if err := WaitFor(DefaultWaitTimeout, func() bool {
+		return h.Node.Calls(jsonrpc.MethodEthGetBalance) > 0 && h.Node.Calls(jsonrpc.MethodEthGetBlockByNumber) >= 3
+	}, "blocks to be fetched"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.StopParser(5 * time.Second); err != nil {
+		t.Fatalf("parser didn't stop: %v", err)
+	}
+
+	if height := h.Parser.GetCurrentBlock(); height != 2 {
+		t.Fatalf("expected blocks up to 2 to be stored, but current block is %d", height)
+	}
+
+	if saved, ok, err := store.Load(); err != nil || !ok || saved != 2 {
+		t.Fatalf("expected checkpoint 2, but got %d (ok=%t, err=%v)", saved, ok, err)
+	}
+
+	if err := h.expectTransactions(alice, hashes...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMaxRetryExceeded checks that the parser terminates with error and reports failed health after MaxRetry failures
+func TestMaxRetryExceeded(t *testing.T) {
+	h := newHarness(t, Config{
+		Chain: quietChain,
+		ParserOptions: []parser.Option{
+			parser.WithRetryPolicy(parser.PerClassPolicy{
+				parser.ErrorClassTransient: &parser.ExponentialBackoff{Base: time.Millisecond, MaxAttempts: parser.MaxRetry},
+			}),
+		},
+	})
+
+	h.Node.SetFaults(testchain.Faults{ErrorRate: 1, Methods: []string{jsonrpc.MethodEthGetBlockByNumber}})
+
+	if err := h.StartParser(0); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-h.Parser.ErrCh():
+		if !strings.Contains(err.Error(), fmt.Sprintf("after %d attempts", parser.MaxRetry)) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(DefaultWaitTimeout):
+		t.Fatal("parser didn't give up")
+	}
+
+	if calls := h.Node.Calls(jsonrpc.MethodEthGetBlockByNumber); calls != parser.MaxRetry {
+		t.Fatalf("expected %d block requests, but got %d", parser.MaxRetry, calls)
+	}
+
+	health := &server.GetHealthResponse{}
+	status, err := h.Get("/v1/health", health)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if health.Status != parser.HealthStateFailed || status < 500 {
+		t.Fatalf("expected failed health with 5xx status, but got %s with %d", health.Status, status)
+	}
+}
+
+// TestRestartFromCheckpoint checks that a restarted parser resumes from the block next to the checkpoint
+// Transactions in memory are lost by restart, and blocks before the checkpoint are not fetched again
+func TestRestartFromCheckpoint(t *testing.T) {
+	store := checkpoint.NewMemoryStore()
+
+	first := newHarness(t, Config{Chain: quietChain, ParserOptions: []parser.Option{parser.WithCheckpointStore(store)}})
+
+	alice, bob := first.Chain.Accounts()[0], first.Chain.Accounts()[1]
+
+	if err := first.subscribe(alice); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := first.StartParser(0); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := first.Chain.Send(alice, bob, big.NewInt(1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := first.Chain.Mine()
+	if err := first.WaitForHeight(block.Number.Big().Uint64()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := first.expectTransactions(alice, before.Hash); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := first.StopParser(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, ok, err := store.Load()
+	if err != nil || !ok || saved != block.Number.Big().Uint64() {
+		t.Fatalf("expected checkpoint %s, but got %d (ok=%t, err=%v)", block.Number, saved, ok, err)
+	}
+
+	// restart with the same node and checkpoint, beginning height is ignored
+	after, err := first.Chain.Send(alice, bob, big.NewInt(2), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block = first.Chain.Mine()
+
+	second := newHarness(t, Config{Node: first.Node, ParserOptions: []parser.Option{parser.WithCheckpointStore(store)}})
+
+	if err := second.subscribe(alice); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := second.StartParser(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if height := uint64(second.Parser.GetCurrentBlock()); height != saved {
+		t.Fatalf("restarted parser should start at checkpoint %d, but current block is %d", saved, height)
+	}
+
+	if err := second.WaitForHeight(block.Number.Big().Uint64()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := second.expectTransactions(alice, after.Hash); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// giveUpQuickly is a retry policy which makes the parser crash after two failures in a row
+var giveUpQuickly = parser.WithRetryPolicy(parser.PerClassPolicy{
+	parser.ErrorClassTransient: &parser.ExponentialBackoff{Base: time.Millisecond, MaxAttempts: 2},
+})
+
+// TestSupervisorRestartsCrashedParser checks that a crashed parser is restarted from the next block of the last processed one
+func TestSupervisorRestartsCrashedParser(t *testing.T) {
+	h := newHarness(t, Config{
+		Chain:         quietChain,
+		ParserOptions: []parser.Option{giveUpQuickly},
+		SupervisorOptions: []parser.SupervisorOption{
+			parser.WithRestartDelay(50*time.Millisecond, time.Second),
+			parser.WithCrashLoopThreshold(3, time.Minute),
+		},
+	})
+
+	alice, bob := h.Chain.Accounts()[0], h.Chain.Accounts()[1]
+
+	if err := h.subscribe(alice); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.StartParser(1); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := h.Chain.Send(alice, bob, big.NewInt(1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := h.Chain.Mine()
+	if err := h.WaitForHeight(block.Number.Big().Uint64()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the parser gives up polling the next block
+	h.Node.InjectFailures(2, testchain.InternalError())
+
+	if err := WaitFor(DefaultWaitTimeout, func() bool {
+		components := h.Supervisor.Components()
+		return components[0].Restarts == 1 && components[0].State == parser.ComponentStateRunning
+	}, "parser to be restarted"); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := h.Chain.Send(alice, bob, big.NewInt(2), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block = h.Chain.Mine()
+	if err := h.WaitForHeight(block.Number.Big().Uint64()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.expectTransactions(alice, before.Hash, after.Hash); err != nil {
+		t.Fatal(err)
+	}
+
+	response := &server.GetComponentsResponse{}
+	if status, err := h.Get("/v1/components", response); err != nil || status != 200 {
+		t.Fatalf("failed to get components, status=%d, err=%v", status, err)
+	}
+
+	if len(response.Components) != 1 || len(response.Components[0].Crashes) != 1 {
+		t.Fatalf("expected a crash of parser, but got %+v", response.Components)
+	}
+
+	select {
+	case err := <-h.Supervisor.ErrCh():
+		t.Fatalf("supervisor reported error after restart: %v", err)
+	default:
+	}
+}
+
+// TestSupervisorGivesUpOnCrashLoop checks that the supervisor reports error once the parser crashes threshold times
+func TestSupervisorGivesUpOnCrashLoop(t *testing.T) {
+	h := newHarness(t, Config{
+		Chain:         quietChain,
+		ParserOptions: []parser.Option{giveUpQuickly},
+		SupervisorOptions: []parser.SupervisorOption{
+			parser.WithRestartDelay(10*time.Millisecond, time.Second),
+			parser.WithCrashLoopThreshold(3, time.Minute),
+		},
+	})
+
+	h.Node.SetFaults(testchain.Faults{ErrorRate: 1, Methods: []string{jsonrpc.MethodEthGetBlockByNumber}})
+
+	if err := h.StartParser(0); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-h.Supervisor.ErrCh():
+		if !strings.Contains(err.Error(), "crashed 3 times") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(DefaultWaitTimeout):
+		t.Fatal("supervisor didn't give up")
+	}
+
+	component := h.Supervisor.Components()[0]
+	if component.State != parser.ComponentStateFailed || component.Restarts != 2 || len(component.Crashes) != 3 {
+		t.Fatalf("expected failed parser restarted twice, but got %+v", component)
+	}
+
+	// 2 attempts in each of 3 runs
+	if calls := h.Node.Calls(jsonrpc.MethodEthGetBlockByNumber); calls != 6 {
+		t.Fatalf("expected 6 block requests, but got %d", calls)
+	}
+}
+
+// TestAdminPauseRewindReindex checks that admin API pauses ingestion, rewinds without duplicates and reindexes an address
+func TestAdminPauseRewindReindex(t *testing.T) {
+	h := newHarness(t, Config{Chain: quietChain, AdminKey: "admin-secret"})
+
+	alice, bob := h.Chain.Accounts()[0], h.Chain.Accounts()[1]
+
+	if err := h.subscribe(alice); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.StartParser(1); err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := make([]types.Hash, 0, 3)
+	for i := 0; i < 2; i++ {
+		tx, err := h.Chain.Send(alice, bob, big.NewInt(1), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hashes = append(hashes, tx.Hash)
+		h.Chain.Mine()
+	}
+
+	if err := h.WaitForHeight(2); err != nil {
+		t.Fatal(err)
+	}
+
+	// blocks mined while paused are not ingested
+	ingestion := &server.IngestionResponse{}
+	if status, err := h.Post("/v1/admin/ingestion/pause", nil, ingestion); err != nil || status != 200 || !ingestion.Paused {
+		t.Fatalf("failed to pause, status=%d, paused=%t, err=%v", status, ingestion.Paused, err)
+	}
+
+	// a poll in progress may fetch the next block before pausing
+	time.Sleep(2 * DefaultPollingInterval)
+
+	tx, err := h.Chain.Send(alice, bob, big.NewInt(1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes = append(hashes, tx.Hash)
+	h.Chain.Mine()
+
+	time.Sleep(5 * DefaultPollingInterval)
+
+	if height := h.Parser.GetCurrentBlock(); height != 2 {
+		t.Fatalf("paused parser should stay at block 2, but current block is %d", height)
+	}
+
+	// rewind to block 2, which is ingested again after resume
+	seek := &server.PostSeekResponse{}
+	if status, err := h.Post("/v1/admin/ingestion/seek", &server.PostSeekRequest{Height: 2}, seek); err != nil || status != 200 {
+		t.Fatalf("failed to seek, status=%d, err=%v", status, err)
+	}
+
+	if seek.Deleted != 1 || seek.NextHeight == nil || *seek.NextHeight != 2 {
+		t.Fatalf("expected 1 deleted transaction and next height 2, but got %+v", seek)
+	}
+
+	if err := h.expectTransactions(alice, hashes[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, err := h.Post("/v1/admin/ingestion/resume", nil, ingestion); err != nil || status != 200 || ingestion.Paused {
+		t.Fatalf("failed to resume, status=%d, paused=%t, err=%v", status, ingestion.Paused, err)
+	}
+
+	if err := h.WaitForHeight(3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.expectTransactions(alice, hashes...); err != nil {
+		t.Fatal(err)
+	}
+
+	// bob is not subscribed, reindexing matches his transactions as well
+	if status, err := h.Post("/v1/admin/reindex", &server.PostReindexRequest{From: 4, To: 4}, nil); err != nil || status != 400 {
+		t.Fatalf("reindexing unprocessed block should fail with 400, but got status=%d, err=%v", status, err)
+	}
+
+	reindex := &parser.ReindexStatus{}
+	if status, err := h.Post("/v1/admin/reindex", &server.PostReindexRequest{From: 1, To: 3, Address: string(bob)}, reindex); err != nil || status != 202 {
+		t.Fatalf("failed to start reindex, status=%d, err=%v", status, err)
+	}
+
+	if err := WaitFor(DefaultWaitTimeout, func() bool {
+		_, err := h.Get("/v1/admin/reindex", reindex)
+		return err == nil && reindex.State != parser.ReindexStateRunning
+	}, "reindex to finish"); err != nil {
+		t.Fatal(err)
+	}
+
+	if reindex.State != parser.ReindexStateCompleted || reindex.Indexed != 3 || reindex.NextHeight != 4 {
+		t.Fatalf("expected reindex of 3 transactions completed, but got %+v", reindex)
+	}
+
+	for _, hash := range hashes {
+		stored, ok := h.Storage.GetTransactionByHash(hash)
+		if !ok || len(stored.MatchedAddresses) != 2 {
+			t.Fatalf("expected transaction %s matched to alice and bob, but got %+v", hash, stored)
+		}
+	}
+
+	if err := h.expectTransactions(alice, hashes...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestBlockMetadataAndTimeRange checks that transactions have timestamp, fee and block summary,
+// and that they are filtered by time range of blocks
+func TestBlockMetadataAndTimeRange(t *testing.T) {
+	h := newHarness(t, Config{Chain: quietChain})
+
+	alice, bob := h.Chain.Accounts()[0], h.Chain.Accounts()[1]
+
+	if err := h.subscribe(alice); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.StartParser(0); err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := make([]*types.Block, 2)
+	txs := make([]*types.Transaction, 2)
+	for i := range blocks {
+		tx, err := h.Chain.Send(alice, bob, big.NewInt(int64(i+1)), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		txs[i] = tx
+
+		blocks[i] = h.Chain.Mine()
+	}
+
+	if err := h.WaitForHeight(blocks[1].Number.Uint64()); err != nil {
+		t.Fatal(err)
+	}
+
+	path := "/v1/addresses/" + string(alice) + "/transactions"
+	response := &server.PostGetTransactionsResponse{}
+	if _, err := h.Get(path, response); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Transactions) != len(txs) {
+		t.Fatalf("expected %d transactions, but got %d", len(txs), len(response.Transactions))
+	}
+
+	for i, tx := range response.Transactions {
+		header := types.NewBlockHeader(blocks[i])
+		fee := types.NewQuantity(new(big.Int).Mul(txs[i].GasPrice.Big(), txs[i].Gas.Big()))
+
+		if tx.Timestamp == nil || !tx.Timestamp.Equal(header.Time()) {
+			t.Fatalf("expected timestamp %s of %s, but got %v", header.Time(), tx.Hash, tx.Timestamp)
+		}
+
+		if tx.MaxFee != fee {
+			t.Fatalf("expected fee %s of %s, but got %s", fee, tx.Hash, tx.MaxFee)
+		}
+
+		if tx.Block == nil || tx.Block.BaseFeePerGas != header.BaseFeePerGas || !tx.Block.Miner.Equal(header.Miner) {
+			t.Fatalf("expected block summary %+v of %s, but got %+v", header, tx.Hash, tx.Block)
+		}
+	}
+
+	// the second block is at or after since, and the first one is before until
+	boundary := types.NewBlockHeader(blocks[1]).Time().Format(time.RFC3339)
+	for query, expected := range map[string]types.Hash{"since": txs[1].Hash, "until": txs[0].Hash} {
+		response := &server.PostGetTransactionsResponse{}
+		if _, err := h.Get(path+"?"+query+"="+boundary, response); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(response.Transactions) != 1 || !response.Transactions[0].Hash.Equal(expected) {
+			t.Fatalf("expected only %s with %s=%s, but got %+v", expected, query, boundary, response.Transactions)
+		}
+	}
+}
+
+// TestAddressAnalytics checks aggregates of an address over block and time ranges, and that rewinding doesn't count
+// transactions twice
+func TestAddressAnalytics(t *testing.T) {
+	h := newHarness(t, Config{Chain: quietChain})
+
+	alice, bob, carol := h.Chain.Accounts()[0], h.Chain.Accounts()[1], h.Chain.Accounts()[2]
+
+	if err := h.subscribe(alice); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.StartParser(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// alice sends to bob, carol and herself in the first block, and bob sends back in the second one
+	maxFeesPaid := new(big.Int)
+	for _, transfer := range []struct {
+		to    types.Address
+		value int64
+	}{{bob, 1}, {carol, 2}, {alice, 1}} {
+		tx, err := h.Chain.Send(alice, transfer.to, big.NewInt(transfer.value), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		maxFeesPaid.Add(maxFeesPaid, new(big.Int).Mul(tx.GasPrice.Big(), tx.Gas.Big()))
+	}
+
+	first := h.Chain.Mine()
+
+	if _, err := h.Chain.Send(bob, alice, big.NewInt(5), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	second := h.Chain.Mine()
+
+	if err := h.WaitForHeight(second.Number.Uint64()); err != nil {
+		t.Fatal(err)
+	}
+
+	path := "/v1/addresses/" + string(alice) + "/analytics"
+	expectAll := func() error {
+		analytics := &server.GetAddressAnalyticsResponse{}
+		if _, err := h.Get(path, analytics); err != nil {
+			return err
+		}
+
+		if analytics.TxCount != 4 || analytics.InboundCount != 2 || analytics.OutboundCount != 3 ||
+			analytics.TotalIn.Uint64() != 6 || analytics.TotalOut.Uint64() != 4 || analytics.NetInflow != "2" ||
+			analytics.MaxFeesPaid.Big().Cmp(maxFeesPaid) != 0 {
+			return fmt.Errorf("unexpected analytics of alice, fees paid should be %s: %+v", maxFeesPaid, analytics)
+		}
+
+		counterparties := analytics.Counterparties
+		if len(counterparties) != 2 ||
+			!counterparties[0].Address.Equal(bob) || counterparties[0].TxCount != 2 || counterparties[0].Volume.Uint64() != 6 ||
+			!counterparties[1].Address.Equal(carol) || counterparties[1].TxCount != 1 || counterparties[1].Volume.Uint64() != 2 {
+			return fmt.Errorf("expected bob and carol ranked by volume, but got %+v", counterparties)
+		}
+
+		return nil
+	}
+
+	if err := expectAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the transfer from bob is in the second block
+	since := types.NewBlockHeader(second).Time().Format(time.RFC3339)
+	for _, query := range []string{"?fromBlock=" + second.Number.Decimal(), "?since=" + since} {
+		analytics := &server.GetAddressAnalyticsResponse{}
+		if _, err := h.Get(path+query, analytics); err != nil {
+			t.Fatal(err)
+		}
+
+		if analytics.TxCount != 1 || analytics.TotalIn.Uint64() != 5 || analytics.NetInflow != "5" || len(analytics.Counterparties) != 1 {
+			t.Fatalf("expected only the transfer from bob with %s, but got %+v", query, analytics)
+		}
+	}
+
+	// counterparties are limited by limit
+	analytics := &server.GetAddressAnalyticsResponse{}
+	if _, err := h.Get(path+"?limit=1", analytics); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(analytics.Counterparties) != 1 || !analytics.Counterparties[0].Address.Equal(bob) {
+		t.Fatalf("expected only bob with limit=1, but got %+v", analytics.Counterparties)
+	}
+
+	// aggregates of rewound blocks are subtracted before they are ingested again
+	if _, err := h.Parser.Seek(context.Background(), first.Number.Uint64()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.WaitForHeight(second.Number.Uint64()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := expectAll(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// subscribe subscribes to address by API
+func (h *Harness) subscribe(address types.Address) error {
+	response := &server.PostBulkSubscribeResponse{}
+	status, err := h.Post("/v1/subscriptions", &server.PostBulkSubscribeRequest{Addresses: []string{string(address)}}, response)
+	if err != nil {
+		return err
+	}
+
+	if status >= 300 || response.Subscribed != 1 {
+		return fmt.Errorf("failed to subscribe to %s, status=%d, subscribed=%d", address, status, response.Subscribed)
+	}
+
+	return nil
+}
+
+// expectTransactions checks that API returns exactly the transactions of address
+func (h *Harness) expectTransactions(address types.Address, hashes ...types.Hash) error {
+	response := &server.PostGetTransactionsResponse{}
+	status, err := h.Get("/v1/addresses/"+string(address)+"/transactions", response)
+	if err != nil {
+		return err
+	}
+
+	if status != 200 {
+		return fmt.Errorf("failed to get transactions of %s, status=%d", address, status)
+	}
+
+	actual := make([]types.Hash, len(response.Transactions))
+	for i, tx := range response.Transactions {
+		actual[i] = tx.Hash
+	}
+
+	if len(actual) != len(hashes) {
+		return fmt.Errorf("expected transactions %v of %s, but got %v", hashes, address, actual)
+	}
+
+	for i := range hashes {
+		if !actual[i].Equal(hashes[i]) {
+			return fmt.Errorf("expected transactions %v of %s, but got %v", hashes, address, actual)
+		}
+	}
+
+	return nil
+}
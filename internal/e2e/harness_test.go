@@ -0,0 +1,294 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/auth"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/server"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/testchain"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/txstorage"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/pkg/parser"
+)
+
+const (
+	// DefaultPollingInterval is the interval of parser to poll the next block, short so that scenarios finish quickly
+	DefaultPollingInterval = 50 * time.Millisecond
+	// DefaultWaitTimeout is the timeout of waiting for the parser in scenarios
+	DefaultWaitTimeout = 10 * time.Second
+)
+
+// TestMain silences logs of API server written by default logger unless -v is given
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+	}
+
+	os.Exit(m.Run())
+}
+
+// Config configures a harness
+type Config struct {
+	// Node is shared with another harness if given, e.g. to restart the parser, otherwise a node of Chain is started
+	Node  *testchain.Server
+	Chain testchain.Config
+
+	// ParserOptions are appended to the options used by the harness
+	ParserOptions []parser.Option
+	// TrackBalances makes the parser fetch balances of subscribed addresses
+	TrackBalances bool
+	// SupervisorOptions makes the parser restarted by a supervisor on errors if not nil
+	SupervisorOptions []parser.SupervisorOption
+	// AdminKey makes API require keys, it has admin role and is sent by Get and Post
+	AdminKey string
+
+	// Logger receives logs of parser, logs are discarded if nil
+	Logger *log.Logger
+}
+
+// newHarness creates a harness whose parser logs to the test log, it's closed when the test finishes
+func newHarness(t *testing.T, config Config) *Harness {
+	t.Helper()
+
+	if config.Logger == nil {
+		config.Logger = newTestLogger(t)
+	}
+
+	h, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create harness: %v", err)
+	}
+
+	t.Cleanup(h.Close)
+
+	return h
+}
+
+// testLogWriter writes logs to the test log, which is printed on failure or with -v
+// Logs after the test finishes are discarded because testing.T panics on them
+type testLogWriter struct {
+	t    *testing.T
+	done *atomic.Bool
+}
+
+func newTestLogger(t *testing.T) *log.Logger {
+	w := testLogWriter{t: t, done: &atomic.Bool{}}
+	t.Cleanup(func() {
+		w.done.Store(true)
+	})
+
+	return log.New(w, "", 0)
+}
+
+func (w testLogWriter) Write(p []byte) (int, error) {
+	if !w.done.Load() {
+		w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+
+	return len(p), nil
+}
+
+// Harness runs the real parser, storage and API server of a chain against a fake node in process
+type Harness struct {
+	Node    *testchain.Server
+	Chain   *testchain.Chain
+	Storage *txstorage.InMemoryTransactionStorage
+	Parser  *parser.Parser
+	Server  *server.EthTransactionsServer
+	// Supervisor restarts the parser, nil unless SupervisorOptions are given
+	Supervisor *parser.Supervisor
+
+	api      *httptest.Server
+	adminKey string
+	ownNode  bool
+	started  bool
+	stopped  bool
+}
+
+// New creates a harness, the parser is not started until StartParser is called
+func New(config Config) (*Harness, error) {
+	h := &Harness{Node: config.Node, adminKey: config.AdminKey}
+
+	if h.Node == nil {
+		h.Node = testchain.NewServer(testchain.NewChain(config.Chain))
+		if err := h.Node.Start("127.0.0.1:0"); err != nil {
+			return nil, err
+		}
+
+		h.ownNode = true
+	}
+
+	h.Chain = h.Node.Chain()
+
+	logger := config.Logger
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+
+	client := jsonrpc.New(&http.Client{}, h.Node.URL())
+	opts := append([]parser.Option{
+		parser.WithLogger(logger),
+		parser.WithPollingInterval(DefaultPollingInterval),
+	}, config.ParserOptions...)
+	if config.TrackBalances {
+		opts = append(opts, parser.WithBalanceTracking(client))
+	}
+
+	h.Storage = txstorage.New()
+	h.Parser = parser.New(client, h.Storage, opts...)
+
+	serverOpts := make([]server.Option, 0, 2)
+	if config.AdminKey != "" {
+		keys := auth.NewStore()
+		if _, err := keys.Add(config.AdminKey, "admin", auth.RoleAdmin); err != nil {
+			return nil, err
+		}
+
+		serverOpts = append(serverOpts, server.WithAuth(keys))
+	}
+
+	if config.SupervisorOptions != nil {
+		h.Supervisor = parser.NewSupervisor(append([]parser.SupervisorOption{parser.WithSupervisorLogger(logger)}, config.SupervisorOptions...)...)
+		serverOpts = append(serverOpts, server.WithSupervisor(h.Supervisor))
+	}
+
+	h.Server = server.New(h.Parser, 0, serverOpts...)
+	h.api = httptest.NewServer(h.Server.Server.Handler)
+
+	return h, nil
+}
+
+// StartParser starts the parser from beginningHeight, or from the checkpoint if saved
+// The parser is started by the supervisor if given
+func (h *Harness) StartParser(beginningHeight uint64) error {
+	height := new(big.Int).SetUint64(beginningHeight)
+
+	if h.Supervisor != nil {
+		h.Supervisor.Add("parser", h.Parser.Supervised(height))
+		if err := h.Supervisor.Start(); err != nil {
+			return err
+		}
+	} else if err := h.Parser.Start(height); err != nil {
+		return err
+	}
+
+	h.started = true
+
+	return nil
+}
+
+// StopParser stops the parser, and the supervisor if given, within timeout, it's stopped only once
+func (h *Harness) StopParser(timeout time.Duration) error {
+	if !h.started || h.stopped {
+		return nil
+	}
+
+	h.stopped = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if h.Supervisor != nil {
+		return h.Supervisor.Stop(ctx)
+	}
+
+	return h.Parser.Stop(ctx)
+}
+
+// Close stops the parser, the API server, and the node unless it's shared
+func (h *Harness) Close() {
+	if err := h.StopParser(time.Second); err != nil {
+		log.Printf("failed to stop parser of harness: %v", err)
+	}
+
+	h.api.Close()
+
+	if h.ownNode {
+		if err := h.Node.Close(); err != nil {
+			log.Printf("failed to stop node of harness: %v", err)
+		}
+	}
+}
+
+// WaitForHeight waits until the parser processes the block at height
+func (h *Harness) WaitForHeight(height uint64) error {
+	return WaitFor(DefaultWaitTimeout, func() bool {
+		return uint64(h.Parser.GetCurrentBlock()) >= height
+	}, fmt.Sprintf("parser to process block %d", height))
+}
+
+// Get calls API and decodes JSON response into out, the status code is returned
+func (h *Harness) Get(path string, out interface{}) (int, error) {
+	return h.do(http.MethodGet, path, nil, out)
+}
+
+// Post calls API with JSON body and decodes JSON response into out, the status code is returned
+func (h *Harness) Post(path string, body interface{}, out interface{}) (int, error) {
+	return h.do(http.MethodPost, path, body, out)
+}
+
+func (h *Harness) do(method, path string, body interface{}, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, h.api.URL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+
+	if h.adminKey != "" {
+		req.Header.Set(server.HeaderAPIKey, h.adminKey)
+	}
+
+	res, err := h.api.Client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer res.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return res.StatusCode, fmt.Errorf("failed to decode response of %s %s: %w", method, path, err)
+		}
+	}
+
+	return res.StatusCode, nil
+}
+
+// WaitFor polls cond until it returns true, or returns error after timeout
+func WaitFor(timeout time.Duration, cond func() bool, description string) error {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %s", description)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return nil
+}
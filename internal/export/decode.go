@@ -0,0 +1,62 @@
+package export
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	// EtherDecimals is the number of decimals between wei and ether
+	EtherDecimals = 18
+
+	// EtherSymbol is the currency symbol used in accounting formats
+	EtherSymbol = "ETH"
+)
+
+// weiToEther converts the amount in wei to decimal string in ether without losing precision
+func weiToEther(wei *big.Int) string {
+	if wei == nil {
+		return ""
+	}
+
+	sign := ""
+	digits := new(big.Int).Abs(wei).String()
+	if wei.Sign() < 0 {
+		sign = "-"
+	}
+
+	// pad with zeros so that integer part has at least one digit
+	if len(digits) <= EtherDecimals {
+		digits = strings.Repeat("0", EtherDecimals-len(digits)+1) + digits
+	}
+
+	integer := digits[:len(digits)-EtherDecimals]
+	fraction := strings.TrimRight(digits[len(digits)-EtherDecimals:], "0")
+	if fraction == "" {
+		return sign + integer
+	}
+
+	return sign + integer + "." + fraction
+}
+
+// maxFeeWei returns gasPrice * gas, which is the maximum fee the sender may pay for the transaction
+func maxFeeWei(gasPrice, gas types.Quantity) *big.Int {
+	price, limit := gasPrice.Big(), gas.Big()
+	if price == nil || limit == nil {
+		return nil
+	}
+
+	return new(big.Int).Mul(price, limit)
+}
+
+// formatTimestamp formats time in ISO 8601, it returns empty string for zero time
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
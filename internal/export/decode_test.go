@@ -0,0 +1,36 @@
+package export
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestWeiToEther(t *testing.T) {
+	for _, tt := range []struct {
+		wei      string
+		expected string
+	}{
+		{"0", "0"},
+		{"1", "0.000000000000000001"},
+		{"100", "0.0000000000000001"},
+		{"999999999999999999", "0.999999999999999999"},
+		{"1000000000000000000", "1"},
+		{"1500000000000000000", "1.5"},
+		{"123456789012345678901234567890", "123456789012.34567890123456789"},
+		{"-1", "-0.000000000000000001"},
+		{"-2500000000000000000", "-2.5"},
+	} {
+		wei, ok := new(big.Int).SetString(tt.wei, 10)
+		if !ok {
+			t.Fatalf("invalid amount %s", tt.wei)
+		}
+
+		if actual := weiToEther(wei); actual != tt.expected {
+			t.Errorf("expected %s wei to be %s ETH, but got %s", tt.wei, tt.expected, actual)
+		}
+	}
+
+	if actual := weiToEther(nil); actual != "" {
+		t.Errorf("expected empty string for nil, but got %q", actual)
+	}
+}
@@ -0,0 +1,301 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// TimestampResolver returns the timestamp of block at given height
+type TimestampResolver func(height uint64) (time.Time, bool)
+
+// Encoder writes transactions one by one to underlying writer
+type Encoder interface {
+	// Encode writes a transaction
+	Encode(tx *types.Transaction) error
+	// Flush writes any buffered data to underlying writer
+	Flush() error
+}
+
+// NewEncoder creates an Encoder for given format
+// address is the account from whose perspective directions and amounts are computed
+func NewEncoder(
+	format Format,
+	w io.Writer,
+	address types.Address,
+	resolveTimestamp TimestampResolver,
+	opts types.ViewOptions,
+) (Encoder, error) {
+	if resolveTimestamp == nil {
+		resolveTimestamp = func(uint64) (time.Time, bool) { return time.Time{}, false }
+	}
+
+	base := baseEncoder{
+		address:          address,
+		resolveTimestamp: resolveTimestamp,
+		opts:             opts,
+	}
+
+	switch format {
+	case FormatCSV:
+		return &csvEncoder{baseEncoder: base, writer: newCSVWriter(w, csvHeader)}, nil
+	case FormatNDJSON:
+		return &ndjsonEncoder{encoder: json.NewEncoder(w), opts: opts}, nil
+	case FormatKoinly:
+		return &koinlyEncoder{baseEncoder: base, writer: newCSVWriter(w, koinlyHeader)}, nil
+	case FormatCoinTracker:
+		return &coinTrackerEncoder{baseEncoder: base, writer: newCSVWriter(w, coinTrackerHeader)}, nil
+	default:
+		return nil, fmt.Errorf("format %s can't be streamed", format)
+	}
+}
+
+// newCSVWriter returns a CSV writer with the header buffered, so that export without rows still has the header
+// An error of writing the header is returned by Flush
+func newCSVWriter(w io.Writer, header []string) *csv.Writer {
+	writer := csv.NewWriter(w)
+	_ = writer.Write(header)
+
+	return writer
+}
+
+// Direction is a direction of transaction from the perspective of an account
+type Direction string
+
+const (
+	DirectionIn   Direction = "in"
+	DirectionOut  Direction = "out"
+	DirectionSelf Direction = "self"
+)
+
+// baseEncoder has common helpers for encoders that decode values
+type baseEncoder struct {
+	address          types.Address
+	resolveTimestamp TimestampResolver
+	opts             types.ViewOptions
+}
+
+// formatAddress returns the address in representation chosen by options
+func (e *baseEncoder) formatAddress(address types.Address) string {
+	if e.opts.ChecksumAddresses {
+		return string(address.Checksum())
+	}
+
+	return string(address)
+}
+
+// direction returns the direction of transaction for the account
+func (e *baseEncoder) direction(tx *types.Transaction) Direction {
+	from, to := tx.From.Equal(e.address), tx.To.Equal(e.address)
+
+	switch {
+	case from && to:
+		return DirectionSelf
+	case from:
+		return DirectionOut
+	default:
+		return DirectionIn
+	}
+}
+
+// timestamp returns the time when the transaction was included
+func (e *baseEncoder) timestamp(tx *types.Transaction) time.Time {
+	if tx.BlockNumber.IsEmpty() {
+		return time.Time{}
+	}
+
+	t, _ := e.resolveTimestamp(tx.BlockNumber.Uint64())
+
+	return t
+}
+
+// csvEncoder writes transactions in CSV with decoded values
+type csvEncoder struct {
+	baseEncoder
+	writer *csv.Writer
+}
+
+var csvHeader = []string{
+	"hash",
+	"block_number",
+	"timestamp",
+	"from",
+	"to",
+	"direction",
+	"value_eth",
+	"value_wei",
+	"gas",
+	"gas_price_wei",
+	"max_fee_eth",
+	"nonce",
+	"type",
+}
+
+func (e *csvEncoder) Encode(tx *types.Transaction) error {
+	return e.writer.Write([]string{
+		string(tx.Hash),
+		tx.BlockNumber.Decimal(),
+		formatTimestamp(e.timestamp(tx)),
+		e.formatAddress(tx.From),
+		e.formatAddress(tx.To),
+		string(e.direction(tx)),
+		weiToEther(tx.Value.Big()),
+		tx.Value.Decimal(),
+		tx.Gas.Decimal(),
+		tx.GasPrice.Decimal(),
+		weiToEther(maxFeeWei(tx.GasPrice, tx.Gas)),
+		tx.Nonce.Decimal(),
+		tx.Type.Decimal(),
+	})
+}
+
+func (e *csvEncoder) Flush() error {
+	e.writer.Flush()
+
+	return e.writer.Error()
+}
+
+// ndjsonEncoder writes transactions in newline delimited JSON as they are
+type ndjsonEncoder struct {
+	encoder *json.Encoder
+	opts    types.ViewOptions
+}
+
+func (e *ndjsonEncoder) Encode(tx *types.Transaction) error {
+	return e.encoder.Encode(types.View(tx, e.opts))
+}
+
+func (e *ndjsonEncoder) Flush() error {
+	return nil
+}
+
+// koinlyEncoder writes transactions in Koinly universal CSV layout
+type koinlyEncoder struct {
+	baseEncoder
+	writer *csv.Writer
+}
+
+var koinlyHeader = []string{
+	"Date",
+	"Sent Amount",
+	"Sent Currency",
+	"Received Amount",
+	"Received Currency",
+	"Fee Amount",
+	"Fee Currency",
+	"Net Worth Amount",
+	"Net Worth Currency",
+	"Label",
+	"Description",
+	"TxHash",
+}
+
+func (e *koinlyEncoder) Encode(tx *types.Transaction) error {
+	row := make([]string, len(koinlyHeader))
+	row[0] = koinlyDate(e.timestamp(tx))
+	row[11] = string(tx.Hash)
+
+	sent, received, fee := accountingAmounts(e.direction(tx), tx)
+	if sent != "" {
+		row[1], row[2] = sent, EtherSymbol
+	}
+	if received != "" {
+		row[3], row[4] = received, EtherSymbol
+	}
+	if fee != "" {
+		row[5], row[6] = fee, EtherSymbol
+	}
+
+	return e.writer.Write(row)
+}
+
+func (e *koinlyEncoder) Flush() error {
+	e.writer.Flush()
+
+	return e.writer.Error()
+}
+
+// coinTrackerEncoder writes transactions in CoinTracker CSV layout
+type coinTrackerEncoder struct {
+	baseEncoder
+	writer *csv.Writer
+}
+
+var coinTrackerHeader = []string{
+	"Date",
+	"Received Quantity",
+	"Received Currency",
+	"Sent Quantity",
+	"Sent Currency",
+	"Fee Amount",
+	"Fee Currency",
+	"Tag",
+}
+
+func (e *coinTrackerEncoder) Encode(tx *types.Transaction) error {
+	row := make([]string, len(coinTrackerHeader))
+	row[0] = coinTrackerDate(e.timestamp(tx))
+
+	sent, received, fee := accountingAmounts(e.direction(tx), tx)
+	if received != "" {
+		row[1], row[2] = received, EtherSymbol
+	}
+	if sent != "" {
+		row[3], row[4] = sent, EtherSymbol
+	}
+	if fee != "" {
+		row[5], row[6] = fee, EtherSymbol
+	}
+
+	return e.writer.Write(row)
+}
+
+func (e *coinTrackerEncoder) Flush() error {
+	e.writer.Flush()
+
+	return e.writer.Error()
+}
+
+// accountingAmounts returns sent, received and fee amount in ether for the account
+// Fee is paid only by the sender and is estimated as gasPrice * gas
+func accountingAmounts(direction Direction, tx *types.Transaction) (sent, received, fee string) {
+	value := tx.Value.Big()
+
+	switch direction {
+	case DirectionOut:
+		if value != nil && value.Sign() > 0 {
+			sent = weiToEther(value)
+		}
+		fee = weiToEther(maxFeeWei(tx.GasPrice, tx.Gas))
+	case DirectionIn:
+		if value != nil && value.Sign() > 0 {
+			received = weiToEther(value)
+		}
+	case DirectionSelf:
+		fee = weiToEther(maxFeeWei(tx.GasPrice, tx.Gas))
+	}
+
+	return sent, received, fee
+}
+
+// koinlyDate formats time as Koinly expects, e.g. 2018-01-01 14:25 UTC
+func koinlyDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format("2006-01-02 15:04 MST")
+}
+
+// coinTrackerDate formats time as CoinTracker expects, e.g. 06/14/2017 20:57:35
+func coinTrackerDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format("01/02/2006 15:04:05")
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+var (
+	alice = types.Address("0x" + strings.Repeat("11", 20))
+	bob   = types.Address("0x" + strings.Repeat("22", 20))
+)
+
+// transferOfAlice is a transfer of 1.5 ETH from alice to bob in block 16
+var transferOfAlice = &types.Transaction{
+	BlockNumber: types.NewQuantityFromUint64(16),
+	From:        alice,
+	To:          bob,
+	Gas:         types.NewQuantityFromUint64(21000),
+	GasPrice:    types.NewQuantityFromUint64(20_000_000_000),
+	Hash:        types.Hash("0x" + strings.Repeat("ab", 32)),
+	Nonce:       types.NewQuantityFromUint64(7),
+	Value:       types.NewQuantityFromUint64(1_500_000_000_000_000_000),
+	Type:        types.NewQuantityFromUint64(2),
+}
+
+// resolveTimestamp returns the time of block 16 only
+func resolveTimestamp(height uint64) (time.Time, bool) {
+	if height != 16 {
+		return time.Time{}, false
+	}
+
+	return time.Date(2024, 5, 1, 12, 34, 56, 0, time.UTC), true
+}
+
+// encode exports transactions for the address and returns the output
+func encode(t *testing.T, format Format, address types.Address, txs ...*types.Transaction) string {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	encoder, err := NewEncoder(format, buf, address, resolveTimestamp, types.ViewOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tx := range txs {
+		if err := encoder.Encode(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := encoder.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func TestEncodeRows(t *testing.T) {
+	hash := string(transferOfAlice.Hash)
+
+	for _, tt := range []struct {
+		format   Format
+		address  types.Address
+		expected []string
+	}{
+		{
+			FormatCSV, alice,
+			[]string{
+				"hash,block_number,timestamp,from,to,direction,value_eth,value_wei,gas,gas_price_wei,max_fee_eth,nonce,type",
+				hash + ",16,2024-05-01T12:34:56Z," + string(alice) + "," + string(bob) + ",out,1.5,1500000000000000000,21000,20000000000,0.00042,7,2",
+			},
+		},
+		{
+			FormatCSV, bob,
+			[]string{
+				"hash,block_number,timestamp,from,to,direction,value_eth,value_wei,gas,gas_price_wei,max_fee_eth,nonce,type",
+				hash + ",16,2024-05-01T12:34:56Z," + string(alice) + "," + string(bob) + ",in,1.5,1500000000000000000,21000,20000000000,0.00042,7,2",
+			},
+		},
+		{
+			FormatKoinly, alice,
+			[]string{
+				"Date,Sent Amount,Sent Currency,Received Amount,Received Currency,Fee Amount,Fee Currency,Net Worth Amount,Net Worth Currency,Label,Description,TxHash",
+				"2024-05-01 12:34 UTC,1.5,ETH,,,0.00042,ETH,,,,," + hash,
+			},
+		},
+		{
+			FormatKoinly, bob,
+			[]string{
+				"Date,Sent Amount,Sent Currency,Received Amount,Received Currency,Fee Amount,Fee Currency,Net Worth Amount,Net Worth Currency,Label,Description,TxHash",
+				"2024-05-01 12:34 UTC,,,1.5,ETH,,,,,,," + hash,
+			},
+		},
+		{
+			FormatCoinTracker, alice,
+			[]string{
+				"Date,Received Quantity,Received Currency,Sent Quantity,Sent Currency,Fee Amount,Fee Currency,Tag",
+				"05/01/2024 12:34:56,,,1.5,ETH,0.00042,ETH,",
+			},
+		},
+		{
+			FormatCoinTracker, bob,
+			[]string{
+				"Date,Received Quantity,Received Currency,Sent Quantity,Sent Currency,Fee Amount,Fee Currency,Tag",
+				"05/01/2024 12:34:56,1.5,ETH,,,,,",
+			},
+		},
+	} {
+		t.Run(string(tt.format)+"/"+string(tt.address), func(t *testing.T) {
+			expected := strings.Join(tt.expected, "\n") + "\n"
+			if actual := encode(t, tt.format, tt.address, transferOfAlice); actual != expected {
+				t.Fatalf("expected\n%s\nbut got\n%s", expected, actual)
+			}
+		})
+	}
+}
+
+func TestEncodeSelfTransferAndUnknownTimestamp(t *testing.T) {
+	tx := *transferOfAlice
+	tx.To = alice
+	tx.BlockNumber = types.NewQuantityFromUint64(17)
+
+	// only the fee is paid for transfer to itself
+	expected := "Date,Sent Amount,Sent Currency,Received Amount,Received Currency,Fee Amount,Fee Currency,Net Worth Amount,Net Worth Currency,Label,Description,TxHash\n" +
+		",,,,,0.00042,ETH,,,,," + string(tx.Hash) + "\n"
+	if actual := encode(t, FormatKoinly, alice, &tx); actual != expected {
+		t.Fatalf("expected\n%s\nbut got\n%s", expected, actual)
+	}
+}
+
+func TestEncodeWithoutTransactions(t *testing.T) {
+	for format, expected := range map[Format]string{
+		FormatCSV:         strings.Join(csvHeader, ",") + "\n",
+		FormatKoinly:      strings.Join(koinlyHeader, ",") + "\n",
+		FormatCoinTracker: strings.Join(coinTrackerHeader, ",") + "\n",
+		FormatNDJSON:      "",
+	} {
+		if actual := encode(t, format, alice); actual != expected {
+			t.Errorf("expected only header %q for %s, but got %q", expected, format, actual)
+		}
+	}
+}
+
+func TestNewEncoderOfJSON(t *testing.T) {
+	if _, err := NewEncoder(FormatJSON, &bytes.Buffer{}, alice, nil, types.ViewOptions{}); err == nil {
+		t.Fatal("expected json not to be streamed")
+	}
+}
@@ -0,0 +1,77 @@
+package export
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// Format is an output format of transaction export
+type Format string
+
+const (
+	FormatJSON        Format = "json"
+	FormatCSV         Format = "csv"
+	FormatNDJSON      Format = "ndjson"
+	FormatKoinly      Format = "koinly"
+	FormatCoinTracker Format = "cointracker"
+)
+
+// mediaTypes maps media types in Accept header to export formats
+var mediaTypes = map[string]Format{
+	"application/json":     FormatJSON,
+	"text/csv":             FormatCSV,
+	"application/x-ndjson": FormatNDJSON,
+	"application/jsonl":    FormatNDJSON,
+}
+
+// ParseFormat parses format name given by user
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatJSON, FormatCSV, FormatNDJSON, FormatKoinly, FormatCoinTracker:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported export format: %s", s)
+	}
+}
+
+// FormatFromAccept picks the first supported format in the value of Accept header
+// It returns FormatJSON if no supported media type is found
+func FormatFromAccept(accept string) Format {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		if f, ok := mediaTypes[mediaType]; ok {
+			return f
+		}
+	}
+
+	return FormatJSON
+}
+
+// ContentType returns the value of Content-Type header for the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV, FormatKoinly, FormatCoinTracker:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/json"
+	}
+}
+
+// FileExtension returns the extension of file name for the format
+func (f Format) FileExtension() string {
+	switch f {
+	case FormatCSV, FormatKoinly, FormatCoinTracker:
+		return "csv"
+	case FormatNDJSON:
+		return "ndjson"
+	default:
+		return "json"
+	}
+}
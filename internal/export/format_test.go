@@ -0,0 +1,43 @@
+package export
+
+import "testing"
+
+func TestParseFormat(t *testing.T) {
+	for raw, expected := range map[string]Format{
+		"json":        FormatJSON,
+		"CSV":         FormatCSV,
+		"ndjson":      FormatNDJSON,
+		"Koinly":      FormatKoinly,
+		"cointracker": FormatCoinTracker,
+	} {
+		if actual, err := ParseFormat(raw); err != nil || actual != expected {
+			t.Errorf("expected %s to be %s, but got format=%s, err=%v", raw, expected, actual, err)
+		}
+	}
+
+	for _, raw := range []string{"", "xml", "text/csv"} {
+		if _, err := ParseFormat(raw); err == nil {
+			t.Errorf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestFormatFromAccept(t *testing.T) {
+	for _, tt := range []struct {
+		accept   string
+		expected Format
+	}{
+		{"", FormatJSON},
+		{"*/*", FormatJSON},
+		{"text/csv", FormatCSV},
+		{"text/csv; charset=utf-8", FormatCSV},
+		{"application/jsonl", FormatNDJSON},
+		{"text/html, application/x-ndjson;q=0.9, text/csv", FormatNDJSON},
+		{"invalid;;, text/csv", FormatCSV},
+		{"application/xml", FormatJSON},
+	} {
+		if actual := FormatFromAccept(tt.accept); actual != tt.expected {
+			t.Errorf("expected %q to be %s, but got %s", tt.accept, tt.expected, actual)
+		}
+	}
+}
@@ -4,23 +4,69 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net"
 	"net/http"
+	"sync/atomic"
+	"time"
 )
 
+// Endpoint is a JSON-RPC server URL with its rate limit
+type Endpoint struct {
+	Url string
+	// RequestsPerSecond is the maximum rate of requests to the endpoint, zero means unlimited
+	RequestsPerSecond float64
+	// Burst is the number of requests allowed at once
+	Burst int
+}
+
+type endpoint struct {
+	url     string
+	limiter *RateLimiter
+}
+
 type EthJsonRpcClient struct {
-	client     *http.Client
-	jsonRpcUrl string
+	client    *http.Client
+	endpoints []endpoint
+
+	// index of endpoint which succeeded last time, requests are sent to it first
+	preferred *atomic.Int32
+}
+
+// New creates a client, fallbackUrls are used in order when a request to jsonRpcUrl fails
+func New(client *http.Client, jsonRpcUrl string, fallbackUrls ...string) *EthJsonRpcClient {
+	endpoints := make([]Endpoint, 0, len(fallbackUrls)+1)
+	for _, url := range append([]string{jsonRpcUrl}, fallbackUrls...) {
+		endpoints = append(endpoints, Endpoint{Url: url})
+	}
+
+	return NewWithEndpoints(client, endpoints)
 }
 
-func New(client *http.Client, jsonRpcUrl string) *EthJsonRpcClient {
+// NewWithEndpoints creates a client sending requests to endpoints within their rate limits
+// Endpoints after the first one are used in order when a request fails
+func NewWithEndpoints(client *http.Client, endpoints []Endpoint) *EthJsonRpcClient {
+	eps := make([]endpoint, len(endpoints))
+	for i, ep := range endpoints {
+		eps[i] = endpoint{
+			url:     ep.Url,
+			limiter: NewRateLimiter(ep.RequestsPerSecond, ep.Burst),
+		}
+	}
+
 	return &EthJsonRpcClient{
-		client:     client,
-		jsonRpcUrl: jsonRpcUrl,
+		client:    client,
+		endpoints: eps,
+		preferred: &atomic.Int32{},
 	}
 }
 
 // call sends JSON-RPC request to server and returns response
+// It fails over to next endpoint if the request doesn't reach to server or server returns non-200 status
+// If all endpoints throttle the request, it waits for them to accept requests again until the context is done
 func (c *EthJsonRpcClient) call(ctx context.Context, request *JsonRpcRequest) (*JsonRpcResponse, error) {
 	// serialize request to JSON
 	reqBody, err := json.Marshal(request)
@@ -28,8 +74,104 @@ func (c *EthJsonRpcClient) call(ctx context.Context, request *JsonRpcRequest) (*
 		return nil, fmt.Errorf("failed to serialize JSON RPC request: %w", err)
 	}
 
+	for {
+		result, err := c.callEndpoints(ctx, request.Method, reqBody)
+		if err == nil || !errors.Is(err, ErrRateLimited) {
+			return result, err
+		}
+
+		// all endpoints are throttled, wait for the earliest one
+		if err := c.waitForAnyEndpoint(ctx); err != nil {
+			return nil, fmt.Errorf("%w: gave up waiting: %w", ErrRateLimited, err)
+		}
+	}
+}
+
+// callEndpoints tries endpoints in order from the preferred one
+// It returns ErrRateLimited only if all endpoints are throttled
+func (c *EthJsonRpcClient) callEndpoints(ctx context.Context, method string, reqBody []byte) (*JsonRpcResponse, error) {
+	num := len(c.endpoints)
+	start := int(c.preferred.Load())
+
+	errs := make([]error, 0, num)
+	throttled := 0
+	for i := 0; i < num; i++ {
+		idx := (start + i) % num
+		ep := c.endpoints[idx]
+
+		// skip paused endpoint while others may accept the request
+		if ep.limiter.PausedFor() > 0 {
+			throttled++
+			continue
+		}
+
+		if err := ep.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		result, err := c.callEndpoint(ctx, ep.url, method, reqBody)
+		if err == nil {
+			ep.limiter.Succeed()
+			c.preferred.Store(int32(idx))
+
+			return result, nil
+		}
+
+		// cancelled or timed out, other endpoints would fail as well
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		var rateLimitErr *RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			ep.limiter.Throttle(rateLimitErr.RetryAfter)
+			throttled++
+
+			log.Printf("JSON RPC endpoint #%d throttled the request, pausing for %s: %v", idx, ep.limiter.PausedFor().Round(time.Millisecond), err)
+
+			continue
+		}
+
+		if num > 1 {
+			log.Printf("JSON RPC endpoint #%d failed, trying next one: %v", idx, err)
+		}
+
+		errs = append(errs, err)
+	}
+
+	if throttled == num {
+		return nil, ErrRateLimited
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// waitForAnyEndpoint waits until one of endpoints finishes its pause
+func (c *EthJsonRpcClient) waitForAnyEndpoint(ctx context.Context) error {
+	wait := c.endpoints[0].limiter.PausedFor()
+	for _, ep := range c.endpoints[1:] {
+		wait = min(wait, ep.limiter.PausedFor())
+	}
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// callEndpoint sends serialized JSON-RPC request to given endpoint and returns response
+func (c *EthJsonRpcClient) callEndpoint(ctx context.Context, url, method string, reqBody []byte) (*JsonRpcResponse, error) {
 	// build request
-	req, err := http.NewRequest("POST", c.jsonRpcUrl, bytes.NewBuffer(reqBody))
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create new JSON RPC request: %w", err)
 	}
@@ -38,20 +180,43 @@ func (c *EthJsonRpcClient) call(ctx context.Context, request *JsonRpcRequest) (*
 	req = req.WithContext(ctx)
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call JSON RPC: %w", err)
+		return nil, &TransportError{Url: url, Err: err}
 	}
 
 	defer resp.Body.Close()
 
+	// server throttles requests
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &RateLimitError{
+			Url:        url,
+			RetryAfter: parseRetryAfter(resp.Header),
+			Reason:     "rpc server returns 429 status",
+		}
+	}
+
 	// server should return Ok
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("rpc server returns not 200 status: %d", resp.StatusCode)
+		return nil, &HTTPStatusError{Url: url, StatusCode: resp.StatusCode}
 	}
 
 	// parse response json
 	result := &JsonRpcResponse{}
 	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON RPC response: %w", err)
+		// body is cut off by connection error, which is not a malformed response
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, new(net.Error)) {
+			return nil, &TransportError{Url: url, Err: err}
+		}
+
+		return nil, &DecodeError{Method: method, Err: err}
+	}
+
+	// some providers return rate limit error with 200 status
+	if isRateLimitError(result.Error) {
+		return nil, &RateLimitError{
+			Url:        url,
+			RetryAfter: parseRetryAfter(resp.Header),
+			Reason:     result.Error.Error(),
+		}
 	}
 
 	return result, nil
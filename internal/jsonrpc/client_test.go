@@ -0,0 +1,220 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/testchain"
+)
+
+// newTestNode starts a fake node serving a chain, it's closed when the test finishes
+func newTestNode(t *testing.T, config testchain.Config) (*testchain.Server, string) {
+	t.Helper()
+
+	node := testchain.NewServer(testchain.NewChain(config))
+	api := httptest.NewServer(node)
+	t.Cleanup(api.Close)
+
+	return node, api.URL
+}
+
+func TestGetBlockByNumber(t *testing.T) {
+	node, url := newTestNode(t, testchain.Config{InitialHeight: 2})
+	client := jsonrpc.New(&http.Client{}, url)
+
+	expected := node.Chain().Block(1)
+
+	block, err := client.GetBlockByNumber(context.Background(), *big.NewInt(1), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if block == nil || !block.Hash.Equal(expected.Hash) || block.Number != expected.Number {
+		t.Fatalf("expected block %s, but got %+v", expected.Hash, block)
+	}
+
+	if len(block.Transactions) != len(expected.Transactions) || len(block.Transactions) == 0 {
+		t.Fatalf("expected %d transactions, but got %d", len(expected.Transactions), len(block.Transactions))
+	}
+
+	for i, tx := range block.Transactions {
+		if !tx.Hash.Equal(expected.Transactions[i].Hash) || !tx.From.Equal(expected.Transactions[i].From) {
+			t.Fatalf("expected transaction %+v at %d, but got %+v", expected.Transactions[i], i, tx)
+		}
+	}
+
+	// a block not mined yet is nil without error
+	block, err = client.GetBlockByNumber(context.Background(), *big.NewInt(3), true)
+	if err != nil || block != nil {
+		t.Fatalf("expected no block at 3, but got block=%+v, err=%v", block, err)
+	}
+}
+
+func TestGetChainIdAndBlockNumber(t *testing.T) {
+	_, url := newTestNode(t, testchain.Config{ChainId: 10, InitialHeight: 4})
+	client := jsonrpc.New(&http.Client{}, url)
+
+	chainId, err := client.GetChainId(context.Background())
+	if err != nil || chainId.Uint64() != 10 {
+		t.Fatalf("expected chain id 10, but got %v, err=%v", chainId, err)
+	}
+
+	height, err := client.GetBlockNumber(context.Background())
+	if err != nil || height.Uint64() != 4 {
+		t.Fatalf("expected block number 4, but got %v, err=%v", height, err)
+	}
+}
+
+func TestGetTransactionByHashAndBalance(t *testing.T) {
+	node, url := newTestNode(t, testchain.Config{TxsPerBlock: -1, WithdrawalsPerBlock: -1})
+	client := jsonrpc.New(&http.Client{}, url)
+
+	chain := node.Chain()
+	alice, bob := chain.Accounts()[0], chain.Accounts()[1]
+
+	sent, err := chain.Send(alice, bob, big.NewInt(100), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain.Mine()
+
+	tx, err := client.GetTransactionByHash(context.Background(), sent.Hash)
+	if err != nil || tx == nil || !tx.Hash.Equal(sent.Hash) || tx.Value.Uint64() != 100 {
+		t.Fatalf("expected transaction %s, but got %+v, err=%v", sent.Hash, tx, err)
+	}
+
+	expected, err := chain.Balance(bob, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := client.GetBalance(context.Background(), bob, nil)
+	if err != nil || balance.Cmp(expected) != 0 {
+		t.Fatalf("expected balance %s of bob, but got %v, err=%v", expected, balance, err)
+	}
+}
+
+func TestFailoverToNextEndpoint(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(down.Close)
+
+	_, url := newTestNode(t, testchain.Config{InitialHeight: 1})
+	client := jsonrpc.New(&http.Client{}, down.URL, url)
+
+	height, err := client.GetBlockNumber(context.Background())
+	if err != nil || height.Uint64() != 1 {
+		t.Fatalf("expected block number 1 from fallback endpoint, but got %v, err=%v", height, err)
+	}
+}
+
+func TestRateLimitedRequestIsRetried(t *testing.T) {
+	node, url := newTestNode(t, testchain.Config{InitialHeight: 1})
+	client := jsonrpc.New(&http.Client{}, url)
+
+	node.InjectFailures(1, testchain.RateLimited(50*time.Millisecond))
+
+	height, err := client.GetBlockNumber(context.Background())
+	if err != nil || height.Uint64() != 1 {
+		t.Fatalf("expected block number 1 after throttling, but got %v, err=%v", height, err)
+	}
+
+	if calls := node.Calls(jsonrpc.MethodEthBlockNumber); calls != 2 {
+		t.Fatalf("expected 2 calls of %s, but got %d", jsonrpc.MethodEthBlockNumber, calls)
+	}
+}
+
+func TestErrorsOfNode(t *testing.T) {
+	node, url := newTestNode(t, testchain.Config{InitialHeight: 1})
+	client := jsonrpc.New(&http.Client{}, url)
+
+	node.InjectFailures(1, testchain.InternalError())
+
+	_, err := client.GetBlockByNumber(context.Background(), *big.NewInt(1), true)
+
+	var rpcErr *jsonrpc.JsonRpcError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != jsonrpc.CodeInternalError {
+		t.Fatalf("expected internal JSON RPC error, but got %v", err)
+	}
+
+	if !jsonrpc.IsRetryable(err) {
+		t.Fatalf("expected internal error to be retryable: %v", err)
+	}
+
+	// the node rejects request in wrong format, which never succeeds
+	node.InjectFailures(1, testchain.Failure{Status: http.StatusBadRequest})
+
+	_, err = client.GetBlockNumber(context.Background())
+	if err == nil || jsonrpc.IsRetryable(err) {
+		t.Fatalf("expected permanent error of 400 status, but got %v", err)
+	}
+}
+
+func TestGetEndpointChainIds(t *testing.T) {
+	_, mainnet := newTestNode(t, testchain.Config{ChainId: 1})
+	_, sepolia := newTestNode(t, testchain.Config{ChainId: 11155111})
+
+	// chain id of fallback is fetched from the fallback itself although the first endpoint is available
+	client := jsonrpc.New(&http.Client{}, mainnet, sepolia)
+
+	chainIds, err := client.GetEndpointChainIds(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chainIds) != 2 || chainIds[0].Uint64() != 1 || chainIds[1].Uint64() != 11155111 {
+		t.Fatalf("expected chain ids 1 and 11155111, but got %v", chainIds)
+	}
+
+	// unavailable endpoint can't be checked
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(down.Close)
+
+	if _, err := jsonrpc.New(&http.Client{}, mainnet, down.URL).GetEndpointChainIds(context.Background()); err == nil {
+		t.Fatal("expected error of unavailable endpoint, but got nil")
+	}
+}
+
+func TestMalformedResponseIsNotRetryable(t *testing.T) {
+	respond := func(body string) string {
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(body))
+		}))
+		t.Cleanup(api.Close)
+
+		return api.URL
+	}
+
+	malformed := respond("<html>bad gateway</html>")
+	wrongResult := respond(`{"jsonrpc":"2.0","id":1,"result":true}`)
+	truncated := respond(`{"jsonrpc":"2.0","id":1,"res`)
+
+	for _, tt := range []struct {
+		name      string
+		urls      []string
+		retryable bool
+	}{
+		{"malformed body", []string{malformed}, false},
+		{"wrong type of result", []string{wrongResult}, false},
+		{"truncated body", []string{truncated}, true},
+		// another endpoint may recover
+		{"malformed body and truncated body", []string{malformed, truncated}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jsonrpc.New(&http.Client{}, tt.urls[0], tt.urls[1:]...).GetBlockNumber(context.Background())
+			if err == nil || jsonrpc.IsRetryable(err) != tt.retryable {
+				t.Fatalf("expected retryable=%t, but got %v", tt.retryable, err)
+			}
+		})
+	}
+}
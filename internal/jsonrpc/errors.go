@@ -0,0 +1,180 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JSON-RPC error codes defined by the specification
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	// CodeServerError is the generic server error used by nodes, e.g. geth
+	CodeServerError = -32000
+)
+
+var (
+	// ErrNotFound is matched by JSON-RPC errors meaning that requested resource doesn't exist (yet)
+	ErrNotFound = errors.New("resource not found")
+	// ErrEmptyResult is wrapped by DecodeError when result is null or empty unexpectedly
+	ErrEmptyResult = errors.New("result is empty")
+	// ErrRateLimited is matched by errors caused by throttling of JSON RPC server
+	ErrRateLimited = errors.New("rate limited by JSON RPC server")
+)
+
+// notFoundErrorMessages are fragments of JSON-RPC error messages used by nodes and providers for missing blocks and transactions
+// Generic fragments like "not found" are avoided as they also match e.g. "Method not found"
+var notFoundErrorMessages = []string{
+	"header not found",
+	"block not found",
+	"unknown block",
+	"transaction not found",
+}
+
+// TransportError is returned when a request doesn't reach to JSON RPC server
+type TransportError struct {
+	Url string
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("failed to call JSON RPC, url=%s: %v", e.Url, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatusError is returned when JSON RPC server responds with non-200 status
+type HTTPStatusError struct {
+	Url        string
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("rpc server returns not 200 status, url=%s: %d", e.Url, e.StatusCode)
+}
+
+// DecodeError is returned when response body or result can't be decoded
+type DecodeError struct {
+	Method string
+	Body   string
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode response of %s, %s: %v", e.Method, e.Body, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimitError is returned when JSON RPC server throttles a request
+type RateLimitError struct {
+	Url string
+	// RetryAfter is given by Retry-After header, zero if absent
+	RetryAfter time.Duration
+	Reason     string
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, url=%s: %s", ErrRateLimited.Error(), e.Url, e.Reason)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// Error makes JsonRpcError usable as an error
+func (e *JsonRpcError) Error() string {
+	if len(e.Data) > 0 {
+		return fmt.Sprintf("JSON RPC server returned an error, code=%d, message=%s, data=%s", e.Code, e.Message, string(e.Data))
+	}
+
+	return fmt.Sprintf("JSON RPC server returned an error, code=%d, message=%s", e.Code, e.Message)
+}
+
+// Is reports whether the error means missing resource or throttling regardless of provider
+func (e *JsonRpcError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		// invalid requests never mean missing resource even if the message says so
+		switch e.Code {
+		case CodeParseError, CodeInvalidRequest, CodeMethodNotFound, CodeInvalidParams:
+			return false
+		}
+
+		message := strings.ToLower(e.Message)
+		for _, fragment := range notFoundErrorMessages {
+			if strings.Contains(message, fragment) {
+				return true
+			}
+		}
+
+		return false
+	case ErrRateLimited:
+		return isRateLimitError(e)
+	}
+
+	return false
+}
+
+// IsBlockNotAvailable returns true if the error means that requested block is not mined or not synced yet
+func IsBlockNotAvailable(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsRetryable returns true if the request may succeed by retrying it later
+// Invalid requests, client errors except for timeout and throttling, and responses which can't be decoded are permanent
+// A request failed on every endpoint is retryable if any of the errors is
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if joined, ok := e.(interface{ Unwrap() []error }); ok {
+			return slices.ContainsFunc(joined.Unwrap(), IsRetryable)
+		}
+	}
+
+	// the node returns the same malformed response again
+	var decodeErr *DecodeError
+	if errors.As(err, &decodeErr) {
+		return false
+	}
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusRequestTimeout, statusErr.StatusCode == http.StatusTooManyRequests:
+			return true
+		case statusErr.StatusCode >= 400 && statusErr.StatusCode < 500:
+			return false
+		}
+
+		return true
+	}
+
+	var rpcErr *JsonRpcError
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.Code {
+		case CodeParseError, CodeInvalidRequest, CodeMethodNotFound, CodeInvalidParams:
+			return false
+		}
+
+		return true
+	}
+
+	// transport, rate limit and unknown errors
+	return true
+}
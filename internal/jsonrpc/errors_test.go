@@ -0,0 +1,64 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+)
+
+func TestJsonRpcErrorIsNotFound(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *jsonrpc.JsonRpcError
+		notFound bool
+	}{
+		{"header of geth", &jsonrpc.JsonRpcError{Code: jsonrpc.CodeServerError, Message: "header not found"}, true},
+		{"block of erigon", &jsonrpc.JsonRpcError{Code: jsonrpc.CodeServerError, Message: "block not found"}, true},
+		{"block of besu", &jsonrpc.JsonRpcError{Code: jsonrpc.CodeServerError, Message: "Unknown block"}, true},
+		{"transaction", &jsonrpc.JsonRpcError{Code: jsonrpc.CodeServerError, Message: "transaction not found"}, true},
+		{"method of geth", &jsonrpc.JsonRpcError{Code: jsonrpc.CodeMethodNotFound, Message: "the method eth_foo does not exist/is not available"}, false},
+		{"method of spec", &jsonrpc.JsonRpcError{Code: jsonrpc.CodeMethodNotFound, Message: "Method not found"}, false},
+		{"method with server code", &jsonrpc.JsonRpcError{Code: jsonrpc.CodeServerError, Message: "the method eth_foo does not exist/is not available"}, false},
+		{"invalid params", &jsonrpc.JsonRpcError{Code: jsonrpc.CodeInvalidParams, Message: "block not found"}, false},
+		{"invalid request", &jsonrpc.JsonRpcError{Code: jsonrpc.CodeInvalidRequest, Message: "unknown block"}, false},
+		{"parse error", &jsonrpc.JsonRpcError{Code: jsonrpc.CodeParseError, Message: "header not found"}, false},
+		{"filter", &jsonrpc.JsonRpcError{Code: jsonrpc.CodeServerError, Message: "filter not found"}, false},
+		{"internal error", &jsonrpc.JsonRpcError{Code: jsonrpc.CodeInternalError, Message: "internal error"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if notFound := errors.Is(tt.err, jsonrpc.ErrNotFound); notFound != tt.notFound {
+				t.Fatalf("expected errors.Is(%v, ErrNotFound) to be %t, but got %t", tt.err, tt.notFound, notFound)
+			}
+
+			if notFound := jsonrpc.IsBlockNotAvailable(tt.err); notFound != tt.notFound {
+				t.Fatalf("expected IsBlockNotAvailable(%v) to be %t, but got %t", tt.err, tt.notFound, notFound)
+			}
+		})
+	}
+}
+
+func TestGetBlockByNumberOfUnsupportedMethod(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"the method eth_getBlockByNumber does not exist/is not available"}}`))
+	}))
+	t.Cleanup(api.Close)
+
+	// the block must not be treated as not mined yet, otherwise the parser waits for it forever
+	block, err := jsonrpc.New(&http.Client{}, api.URL).GetBlockByNumber(context.Background(), *big.NewInt(1), true)
+
+	var rpcErr *jsonrpc.JsonRpcError
+	if block != nil || !errors.As(err, &rpcErr) || rpcErr.Code != jsonrpc.CodeMethodNotFound {
+		t.Fatalf("expected error of missing method, but got block=%v, err=%v", block, err)
+	}
+
+	if jsonrpc.IsRetryable(err) {
+		t.Fatalf("expected missing method not to be retryable: %v", err)
+	}
+}
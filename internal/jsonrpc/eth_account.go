@@ -0,0 +1,58 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// BlockTagLatest is a block parameter for the latest block
+const BlockTagLatest = "latest"
+
+// GetBalance queries eth_getBalance request to JSON-RPC server
+// It returns the balance at given height, or at the latest block if height is nil
+func (c *EthJsonRpcClient) GetBalance(ctx context.Context, address types.Address, height *big.Int) (*big.Int, error) {
+	return c.callAccountQuantity(ctx, MethodEthGetBalance, address, height)
+}
+
+// GetTransactionCount queries eth_getTransactionCount request to JSON-RPC server
+// It returns the nonce at given height, or at the latest block if height is nil
+func (c *EthJsonRpcClient) GetTransactionCount(ctx context.Context, address types.Address, height *big.Int) (*big.Int, error) {
+	return c.callAccountQuantity(ctx, MethodEthGetTransactionCount, address, height)
+}
+
+// callAccountQuantity calls a method taking address and block parameter and returning quantity
+func (c *EthJsonRpcClient) callAccountQuantity(
+	ctx context.Context,
+	method string,
+	address types.Address,
+	height *big.Int,
+) (*big.Int, error) {
+	block := BlockTagLatest
+	if height != nil {
+		block = "0x" + height.Text(16)
+	}
+
+	req := NewJsonRpcRequest(method, []interface{}{address, block})
+	res, err := c.call(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	var value types.Quantity
+	if err := json.Unmarshal(res.Result, &value); err != nil {
+		return nil, &DecodeError{Method: req.Method, Body: string(res.Result), Err: err}
+	}
+
+	if value.IsEmpty() {
+		return nil, &DecodeError{Method: method, Body: string(res.Result), Err: ErrEmptyResult}
+	}
+
+	return value.Big(), nil
+}
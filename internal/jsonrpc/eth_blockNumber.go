@@ -3,8 +3,9 @@ package jsonrpc
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"math/big"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
 )
 
 // GetBlockNumber queries eth_blockNumber request to JSON-RPC server
@@ -16,18 +17,17 @@ func (c *EthJsonRpcClient) GetBlockNumber(ctx context.Context) (*big.Int, error)
 	}
 
 	if res.Error != nil {
-		return nil, fmt.Errorf("JSON RPC server returned an error, code=%d, message=%s", res.Error.Code, res.Error.Message)
+		return nil, res.Error
 	}
 
-	var hexHeight string
-	if err := json.Unmarshal(res.Result, &hexHeight); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal json, %s: %w", string(res.Result), err)
+	var height types.Quantity
+	if err := json.Unmarshal(res.Result, &height); err != nil {
+		return nil, &DecodeError{Method: req.Method, Body: string(res.Result), Err: err}
 	}
 
-	height, ok := (&big.Int{}).SetString(hexHeight, 0)
-	if !ok {
-		return nil, fmt.Errorf("failed to parse block height in hex, %s", hexHeight)
+	if height.IsEmpty() {
+		return nil, &DecodeError{Method: req.Method, Body: string(res.Result), Err: ErrEmptyResult}
 	}
 
-	return height, nil
+	return height.Big(), nil
 }
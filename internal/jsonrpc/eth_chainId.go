@@ -0,0 +1,70 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// GetChainId queries eth_chainId request to JSON-RPC server
+func (c *EthJsonRpcClient) GetChainId(ctx context.Context) (*big.Int, error) {
+	req := NewJsonRpcRequest(MethodEthChainId, nil)
+	res, err := c.call(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeChainId(req.Method, res)
+}
+
+// GetEndpointChainIds queries eth_chainId request to each endpoint directly without failover
+// Chain ids are returned in order of endpoints, it fails if any endpoint fails so that every endpoint is checked
+func (c *EthJsonRpcClient) GetEndpointChainIds(ctx context.Context) ([]*big.Int, error) {
+	req := NewJsonRpcRequest(MethodEthChainId, nil)
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize JSON RPC request: %w", err)
+	}
+
+	chainIds := make([]*big.Int, len(c.endpoints))
+	for i, ep := range c.endpoints {
+		if err := ep.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		res, err := c.callEndpoint(ctx, ep.url, req.Method, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("JSON RPC endpoint #%d failed: %w", i, err)
+		}
+
+		chainId, err := decodeChainId(req.Method, res)
+		if err != nil {
+			return nil, fmt.Errorf("JSON RPC endpoint #%d failed: %w", i, err)
+		}
+
+		chainIds[i] = chainId
+	}
+
+	return chainIds, nil
+}
+
+// decodeChainId returns chain id in response of eth_chainId
+func decodeChainId(method string, res *JsonRpcResponse) (*big.Int, error) {
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	var chainId types.Quantity
+	if err := json.Unmarshal(res.Result, &chainId); err != nil {
+		return nil, &DecodeError{Method: method, Body: string(res.Result), Err: err}
+	}
+
+	if chainId.IsEmpty() {
+		return nil, &DecodeError{Method: method, Body: string(res.Result), Err: ErrEmptyResult}
+	}
+
+	return chainId.Big(), nil
+}
@@ -0,0 +1,50 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// NewPendingTransactionFilter queries eth_newPendingTransactionFilter request to JSON-RPC server
+// It returns the id of filter which is used by GetFilterChanges
+func (c *EthJsonRpcClient) NewPendingTransactionFilter(ctx context.Context) (string, error) {
+	req := NewJsonRpcRequest(MethodEthNewPendingTransactionFilter, nil)
+	res, err := c.call(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	if res.Error != nil {
+		return "", res.Error
+	}
+
+	var filterId string
+	if err := json.Unmarshal(res.Result, &filterId); err != nil {
+		return "", &DecodeError{Method: req.Method, Body: string(res.Result), Err: err}
+	}
+
+	return filterId, nil
+}
+
+// GetPendingTransactionFilterChanges queries eth_getFilterChanges request for pending transaction filter
+// It returns hashes of transactions which arrived since last poll
+func (c *EthJsonRpcClient) GetPendingTransactionFilterChanges(ctx context.Context, filterId string) ([]types.Hash, error) {
+	req := NewJsonRpcRequest(MethodEthGetFilterChanges, []interface{}{filterId})
+	res, err := c.call(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	var hashes []types.Hash
+	if err := json.Unmarshal(res.Result, &hashes); err != nil {
+		return nil, &DecodeError{Method: req.Method, Body: string(res.Result), Err: err}
+	}
+
+	return hashes, nil
+}
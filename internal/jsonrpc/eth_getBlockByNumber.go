@@ -3,7 +3,6 @@ package jsonrpc
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"math/big"
 
 	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
@@ -27,11 +26,12 @@ func (c *EthJsonRpcClient) GetBlockByNumber(
 	}
 
 	if res.Error != nil {
-		if res.Error.Message == "Resource not found." {
+		// providers return not found error instead of null for blocks not mined yet
+		if IsBlockNotAvailable(res.Error) {
 			return nil, nil
 		}
 
-		return nil, fmt.Errorf("JSON RPC server returned an error, code=%d, message=%s", res.Error.Code, res.Error.Message)
+		return nil, res.Error
 	}
 
 	if string(res.Result) == "null" {
@@ -40,7 +40,7 @@ func (c *EthJsonRpcClient) GetBlockByNumber(
 
 	block := &types.Block{}
 	if err := json.Unmarshal(res.Result, block); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal json, %s: %w", string(res.Result), err)
+		return nil, &DecodeError{Method: req.Method, Body: string(res.Result), Err: err}
 	}
 
 	return block, nil
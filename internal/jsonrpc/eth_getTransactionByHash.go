@@ -0,0 +1,38 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// GetTransactionByHash queries eth_getTransactionByHash request to JSON-RPC server
+// It returns nil if the transaction is unknown to the server, e.g. dropped from mempool
+func (c *EthJsonRpcClient) GetTransactionByHash(ctx context.Context, hash types.Hash) (*types.Transaction, error) {
+	req := NewJsonRpcRequest(MethodEthGetTransactionByHash, []interface{}{hash})
+	res, err := c.call(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Error != nil {
+		if errors.Is(res.Error, ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, res.Error
+	}
+
+	if string(res.Result) == "null" {
+		return nil, nil
+	}
+
+	tx := &types.Transaction{}
+	if err := json.Unmarshal(res.Result, tx); err != nil {
+		return nil, &DecodeError{Method: req.Method, Body: string(res.Result), Err: err}
+	}
+
+	return tx, nil
+}
@@ -0,0 +1,201 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/rpcfixture"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/verify"
+)
+
+const (
+	// fixturesDir has fixtures in <chain>/<name>.ndjson
+	fixturesDir = "../../testdata/rpc"
+	// replayUrl is the URL of clients replaying fixtures, requests never reach it
+	replayUrl = "http://replay.invalid"
+)
+
+// replayClient returns a client whose requests are answered by the fixture
+func replayClient(t *testing.T, interactions []rpcfixture.Interaction) *jsonrpc.EthJsonRpcClient {
+	t.Helper()
+
+	return jsonrpc.New(&http.Client{Transport: rpcfixture.NewReplayer(interactions)}, replayUrl)
+}
+
+// recordedBlock decodes the recorded block at height with full transactions
+func recordedBlock(t *testing.T, fixture string, height uint64) *types.Block {
+	t.Helper()
+
+	interactions, err := rpcfixture.ReadFile(filepath.Join(fixturesDir, fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := replayClient(t, interactions).GetBlockByNumber(context.Background(), *new(big.Int).SetUint64(height), true)
+	if err != nil {
+		t.Fatalf("failed to decode block %d of %s: %v", height, fixture, err)
+	}
+
+	if block == nil || block.Number.Uint64() != height {
+		t.Fatalf("expected block %d in %s, but got %+v", height, fixture, block)
+	}
+
+	return block
+}
+
+// TestRecordedBlocksAreDecoded replays every recorded eth_getBlockByNumber request of fixtures
+// Blocks of the fake node are verified as well because it seals blocks with valid hashes and roots
+func TestRecordedBlocksAreDecoded(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(fixturesDir, "*", "*.ndjson"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no fixture is found in %s: %v", fixturesDir, err)
+	}
+
+	for _, path := range paths {
+		name, _ := filepath.Rel(fixturesDir, path)
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			interactions, err := rpcfixture.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := replayClient(t, interactions)
+			verifier := verify.New()
+			verifyBlocks := strings.HasPrefix(name, "testchain"+string(filepath.Separator))
+
+			seen := make(map[string]bool)
+			for _, interaction := range interactions {
+				key := string(interaction.Params)
+				if interaction.Method != jsonrpc.MethodEthGetBlockByNumber || seen[key] {
+					continue
+				}
+
+				seen[key] = true
+
+				var params []interface{}
+				if err := json.Unmarshal(interaction.Params, &params); err != nil || len(params) != 2 {
+					t.Fatalf("invalid params of %s: %s", interaction.Method, interaction.Params)
+				}
+
+				raw, _ := params[0].(string)
+				fullTxs, _ := params[1].(bool)
+
+				height, ok := new(big.Int).SetString(raw, 0)
+				if !ok {
+					t.Fatalf("invalid block number of %s: %s", interaction.Method, raw)
+				}
+
+				block, err := client.GetBlockByNumber(context.Background(), *height, fullTxs)
+				if err != nil {
+					t.Errorf("failed to decode block %s: %v", raw, err)
+					continue
+				}
+
+				// block was not mined when it was recorded
+				if block == nil || !verifyBlocks || !fullTxs {
+					continue
+				}
+
+				if err := verifier.VerifyBlock(block); err != nil {
+					t.Errorf("failed to verify block %s: %v", raw, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeBlockWithNullFields(t *testing.T) {
+	block := recordedBlock(t, "mainnet/null-fields.ndjson", 16_000_000)
+
+	// fields introduced by later forks are null
+	if !block.BlobGasUsed.IsEmpty() || !block.ExcessBlobGas.IsEmpty() || block.ParentBeaconBlockRoot != "" ||
+		!block.TotalDifficulty.IsEmpty() || block.WithdrawalsRoot != "" || block.Withdrawals != nil {
+		t.Fatalf("expected null fields to be empty, but got %+v", block)
+	}
+
+	if block.BaseFeePerGas.Uint64() != 0x2540be3ff || len(block.Transactions) != 3 {
+		t.Fatalf("expected base fee and 3 transactions, but got %+v", block)
+	}
+
+	legacy := block.Transactions[0]
+	if legacy.Type != "0x0" || !legacy.ChainId.IsEmpty() || legacy.AccessList != nil || legacy.V != "0x1c" {
+		t.Fatalf("expected legacy transaction without chain id, but got %+v", legacy)
+	}
+
+	creation := block.Transactions[1]
+	if creation.Type != "0x2" || !creation.To.IsEmpty() || creation.Input == "" {
+		t.Fatalf("expected contract creation with null recipient, but got %+v", creation)
+	}
+
+	accessList := block.Transactions[2]
+	if accessList.Type != "0x1" || len(accessList.AccessList) != 1 || len(accessList.AccessList[0].StorageKeys) != 1 {
+		t.Fatalf("expected access list transaction, but got %+v", accessList)
+	}
+}
+
+func TestDecodeContractCreationWithoutRecipient(t *testing.T) {
+	block := recordedBlock(t, "mainnet/contract-creation.ndjson", 22_500_000)
+
+	if len(block.Transactions) != 3 || len(block.Withdrawals) != 1 || block.RequestsHash == "" || block.BlobGasUsed.IsEmpty() {
+		t.Fatalf("expected 3 transactions, a withdrawal and fields of Prague, but got %+v", block)
+	}
+
+	creation := block.Transactions[0]
+	if creation.Type != "0x2" || !creation.To.IsEmpty() || creation.From.IsEmpty() {
+		t.Fatalf("expected contract creation without recipient, but got %+v", creation)
+	}
+
+	blob := block.Transactions[1]
+	if blob.Type != "0x3" || len(blob.BlobVersionedHashes) != 1 || blob.MaxFeePerBlobGas.IsEmpty() {
+		t.Fatalf("expected blob transaction, but got %+v", blob)
+	}
+
+	setCode := block.Transactions[2]
+	if setCode.Type != "0x4" || len(setCode.AuthorizationList) != 1 || setCode.AuthorizationList[0].Address.IsEmpty() {
+		t.Fatalf("expected set code transaction, but got %+v", setCode)
+	}
+}
+
+func TestDecodeDepositTransactionsOfOptimism(t *testing.T) {
+	block := recordedBlock(t, "optimism/deposit.ndjson", 130_000_000)
+
+	if len(block.Transactions) != 3 || block.Withdrawals == nil || len(block.Withdrawals) != 0 {
+		t.Fatalf("expected 3 transactions and empty withdrawals, but got %+v", block)
+	}
+
+	for _, deposit := range block.Transactions[:2] {
+		if deposit.Type != "0x7e" || deposit.GasPrice != "0x0" || !deposit.ChainId.IsEmpty() || deposit.R != "0x0" {
+			t.Fatalf("expected deposit transaction without signature, but got %+v", deposit)
+		}
+	}
+
+	if transfer := block.Transactions[2]; transfer.Type != "0x2" || transfer.ChainId != "0xa" {
+		t.Fatalf("expected dynamic fee transaction of chain 10, but got %+v", transfer)
+	}
+}
+
+func TestDecodeInternalTransactionsOfArbitrum(t *testing.T) {
+	block := recordedBlock(t, "arbitrum/internal-tx.ndjson", 280_000_000)
+
+	if len(block.Transactions) != 3 || block.Withdrawals != nil || block.WithdrawalsRoot != "" {
+		t.Fatalf("expected 3 transactions without withdrawals, but got %+v", block)
+	}
+
+	if internal := block.Transactions[0]; internal.Type != "0x6a" || internal.Gas != "0x0" || !internal.From.Equal(internal.To) {
+		t.Fatalf("expected internal transaction of ArbOS, but got %+v", internal)
+	}
+
+	if deposit := block.Transactions[1]; deposit.Type != "0x64" || deposit.Value.IsEmpty() || deposit.To.IsEmpty() {
+		t.Fatalf("expected retryable deposit transaction, but got %+v", deposit)
+	}
+}
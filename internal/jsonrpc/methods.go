@@ -2,6 +2,12 @@ package jsonrpc
 
 const (
 	// JSON-RPC method values
-	MethodEthBlockNumber      = "eth_blockNumber"
-	MethodEthGetBlockByNumber = "eth_getBlockByNumber"
+	MethodEthBlockNumber                 = "eth_blockNumber"
+	MethodEthChainId                     = "eth_chainId"
+	MethodEthGetBalance                  = "eth_getBalance"
+	MethodEthGetBlockByNumber            = "eth_getBlockByNumber"
+	MethodEthGetFilterChanges            = "eth_getFilterChanges"
+	MethodEthGetTransactionCount         = "eth_getTransactionCount"
+	MethodEthGetTransactionByHash        = "eth_getTransactionByHash"
+	MethodEthNewPendingTransactionFilter = "eth_newPendingTransactionFilter"
 )
@@ -0,0 +1,195 @@
+package jsonrpc
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// pause after 429 without Retry-After, doubled on every consecutive 429
+	DefaultRateLimitBackoff = 1 * time.Second
+	MaxRateLimitBackoff     = 1 * time.Minute
+
+	// rate is halved on 429 down to this ratio of configured rate,
+	// and recovered by recoverRatio of configured rate on every success
+	minRateRatio = 0.1
+	recoverRatio = 0.05
+)
+
+// rateLimitErrorCodes are JSON-RPC error codes used by providers for rate limiting
+var rateLimitErrorCodes = map[int]bool{
+	429:    true, // Alchemy and others use HTTP status as code
+	-32005: true, // EIP-1474 "limit exceeded", used by Infura
+	-32007: true, // QuickNode "request limit reached"
+	-32029: true, // some providers use "too many requests"
+}
+
+// rateLimitErrorMessages are fragments of JSON-RPC error messages meaning rate limiting
+var rateLimitErrorMessages = []string{
+	"rate limit",
+	"too many requests",
+	"request limit",
+	"exceeded its compute units",
+}
+
+// RateLimiter is a token bucket which slows down adaptively when the server throttles requests
+type RateLimiter struct {
+	mu sync.Mutex
+
+	limit  float64 // configured requests per second, zero means unlimited
+	rate   float64 // current requests per second
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	pausedUntil time.Time
+	backoff     time.Duration
+}
+
+// NewRateLimiter creates a limiter allowing perSecond requests with burst, perSecond zero means unlimited
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		limit:   perSecond,
+		rate:    perSecond,
+		burst:   float64(burst),
+		tokens:  float64(burst),
+		last:    time.Now(),
+		backoff: DefaultRateLimitBackoff,
+	}
+}
+
+// Wait blocks until a request is allowed or the context is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		delay := l.reserve()
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// PausedFor returns remaining time of the pause caused by throttling
+func (l *RateLimiter) PausedFor() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return time.Until(l.pausedUntil)
+}
+
+// Throttle pauses requests for retryAfter, or for exponential backoff if it's zero, and lowers the rate
+func (l *RateLimiter) Throttle(retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if retryAfter <= 0 {
+		retryAfter = l.backoff
+		l.backoff = min(l.backoff*2, MaxRateLimitBackoff)
+	}
+
+	if until := time.Now().Add(retryAfter); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+
+	if l.limit > 0 {
+		l.rate = math.Max(l.rate/2, l.limit*minRateRatio)
+		l.tokens = 0
+		l.last = l.pausedUntil
+	}
+}
+
+// Succeed recovers the rate gradually after throttling
+func (l *RateLimiter) Succeed() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.backoff = DefaultRateLimitBackoff
+
+	if l.limit > 0 && l.rate < l.limit {
+		l.rate = math.Min(l.rate+l.limit*recoverRatio, l.limit)
+	}
+}
+
+// reserve takes a token and returns zero, or returns the time to wait for next token
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+
+	if l.limit <= 0 {
+		return 0
+	}
+
+	// refill tokens since last reservation
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// parseRetryAfter parses Retry-After header given in seconds or HTTP date, returns zero if absent or invalid
+func parseRetryAfter(header http.Header) time.Duration {
+	raw := strings.TrimSpace(header.Get("Retry-After"))
+	if raw == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseFloat(raw, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	if date, err := http.ParseTime(raw); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
+
+// isRateLimitError returns true if JSON-RPC error means the request is throttled by provider
+func isRateLimitError(err *JsonRpcError) bool {
+	if err == nil {
+		return false
+	}
+
+	if rateLimitErrorCodes[err.Code] {
+		return true
+	}
+
+	message := strings.ToLower(err.Message)
+	for _, fragment := range rateLimitErrorMessages {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
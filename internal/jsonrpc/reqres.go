@@ -40,6 +40,7 @@ type JsonRpcResponse struct {
 
 // JsonRpcError is an error object in JSON RPC response
 type JsonRpcError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
 }
@@ -0,0 +1,67 @@
+package rlp
+
+import (
+	"math/big"
+)
+
+// Recursive Length Prefix encoding used in Ethereum
+// Encoded values are built bottom-up, list items are already encoded values
+
+const (
+	shortStringOffset = 0x80
+	longStringOffset  = 0xb7
+	shortListOffset   = 0xc0
+	longListOffset    = 0xf7
+
+	// maxShortLength is the maximum payload length encoded with single prefix byte
+	maxShortLength = 55
+)
+
+// EncodeBytes encodes byte array as RLP string
+func EncodeBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < shortStringOffset {
+		return []byte{b[0]}
+	}
+
+	return append(encodeLength(len(b), shortStringOffset, longStringOffset), b...)
+}
+
+// EncodeUint64 encodes unsigned integer as RLP string of big endian bytes without leading zeros
+func EncodeUint64(v uint64) []byte {
+	return EncodeBig(new(big.Int).SetUint64(v))
+}
+
+// EncodeBig encodes non-negative big integer as RLP string, nil is encoded same as zero
+func EncodeBig(v *big.Int) []byte {
+	if v == nil {
+		return EncodeBytes(nil)
+	}
+
+	return EncodeBytes(v.Bytes())
+}
+
+// EncodeList encodes list of already encoded items
+func EncodeList(items ...[]byte) []byte {
+	size := 0
+	for _, item := range items {
+		size += len(item)
+	}
+
+	encoded := encodeLength(size, shortListOffset, longListOffset)
+	for _, item := range items {
+		encoded = append(encoded, item...)
+	}
+
+	return encoded
+}
+
+// encodeLength returns prefix bytes representing payload length
+func encodeLength(length int, shortOffset, longOffset byte) []byte {
+	if length <= maxShortLength {
+		return []byte{shortOffset + byte(length)}
+	}
+
+	lengthBytes := big.NewInt(int64(length)).Bytes()
+
+	return append([]byte{longOffset + byte(len(lengthBytes))}, lengthBytes...)
+}
@@ -0,0 +1,97 @@
+package rpcfixture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// maximum size of a line in fixture file, blocks with many transactions are large
+const maxLineSize = 64 * 1024 * 1024
+
+// Interaction is a recorded JSON-RPC request and the response of server
+// A fixture file has one interaction per line in JSON
+type Interaction struct {
+	Method     string          `json:"method"`
+	Params     json.RawMessage `json:"params"`
+	Status     int             `json:"status"`
+	RetryAfter string          `json:"retryAfter,omitempty"`
+	// Response is the JSON body of response, Body is used instead if the body is not JSON
+	Response json.RawMessage `json:"response,omitempty"`
+	Body     string          `json:"body,omitempty"`
+}
+
+// key returns the key matching a request to its interactions
+func (i *Interaction) key() string {
+	return requestKey(i.Method, i.Params)
+}
+
+// body returns the body of response
+func (i *Interaction) body() []byte {
+	if len(i.Response) > 0 {
+		return i.Response
+	}
+
+	return []byte(i.Body)
+}
+
+// requestKey returns method and compacted params, so that whitespaces don't affect matching
+func requestKey(method string, params json.RawMessage) string {
+	compacted := &bytes.Buffer{}
+	if err := json.Compact(compacted, params); err != nil || compacted.String() == "null" {
+		compacted.Reset()
+		compacted.WriteString("[]")
+	}
+
+	return method + " " + compacted.String()
+}
+
+// ReadFile reads interactions from fixture file
+func ReadFile(path string) ([]Interaction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	interactions, err := Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return interactions, nil
+}
+
+// Read reads interactions in JSON lines, empty lines are skipped
+func Read(r io.Reader) ([]Interaction, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	interactions := make([]Interaction, 0)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		interaction := Interaction{}
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, fmt.Errorf("invalid interaction at line %d: %w", line, err)
+		}
+
+		if interaction.Method == "" || interaction.Status == 0 {
+			return nil, fmt.Errorf("invalid interaction at line %d: method and status are required", line)
+		}
+
+		interactions = append(interactions, interaction)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return interactions, nil
+}
@@ -0,0 +1,148 @@
+package rpcfixture
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Recorder is http.RoundTripper which saves JSON-RPC requests and responses passing through it to fixture file
+// It's given to http.Client of jsonrpc.EthJsonRpcClient, so that responses are decoded by the client as usual
+type Recorder struct {
+	transport http.RoundTripper
+	methods   map[string]bool // recorded methods, all methods if empty
+
+	file    *os.File
+	encoder *json.Encoder
+	closed  bool
+	mutex   sync.Mutex
+}
+
+// NewRecorder creates Recorder appending interactions to file at path
+// Requests are sent by transport, or http.DefaultTransport if nil, only the given methods are recorded if any
+func NewRecorder(path string, transport http.RoundTripper, methods ...string) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory of %s: %w", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	recorded := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		recorded[method] = true
+	}
+
+	return &Recorder{
+		transport: transport,
+		methods:   recorded,
+		file:      file,
+		encoder:   json.NewEncoder(file),
+	}, nil
+}
+
+// RoundTrip sends the request and records it with the response
+// Requests failing before receiving response are not recorded
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		reqBody = body
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	res, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	if err := r.record(reqBody, res, resBody); err != nil {
+		log.Printf("failed to record JSON-RPC interaction: %v", err)
+	}
+
+	return res, nil
+}
+
+// record appends the interaction to file
+func (r *Recorder) record(reqBody []byte, res *http.Response, resBody []byte) error {
+	request := struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}{}
+	if err := json.Unmarshal(reqBody, &request); err != nil {
+		return fmt.Errorf("request is not a single JSON-RPC request: %w", err)
+	}
+
+	if len(r.methods) > 0 && !r.methods[request.Method] {
+		return nil
+	}
+
+	interaction := &Interaction{
+		Method:     request.Method,
+		Params:     request.Params,
+		Status:     res.StatusCode,
+		RetryAfter: res.Header.Get("Retry-After"),
+	}
+
+	compacted := &bytes.Buffer{}
+	if err := json.Compact(compacted, resBody); err == nil {
+		interaction.Response = compacted.Bytes()
+	} else {
+		interaction.Body = string(resBody)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return errors.New("recorder is closed")
+	}
+
+	return r.encoder.Encode(interaction)
+}
+
+// Close stops recording and closes the file
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return nil
+	}
+
+	r.closed = true
+
+	return r.file.Close()
+}
+
+// Stop closes the recorder, it's called when the application terminates
+func (r *Recorder) Stop(context.Context) error {
+	return r.Close()
+}
@@ -0,0 +1,136 @@
+package rpcfixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// CodeNotRecorded is the JSON-RPC error code returned by Replayer for requests missing in fixture
+const CodeNotRecorded = -32603
+
+// Replayer is http.RoundTripper which serves recorded responses without network access
+// Requests are matched by method and params, and interactions of the same request are replayed in recorded order
+// The last one is repeated once all of them are served, e.g. eth_blockNumber keeps returning the last height
+type Replayer struct {
+	interactions map[string][]Interaction
+	served       map[string]int
+	missed       map[string]int
+
+	mutex sync.Mutex
+}
+
+// NewReplayer creates Replayer serving the interactions
+func NewReplayer(interactions []Interaction) *Replayer {
+	r := &Replayer{
+		interactions: make(map[string][]Interaction),
+		served:       make(map[string]int),
+		missed:       make(map[string]int),
+	}
+
+	for _, interaction := range interactions {
+		key := interaction.key()
+		r.interactions[key] = append(r.interactions[key], interaction)
+	}
+
+	return r
+}
+
+// LoadReplayer creates Replayer serving interactions in fixture file
+func LoadReplayer(path string) (*Replayer, error) {
+	interactions, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewReplayer(interactions), nil
+}
+
+// RoundTrip returns recorded response of the request
+// Requests missing in fixture get internal JSON-RPC error, and they are reported by Missed
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	request := struct {
+		Id     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}{}
+
+	if req.Body != nil {
+		defer req.Body.Close()
+
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			return nil, fmt.Errorf("request is not a single JSON-RPC request: %w", err)
+		}
+	}
+
+	interaction, ok := r.next(requestKey(request.Method, request.Params))
+	if !ok {
+		body, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      request.Id,
+			"error": map[string]interface{}{
+				"code":    CodeNotRecorded,
+				"message": fmt.Sprintf("request is not recorded, method=%s, params=%s", request.Method, request.Params),
+			},
+		})
+
+		return newResponse(req, http.StatusOK, "", body), nil
+	}
+
+	return newResponse(req, interaction.Status, interaction.RetryAfter, interaction.body()), nil
+}
+
+// next returns the interaction to replay for the request key
+func (r *Replayer) next(key string) (Interaction, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	interactions, ok := r.interactions[key]
+	if !ok {
+		r.missed[key]++
+		return Interaction{}, false
+	}
+
+	index := min(r.served[key], len(interactions)-1)
+	r.served[key]++
+
+	return interactions[index], true
+}
+
+// Missed returns requests missing in fixture with the number of times they were requested
+func (r *Replayer) Missed() map[string]int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	missed := make(map[string]int, len(r.missed))
+	for key, count := range r.missed {
+		missed[key] = count
+	}
+
+	return missed
+}
+
+func newResponse(req *http.Request, status int, retryAfter string, body []byte) *http.Response {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	if retryAfter != "" {
+		header.Set("Retry-After", retryAfter)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
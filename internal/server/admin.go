@@ -0,0 +1,68 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/auth"
+)
+
+// handlePostAPIKey is a handler for POST /v1/admin/keys
+func (s *EthTransactionsServer) handlePostAPIKey(w http.ResponseWriter, r *http.Request) {
+	// parse request body
+	request := &PostAPIKeyRequest{}
+	if err := s.readRequestBody(r, request); err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// validate request body
+	role, err := auth.ParseRole(request.Role)
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// register
+	key, secret, err := s.Keys.Create(request.Tenant, role)
+	if errors.Is(err, auth.ErrEmptyTenant) {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	} else if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+
+	log.Printf("/v1/admin/keys is called, id=%s, tenant=%s, role=%s", key.Id, key.Tenant, key.Role)
+
+	// return response
+	s.writeResponseWithStatus(w, http.StatusCreated, &PostAPIKeyResponse{
+		Key:    *key,
+		Secret: secret,
+	})
+}
+
+// handleGetAPIKeys is a handler for GET /v1/admin/keys
+func (s *EthTransactionsServer) handleGetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys := s.Keys.List()
+
+	log.Printf("/v1/admin/keys is called, num keys=%d", len(keys))
+
+	s.writeResponse(w, &GetAPIKeysResponse{
+		Keys: keys,
+	})
+}
+
+// handleDeleteAPIKey is a handler for DELETE /v1/admin/keys/{id}
+func (s *EthTransactionsServer) handleDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	key, err := s.Keys.Revoke(r.PathValue("id"))
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeNotFound, err.Error())
+		return
+	}
+
+	log.Printf("/v1/admin/keys is called, revoked id=%s, tenant=%s", key.Id, key.Tenant)
+
+	w.WriteHeader(http.StatusNoContent)
+}
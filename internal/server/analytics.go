@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	// DefaultAnalyticsCounterparties is the number of counterparties returned by analytics API unless limit is given
+	DefaultAnalyticsCounterparties = 10
+	// MaxAnalyticsCounterparties is the maximum limit of counterparties returned by analytics API
+	MaxAnalyticsCounterparties = 100
+)
+
+// handleGetAddressAnalytics is a handler for GET /v1/addresses/{address}/analytics
+// Blocks are selected by fromBlock and toBlock, or by since and until in RFC 3339, all blocks if omitted
+func (s *EthTransactionsServer) handleGetAddressAnalytics(w http.ResponseWriter, r *http.Request) {
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	address, err := parseAddress(r.PathValue("address"))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidAddress, err.Error())
+		return
+	}
+
+	if !s.canReadAddress(r, parser, address) {
+		s.fail(w, r, http.StatusForbidden, ErrCodeForbidden, fmt.Sprintf("address %s is not subscribed by the tenant", address))
+		return
+	}
+
+	// parse query parameters
+	query := r.URL.Query()
+
+	fromBlock, err := parseBlockQuery(query.Get("fromBlock"))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidBlock, err.Error())
+		return
+	}
+
+	toBlock, err := parseBlockQuery(query.Get("toBlock"))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidBlock, err.Error())
+		return
+	}
+
+	since, until, err := timeRangeQuery(r)
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	byBlock := fromBlock != nil || toBlock != nil
+	byTime := !since.IsZero() || !until.IsZero()
+	if byBlock && byTime {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "block range and time range can't be given at once")
+		return
+	}
+
+	limit, err := counterpartiesLimit(query.Get("limit"))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	response := &GetAddressAnalyticsResponse{Address: address}
+
+	// resolve range of blocks
+	from, to, ok := uint64(0), uint64(max(parser.GetCurrentBlock(), 0)), true
+	if byTime {
+		from, to, ok = parser.GetBlockRangeByTime(since, until)
+
+		if !since.IsZero() {
+			response.Since = &since
+		}
+		if !until.IsZero() {
+			response.Until = &until
+		}
+	}
+
+	if fromBlock != nil {
+		from = *fromBlock
+	}
+	if toBlock != nil {
+		to = *toBlock
+	}
+
+	if byBlock && from > to {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidBlock, fmt.Sprintf("fromBlock %d is greater than toBlock %d", from, to))
+		return
+	}
+
+	// get data
+	var analytics *types.AddressAnalytics
+	if ok {
+		response.FromBlock, response.ToBlock = &from, &to
+		analytics = parser.GetAddressAnalytics(address, from, to, limit)
+	} else {
+		// no block having transactions is in the time range, an empty range gives zero aggregates
+		analytics = parser.GetAddressAnalytics(address, 1, 0, limit)
+	}
+
+	response.AddressAnalytics = *analytics
+	response.NetInflow = new(big.Int).Sub(analytics.TotalIn.Big(), analytics.TotalOut.Big()).String()
+
+	log.Printf("/analytics is called, address=%s, from=%d, to=%d, num transactions=%d", address, from, to, analytics.TxCount)
+
+	// return response
+	s.writeResponse(w, types.View(response, viewOptions(r)))
+}
+
+// counterpartiesLimit parses limit query parameter of analytics API
+func counterpartiesLimit(raw string) (int, error) {
+	if raw == "" {
+		return DefaultAnalyticsCounterparties, nil
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > MaxAnalyticsCounterparties {
+		return 0, fmt.Errorf("given limit is invalid, it must be between 1 and %d: %s", MaxAnalyticsCounterparties, raw)
+	}
+
+	return limit, nil
+}
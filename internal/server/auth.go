@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/auth"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	// HeaderAPIKey is a header to give API key, Authorization header with Bearer scheme is accepted as well
+	HeaderAPIKey = "X-API-Key"
+)
+
+// routeAccess is a level of access required to call a route
+type routeAccess int
+
+const (
+	// accessTenant requires API key of any role, data is scoped to the tenant of the key
+	accessTenant routeAccess = iota
+	// accessPublic requires no API key
+	accessPublic
+	// accessAdmin requires API key of admin role
+	accessAdmin
+)
+
+type apiKeyContextKey struct{}
+
+// WithAuth requires API keys for all routes except health checks and API documents
+// Subscriptions are scoped to the tenant of API key, and transactions are returned only for its addresses
+func WithAuth(keys *auth.Store) Option {
+	return func(s *EthTransactionsServer) {
+		s.Keys = keys
+		s.tenants = newTenantSubscriptions()
+	}
+}
+
+// authenticate wraps handler to require API key for given access level
+func (s *EthTransactionsServer) authenticate(access routeAccess, h http.HandlerFunc) http.HandlerFunc {
+	if s.Keys == nil || access == accessPublic {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := s.Keys.Authenticate(apiKeyFromRequest(r))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			s.fail(w, r, http.StatusUnauthorized, ErrCodeUnauthorized, "valid API key is required")
+			return
+		}
+
+		if access == accessAdmin && !key.IsAdmin() {
+			s.fail(w, r, http.StatusForbidden, ErrCodeForbidden, "API key of admin role is required")
+			return
+		}
+
+		h(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key)))
+	}
+}
+
+// apiKeyFromRequest returns API key given in Authorization header or X-API-Key header
+func apiKeyFromRequest(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+
+	return r.Header.Get(HeaderAPIKey)
+}
+
+// requestKey returns API key of the request, nil if authentication is disabled
+func requestKey(r *http.Request) *auth.Key {
+	key, _ := r.Context().Value(apiKeyContextKey{}).(*auth.Key)
+
+	return key
+}
+
+// scopedTenant returns the tenant whose data the request can read, ok is false if the request can read all data
+func scopedTenant(r *http.Request) (tenant string, ok bool) {
+	key := requestKey(r)
+	if key == nil || key.IsAdmin() {
+		return "", false
+	}
+
+	return key.Tenant, true
+}
+
+// subscribeAddresses subscribes to addresses for the tenant of the request
+// It returns the number of addresses newly subscribed by the tenant, or by the parser if authentication is disabled
+// ErrQuotaExceeded is returned if the client has subscribed too many addresses today
+func (s *EthTransactionsServer) subscribeAddresses(r *http.Request, parser Parser, addresses []types.Address) (int, error) {
+	if s.limiter != nil && s.limiter.config.DailySubscriptionQuota > 0 {
+		// concurrent requests of the same addresses must not be counted as new by both
+		s.quotaMu.Lock()
+		defer s.quotaMu.Unlock()
+
+		if err := s.takeSubscriptionQuota(r, s.countNewAddresses(r, parser, addresses)); err != nil {
+			return 0, err
+		}
+	}
+
+	added := parser.SubscribeMany(addresses)
+
+	key := requestKey(r)
+	if key == nil {
+		return added, nil
+	}
+
+	return s.tenants.add(key.Tenant, parser, addresses), nil
+}
+
+// countNewAddresses returns the number of distinct addresses not subscribed by the tenant of the request yet
+func (s *EthTransactionsServer) countNewAddresses(r *http.Request, parser Parser, addresses []types.Address) int {
+	key := requestKey(r)
+	seen := make(map[types.Address]struct{}, len(addresses))
+
+	for _, address := range addresses {
+		lower := address.Lower()
+		if _, ok := seen[lower]; ok {
+			continue
+		}
+
+		if (key == nil && parser.IsSubscribed(address)) || (key != nil && s.tenants.contains(key.Tenant, parser, address)) {
+			continue
+		}
+
+		seen[lower] = struct{}{}
+	}
+
+	return len(seen)
+}
+
+// unsubscribeAddress unsubscribes from address for the tenant of the request
+// Parser keeps observing the address until all tenants unsubscribe, admin unsubscribes it for all tenants
+func (s *EthTransactionsServer) unsubscribeAddress(r *http.Request, parser Parser, address types.Address) bool {
+	key := requestKey(r)
+	if key == nil {
+		return parser.Unsubscribe(address)
+	}
+
+	if key.IsAdmin() {
+		s.tenants.removeAll(parser, address)
+
+		return parser.Unsubscribe(address)
+	}
+
+	removed, orphaned := s.tenants.remove(key.Tenant, parser, address)
+	if orphaned {
+		parser.Unsubscribe(address)
+	}
+
+	return removed
+}
+
+// isSubscribed returns true if the address is subscribed by the tenant of the request
+func (s *EthTransactionsServer) isSubscribed(r *http.Request, parser Parser, address types.Address) bool {
+	if tenant, ok := scopedTenant(r); ok {
+		return s.tenants.contains(tenant, parser, address)
+	}
+
+	return parser.IsSubscribed(address)
+}
+
+// canReadAddress returns true if the request can read data of the address
+func (s *EthTransactionsServer) canReadAddress(r *http.Request, parser Parser, address types.Address) bool {
+	tenant, ok := scopedTenant(r)
+
+	return !ok || s.tenants.contains(tenant, parser, address)
+}
+
+// visibleTransaction returns the transaction if the request can read it
+// Matched addresses are limited to the ones subscribed by the tenant of the request
+func (s *EthTransactionsServer) visibleTransaction(
+	r *http.Request,
+	parser Parser,
+	tx *types.IndexedTransaction,
+) (*types.IndexedTransaction, bool) {
+	tenant, ok := scopedTenant(r)
+	if !ok {
+		return tx, true
+	}
+
+	matched := s.tenants.filter(tenant, parser, tx.MatchedAddresses)
+	if len(matched) == 0 {
+		return nil, false
+	}
+
+	copied := *tx
+	copied.MatchedAddresses = matched
+
+	return &copied, true
+}
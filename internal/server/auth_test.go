@@ -0,0 +1,197 @@
+package server
+
+import (
+	"math/big"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/auth"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// doAs calls API with API key, and decodes JSON response into out if given
+func (s *testServer) doAs(t *testing.T, secret, method, path string, body interface{}, out interface{}) *http.Response {
+	t.Helper()
+
+	req := s.request(t, method, path, body)
+	req.Header.Set(HeaderAPIKey, secret)
+
+	return s.send(t, req, out)
+}
+
+// createKey issues API key of the role for the tenant and returns its secret
+func createKey(t *testing.T, keys *auth.Store, tenant string, role auth.Role) (*auth.Key, string) {
+	t.Helper()
+
+	key, secret, err := keys.Create(tenant, role)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, secret
+}
+
+func TestTenantsCanReadOnlyTheirAddresses(t *testing.T) {
+	keys := auth.NewStore()
+	s := newTestServer(t, WithAuth(keys))
+
+	_, admin := createKey(t, keys, "operator", auth.RoleAdmin)
+	_, tenantA := createKey(t, keys, "a", auth.RoleTenant)
+	_, tenantB := createKey(t, keys, "b", auth.RoleTenant)
+	_, tenantC := createKey(t, keys, "c", auth.RoleTenant)
+
+	alice, bob := s.chain.Accounts()[0], s.chain.Accounts()[1]
+
+	for secret, address := range map[string]types.Address{tenantA: alice, tenantB: bob} {
+		if res := s.doAs(t, secret, http.MethodPut, "/v1/subscriptions/"+string(address), nil, nil); res.StatusCode != http.StatusCreated {
+			t.Fatalf("failed to subscribe %s, status=%d", address, res.StatusCode)
+		}
+	}
+
+	sent, err := s.chain.Send(alice, bob, big.NewInt(1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := s.chain.Mine()
+	s.waitForHeight(t, block.Number.Uint64())
+
+	// an address of another tenant is forbidden
+	response := &ErrorResponse{}
+	res := s.doAs(t, tenantA, http.MethodGet, "/v1/addresses/"+string(bob)+"/transactions", nil, response)
+	if res.StatusCode != http.StatusForbidden || response.Error.Code != ErrCodeForbidden {
+		t.Fatalf("expected 403 %s for address of another tenant, but got status=%d, error=%+v", ErrCodeForbidden, res.StatusCode, response.Error)
+	}
+
+	for _, path := range []string{"/v1/addresses/" + string(bob) + "/analytics", "/v1/addresses/" + string(bob) + "/balance"} {
+		if res := s.doAs(t, tenantA, http.MethodGet, path, nil, nil); res.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected 403 for %s, but got %d", path, res.StatusCode)
+		}
+	}
+
+	// matched addresses of other tenants are hidden in the shared transaction
+	for _, tt := range []struct {
+		secret  string
+		matched []types.Address
+	}{
+		{tenantA, []types.Address{alice.Lower()}},
+		{tenantB, []types.Address{bob.Lower()}},
+		{admin, []types.Address{alice.Lower(), bob.Lower()}},
+	} {
+		tx := &TransactionResponse{}
+		if res := s.doAs(t, tt.secret, http.MethodGet, "/v1/transactions/"+string(sent.Hash), nil, tx); res.StatusCode != http.StatusOK {
+			t.Fatalf("expected transaction to be found, but got %d", res.StatusCode)
+		}
+
+		matched := slices.Clone(tx.MatchedAddresses)
+		slices.Sort(matched)
+		if !slices.Equal(matched, tt.matched) {
+			t.Fatalf("expected matched addresses %v, but got %v", tt.matched, tx.MatchedAddresses)
+		}
+
+		blockTxs := &GetBlockTransactionsResponse{}
+		s.doAs(t, tt.secret, http.MethodGet, "/v1/blocks/"+block.Number.Decimal()+"/transactions", nil, blockTxs)
+		if len(blockTxs.Transactions) != 1 || len(blockTxs.Transactions[0].MatchedAddresses) != len(tt.matched) {
+			t.Fatalf("expected the transaction with %d matched addresses in the block, but got %+v", len(tt.matched), blockTxs.Transactions)
+		}
+	}
+
+	// a tenant without subscriptions can't see the transaction at all
+	if res := s.doAs(t, tenantC, http.MethodGet, "/v1/transactions/"+string(sent.Hash), nil, nil); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for transaction of other tenants, but got %d", res.StatusCode)
+	}
+
+	blockTxs := &GetBlockTransactionsResponse{}
+	s.doAs(t, tenantC, http.MethodGet, "/v1/blocks/"+block.Number.Decimal()+"/transactions", nil, blockTxs)
+	if len(blockTxs.Transactions) != 0 {
+		t.Fatalf("expected no transaction of other tenants in the block, but got %+v", blockTxs.Transactions)
+	}
+
+	// unsubscribing by a tenant doesn't affect another tenant subscribing to the same address
+	s.doAs(t, tenantC, http.MethodPut, "/v1/subscriptions/"+string(alice), nil, nil)
+	s.doAs(t, tenantC, http.MethodDelete, "/v1/subscriptions/"+string(alice), nil, nil)
+	if !s.parser.IsSubscribed(alice) {
+		t.Fatal("expected alice to be observed while another tenant subscribes to it")
+	}
+}
+
+func TestInvalidAPIKeysAreRejected(t *testing.T) {
+	keys := auth.NewStore()
+	s := newTestServer(t, WithAuth(keys))
+
+	_, admin := createKey(t, keys, "operator", auth.RoleAdmin)
+	revoked, revokedSecret := createKey(t, keys, "a", auth.RoleTenant)
+	_, tenant := createKey(t, keys, "a", auth.RoleTenant)
+
+	if res := s.doAs(t, admin, http.MethodDelete, "/v1/admin/keys/"+revoked.Id, nil, nil); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("failed to revoke key, status=%d", res.StatusCode)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		secret string
+	}{
+		{"missing", ""},
+		{"unknown", auth.KeyPrefix + "unknown"},
+		{"revoked", revokedSecret},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &ErrorResponse{}
+			res := s.doAs(t, tt.secret, http.MethodGet, "/v1/blocks/current", nil, response)
+			if res.StatusCode != http.StatusUnauthorized || response.Error.Code != ErrCodeUnauthorized {
+				t.Fatalf("expected 401 %s, but got status=%d, error=%+v", ErrCodeUnauthorized, res.StatusCode, response.Error)
+			}
+
+			if res.Header.Get("WWW-Authenticate") == "" {
+				t.Fatal("expected WWW-Authenticate header")
+			}
+		})
+	}
+
+	// the other key of the tenant is still valid, also in Authorization header
+	req := s.request(t, http.MethodGet, "/v1/blocks/current", nil)
+	req.Header.Set("Authorization", "Bearer "+tenant)
+	if res := s.send(t, req, nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected valid key to be accepted, but got %d", res.StatusCode)
+	}
+
+	// public routes don't require API key
+	if res := s.do(t, http.MethodGet, "/v1/health", nil, nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected health check without API key, but got %d", res.StatusCode)
+	}
+}
+
+func TestTenantKeyIsRejectedOnAdminRoutes(t *testing.T) {
+	keys := auth.NewStore()
+	s := newTestServer(t, WithAuth(keys))
+
+	_, admin := createKey(t, keys, "operator", auth.RoleAdmin)
+	_, tenant := createKey(t, keys, "a", auth.RoleTenant)
+
+	for _, route := range []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/v1/admin/keys"},
+		{http.MethodPost, "/v1/admin/keys"},
+		{http.MethodDelete, "/v1/admin/keys/0123456789abcdef"},
+		{http.MethodGet, "/v1/admin/ingestion"},
+		{http.MethodPost, "/v1/admin/ingestion/pause"},
+		{http.MethodPost, "/v1/admin/reindex"},
+	} {
+		response := &ErrorResponse{}
+		res := s.doAs(t, tenant, route.method, route.path, nil, response)
+		if res.StatusCode != http.StatusForbidden || response.Error.Code != ErrCodeForbidden {
+			t.Fatalf("expected 403 %s for %s %s, but got status=%d, error=%+v", ErrCodeForbidden, route.method, route.path, res.StatusCode, response.Error)
+		}
+	}
+
+	if s.parser.IsPaused() {
+		t.Fatal("expected ingestion not to be paused by tenant key")
+	}
+
+	if res := s.doAs(t, admin, http.MethodGet, "/v1/admin/keys", nil, nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected admin key to be accepted, but got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// handleGetBalance is a handler for GET /balance
+func (s *EthTransactionsServer) handleGetBalance(w http.ResponseWriter, r *http.Request) {
+	// validate request
+	if r.Method != http.MethodGet {
+		s.fail(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	// parse query parameters
+	address, err := parseAddress(addressParam(r))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidAddress, err.Error())
+		return
+	}
+
+	if !s.canReadAddress(r, parser, address) {
+		s.fail(w, r, http.StatusForbidden, ErrCodeForbidden, fmt.Sprintf("address %s is not subscribed by the tenant", address))
+		return
+	}
+
+	height, err := parseBlockQuery(r.URL.Query().Get("block"))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidBlock, err.Error())
+		return
+	}
+
+	// get data
+	state, ok := parser.GetAccountState(address, height)
+
+	log.Printf("/balance is called, address=%s, found=%t", address, ok)
+
+	if !ok {
+		s.fail(w, r, http.StatusNotFound, ErrCodeNotFound, "balance of given address is not tracked at given block")
+		return
+	}
+
+	// return response
+	s.writeResponse(w, types.View(&GetBalanceResponse{AccountState: *state}, viewOptions(r)))
+}
+
+// addressParam returns address given in path of /v1 API or in query parameter of legacy API
+func addressParam(r *http.Request) string {
+	if address := r.PathValue("address"); address != "" {
+		return address
+	}
+
+	return r.URL.Query().Get("address")
+}
+
+// parseBlockQuery parses block height given in decimal or hex, returns nil for latest if empty
+func parseBlockQuery(raw string) (*uint64, error) {
+	if raw == "" || raw == "latest" {
+		return nil, nil
+	}
+
+	height, err := strconv.ParseUint(raw, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("given block is invalid: %w", err)
+	}
+
+	return &height, nil
+}
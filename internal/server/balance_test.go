@@ -0,0 +1,90 @@
+package server
+
+import (
+	"math/big"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/auth"
+)
+
+func TestGetBalance(t *testing.T) {
+	keys := auth.NewStore()
+	s := newTestServer(t, WithAuth(keys))
+
+	_, tenantA := createKey(t, keys, "a", auth.RoleTenant)
+	_, tenantB := createKey(t, keys, "b", auth.RoleTenant)
+
+	alice, bob := s.chain.Accounts()[0], s.chain.Accounts()[1]
+	if res := s.doAs(t, tenantA, http.MethodPut, "/v1/subscriptions/"+string(alice), nil, nil); res.StatusCode != http.StatusCreated {
+		t.Fatalf("failed to subscribe alice, status=%d", res.StatusCode)
+	}
+
+	heights := make([]uint64, 0, 2)
+	balances := make(map[uint64]string)
+	for i := 0; i < 2; i++ {
+		if _, err := s.chain.Send(alice, bob, big.NewInt(1), ""); err != nil {
+			t.Fatal(err)
+		}
+
+		block := s.chain.Mine()
+		s.waitForHeight(t, block.Number.Uint64())
+
+		latest := &GetBalanceResponse{}
+		if res := s.doAs(t, tenantA, http.MethodGet, "/v1/addresses/"+string(alice)+"/balance", nil, latest); res.StatusCode != http.StatusOK {
+			t.Fatalf("expected balance of alice, but got %d", res.StatusCode)
+		}
+
+		if latest.BlockNumber.Uint64() != block.Number.Uint64() || !latest.Address.Equal(alice) {
+			t.Fatalf("expected balance of alice at block %d, but got %+v", block.Number.Uint64(), latest)
+		}
+
+		heights = append(heights, block.Number.Uint64())
+		balances[block.Number.Uint64()] = string(latest.Balance)
+	}
+
+	if balances[heights[0]] == balances[heights[1]] {
+		t.Fatalf("expected balance of alice to change, but got %v", balances)
+	}
+
+	first, second := strconv.FormatUint(heights[0], 10), strconv.FormatUint(heights[1], 10)
+
+	// balance at a past block and at a later block without a state
+	for _, tt := range []struct {
+		block    string
+		expected uint64
+	}{
+		{first, heights[0]},
+		{"0x" + strconv.FormatUint(heights[0], 16), heights[0]},
+		{second, heights[1]},
+		{"100", heights[1]},
+		{"latest", heights[1]},
+	} {
+		state := &GetBalanceResponse{}
+		s.doAs(t, tenantA, http.MethodGet, "/v1/addresses/"+string(alice)+"/balance?block="+tt.block, nil, state)
+		if state.BlockNumber.Uint64() != tt.expected || string(state.Balance) != balances[tt.expected] {
+			t.Fatalf("expected balance %s at block %d for %s, but got %+v", balances[tt.expected], tt.expected, tt.block, state)
+		}
+	}
+
+	for _, tt := range []struct {
+		name   string
+		secret string
+		query  string
+		status int
+		code   ErrorCode
+	}{
+		{"another tenant", tenantB, "", http.StatusForbidden, ErrCodeForbidden},
+		{"before tracking", tenantA, "?block=" + strconv.FormatUint(heights[0]-1, 10), http.StatusNotFound, ErrCodeNotFound},
+		{"invalid block", tenantA, "?block=abc", http.StatusBadRequest, ErrCodeInvalidBlock},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &ErrorResponse{}
+			res := s.doAs(t, tt.secret, http.MethodGet, "/v1/addresses/"+string(alice)+"/balance"+tt.query, nil, response)
+			if res.StatusCode != tt.status || response.Error.Code != tt.code {
+				t.Fatalf("expected %d %s, but got status=%d, error=%+v", tt.status, tt.code, res.StatusCode, response.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	// MaxBulkSubscribeBodySize is the maximum size of request body of POST /subscribe/bulk
+	MaxBulkSubscribeBodySize = 64 << 20
+	// MaxInvalidAddressesInResponse limits invalid addresses reported in response
+	MaxInvalidAddressesInResponse = 100
+)
+
+// handlePostBulkSubscribe is a handler for POST /subscribe/bulk
+// It accepts JSON array of addresses, JSON object having addresses field, or CSV
+func (s *EthTransactionsServer) handlePostBulkSubscribe(w http.ResponseWriter, r *http.Request) {
+	// validate request
+	if r.Method != http.MethodPost {
+		s.fail(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	// parse request body
+	body := http.MaxBytesReader(w, r.Body, MaxBulkSubscribeBodySize)
+
+	var rawAddresses []string
+	if isCSV(r.Header.Get("Content-Type")) {
+		rawAddresses, err = readCSVAddresses(body)
+	} else {
+		rawAddresses, err = readJSONAddresses(body)
+	}
+
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// validate request body
+	response := &PostBulkSubscribeResponse{
+		Invalid: make([]InvalidAddress, 0),
+	}
+
+	addresses := make([]types.Address, 0, len(rawAddresses))
+	for i, raw := range rawAddresses {
+		address, err := parseAddress(raw)
+		if err != nil {
+			response.NumInvalid++
+			if len(response.Invalid) < MaxInvalidAddressesInResponse {
+				response.Invalid = append(response.Invalid, InvalidAddress{
+					Index:   i,
+					Address: raw,
+					Error:   err.Error(),
+				})
+			}
+
+			continue
+		}
+
+		addresses = append(addresses, address)
+	}
+
+	// register
+	response.Subscribed, err = s.subscribeAddresses(r, parser, addresses)
+	if errors.Is(err, ErrQuotaExceeded) {
+		s.fail(w, r, http.StatusTooManyRequests, ErrCodeQuotaExceeded, err.Error())
+		return
+	} else if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	response.AlreadySubscribed = len(addresses) - response.Subscribed
+
+	log.Printf(
+		"/subscribe/bulk is called, subscribed=%d, already subscribed=%d, invalid=%d",
+		response.Subscribed, response.AlreadySubscribed, response.NumInvalid,
+	)
+
+	// return response
+	s.writeResponse(w, response)
+}
+
+// isCSV returns true if content type is CSV
+func isCSV(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	return err == nil && mediaType == "text/csv"
+}
+
+// readJSONAddresses reads either ["0x..."] or {"addresses": ["0x..."]}
+func readJSONAddresses(r io.Reader) ([]string, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse request body: %w", err)
+	}
+
+	var addresses []string
+	if err := json.Unmarshal(raw, &addresses); err == nil {
+		return addresses, nil
+	}
+
+	request := &PostBulkSubscribeRequest{}
+	if err := json.Unmarshal(raw, request); err != nil {
+		return nil, errors.New("request body must be an array of addresses or an object having addresses")
+	}
+
+	return request.Addresses, nil
+}
+
+// readCSVAddresses reads addresses from the column named address, or the first column if there is no header
+func readCSVAddresses(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	column := 0
+	addresses := make([]string, 0)
+	for line := 0; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV: %w", err)
+		}
+
+		// header
+		if line == 0 {
+			if idx := indexOfColumn(record, "address"); idx >= 0 {
+				column = idx
+				continue
+			}
+		}
+
+		if column >= len(record) || strings.TrimSpace(record[column]) == "" {
+			continue
+		}
+
+		addresses = append(addresses, strings.TrimSpace(record[column]))
+	}
+
+	return addresses, nil
+}
+
+// indexOfColumn returns the index of column of given name, or -1
+func indexOfColumn(header []string, name string) int {
+	for i, column := range header {
+		if strings.EqualFold(strings.TrimSpace(column), name) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	// checksummedAddress is a vector of EIP-55
+	checksummedAddress = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	anotherAddress     = "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"
+)
+
+// postBulk posts raw body of given content type to bulk subscription API
+func (s *testServer) postBulk(t *testing.T, contentType, body string, out interface{}) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, s.api.URL+"/v1/subscriptions", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	return s.send(t, req, out)
+}
+
+func TestBulkSubscribeJSON(t *testing.T) {
+	s := newTestServer(t)
+
+	// the same address in different case is a duplicate
+	response := &PostBulkSubscribeResponse{}
+	res := s.do(t, http.MethodPost, "/v1/subscriptions", []string{
+		checksummedAddress,
+		"0x1234",
+		strings.ToLower(checksummedAddress),
+		"hello",
+		anotherAddress,
+	}, response)
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, but got %d", res.StatusCode)
+	}
+
+	if response.Subscribed != 2 || response.AlreadySubscribed != 1 || response.NumInvalid != 2 {
+		t.Fatalf("expected 2 subscribed, 1 duplicate and 2 invalid, but got %+v", response)
+	}
+
+	// invalid rows are reported by their positions in the request
+	if len(response.Invalid) != 2 ||
+		response.Invalid[0].Index != 1 || response.Invalid[0].Address != "0x1234" || response.Invalid[0].Error == "" ||
+		response.Invalid[1].Index != 3 || response.Invalid[1].Address != "hello" || response.Invalid[1].Error == "" {
+		t.Fatalf("expected rows 1 and 3 to be invalid, but got %+v", response.Invalid)
+	}
+
+	for _, address := range []types.Address{checksummedAddress, anotherAddress} {
+		if !s.parser.IsSubscribed(address) {
+			t.Fatalf("expected %s to be subscribed", address)
+		}
+	}
+
+	// object form, addresses subscribed already are counted separately
+	response = &PostBulkSubscribeResponse{}
+	s.do(t, http.MethodPost, "/v1/subscriptions", &PostBulkSubscribeRequest{
+		Addresses: []string{anotherAddress, "0x" + strings.Repeat("ab", 20)},
+	}, response)
+
+	if response.Subscribed != 1 || response.AlreadySubscribed != 1 || response.NumInvalid != 0 || len(response.Invalid) != 0 {
+		t.Fatalf("expected 1 subscribed and 1 already subscribed, but got %+v", response)
+	}
+}
+
+func TestBulkSubscribeCSV(t *testing.T) {
+	s := newTestServer(t)
+
+	// address column is found by header, empty rows are skipped
+	response := &PostBulkSubscribeResponse{}
+	res := s.postBulk(t, "text/csv; charset=utf-8", strings.Join([]string{
+		"label, Address",
+		"treasury, " + checksummedAddress,
+		"broken, 0xzz",
+		"",
+		"duplicate, " + strings.ToUpper(checksummedAddress[2:]),
+		"other, " + anotherAddress,
+	}, "\n"), response)
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, but got %d", res.StatusCode)
+	}
+
+	if response.Subscribed != 2 || response.NumInvalid != 2 || response.AlreadySubscribed != 0 {
+		t.Fatalf("expected 2 subscribed and 2 invalid, but got %+v", response)
+	}
+
+	// address without 0x prefix is invalid
+	if len(response.Invalid) != 2 || response.Invalid[0].Index != 1 || response.Invalid[0].Address != "0xzz" ||
+		response.Invalid[1].Index != 2 || response.Invalid[1].Address != strings.ToUpper(checksummedAddress[2:]) {
+		t.Fatalf("expected addresses 1 and 2 to be invalid, but got %+v", response.Invalid)
+	}
+
+	// the first column is used without header
+	response = &PostBulkSubscribeResponse{}
+	s.postBulk(t, "text/csv", checksummedAddress+",treasury\n0x"+strings.Repeat("cd", 20)+",new\n", response)
+
+	if response.Subscribed != 1 || response.AlreadySubscribed != 1 || response.NumInvalid != 0 {
+		t.Fatalf("expected 1 subscribed and 1 already subscribed, but got %+v", response)
+	}
+}
+
+func TestBulkSubscribeInvalidBody(t *testing.T) {
+	s := newTestServer(t)
+
+	for _, tt := range []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"malformed JSON", "application/json", `["` + anotherAddress},
+		{"JSON of other type", "application/json", `{"addresses": 1}`},
+		{"malformed CSV", "text/csv", "address\n\"" + anotherAddress + "\"x\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &ErrorResponse{}
+			res := s.postBulk(t, tt.contentType, tt.body, response)
+			if res.StatusCode != http.StatusBadRequest || response.Error.Code != ErrCodeInvalidRequest {
+				t.Fatalf("expected 400 %s, but got status=%d, error=%+v", ErrCodeInvalidRequest, res.StatusCode, response.Error)
+			}
+		})
+	}
+
+	if s.parser.IsSubscribed(anotherAddress) {
+		t.Fatal("expected nothing to be subscribed by invalid bodies")
+	}
+}
+
+func TestBulkSubscribeReportsLimitedInvalidAddresses(t *testing.T) {
+	s := newTestServer(t)
+
+	addresses := make([]string, 0, MaxInvalidAddressesInResponse+11)
+	for i := 0; i < MaxInvalidAddressesInResponse+10; i++ {
+		addresses = append(addresses, fmt.Sprintf("invalid-%d", i))
+	}
+	addresses = append(addresses, anotherAddress)
+
+	response := &PostBulkSubscribeResponse{}
+	s.do(t, http.MethodPost, "/v1/subscriptions", addresses, response)
+
+	if response.Subscribed != 1 || response.NumInvalid != MaxInvalidAddressesInResponse+10 {
+		t.Fatalf("expected 1 subscribed and %d invalid, but got %+v", MaxInvalidAddressesInResponse+10, response)
+	}
+
+	if len(response.Invalid) != MaxInvalidAddressesInResponse || response.Invalid[0].Address != "invalid-0" {
+		t.Fatalf("expected first %d invalid addresses, but got %d", MaxInvalidAddressesInResponse, len(response.Invalid))
+	}
+}
+
+func TestBulkSubscribeExceedingQuotaSubscribesNothing(t *testing.T) {
+	s := newTestServer(t, WithRateLimits(RateLimitConfig{DailySubscriptionQuota: 2}))
+
+	addresses := []string{checksummedAddress, anotherAddress, "0x" + strings.Repeat("ab", 20)}
+
+	response := &ErrorResponse{}
+	res := s.do(t, http.MethodPost, "/v1/subscriptions", addresses, response)
+	if res.StatusCode != http.StatusTooManyRequests || response.Error.Code != ErrCodeQuotaExceeded {
+		t.Fatalf("expected 429 %s, but got status=%d, error=%+v", ErrCodeQuotaExceeded, res.StatusCode, response.Error)
+	}
+
+	for _, address := range addresses {
+		if s.parser.IsSubscribed(types.Address(address)) {
+			t.Fatalf("expected %s not to be subscribed by rejected request", address)
+		}
+	}
+
+	// quota is not consumed by the rejected request, and duplicates and invalid rows don't consume it either
+	subscribed := &PostBulkSubscribeResponse{}
+	res = s.do(t, http.MethodPost, "/v1/subscriptions", []string{checksummedAddress, strings.ToLower(checksummedAddress), anotherAddress, "0x1234"}, subscribed)
+	if res.StatusCode != http.StatusOK || subscribed.Subscribed != 2 || subscribed.AlreadySubscribed != 1 || subscribed.NumInvalid != 1 {
+		t.Fatalf("expected 2 subscribed within quota, but got status=%d, response=%+v", res.StatusCode, subscribed)
+	}
+}
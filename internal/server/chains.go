@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Chain is a chain indexed by a parser
+type Chain struct {
+	Name    string
+	ChainId uint64
+	Parser  Parser
+}
+
+// WithChains makes chains accessible by /chains/{chain} routes, {chain} is either name or chain id
+func WithChains(chains ...Chain) Option {
+	return func(s *EthTransactionsServer) {
+		s.Chains = append(s.Chains, chains...)
+	}
+}
+
+// resolveParser returns the parser of chain specified in path, or the parser of default chain for routes without chain
+func (s *EthTransactionsServer) resolveParser(r *http.Request) (Parser, error) {
+	key := r.PathValue("chain")
+	if key == "" {
+		return s.Parser, nil
+	}
+
+	chain := s.findChain(key)
+	if chain == nil {
+		return nil, fmt.Errorf("chain %s is not found", key)
+	}
+
+	return chain.Parser, nil
+}
+
+// findChain looks up chain by name or chain id in decimal
+func (s *EthTransactionsServer) findChain(key string) *Chain {
+	chainId, err := strconv.ParseUint(key, 10, 64)
+	isChainId := err == nil
+
+	for i := range s.Chains {
+		chain := &s.Chains[i]
+		if chain.Name == key || (isChainId && chain.ChainId == chainId) {
+			return chain
+		}
+	}
+
+	return nil
+}
+
+// handleGetChains is a handler for GET /chains
+func (s *EthTransactionsServer) handleGetChains(w http.ResponseWriter, r *http.Request) {
+	// validate request
+	if r.Method != http.MethodGet {
+		s.fail(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	chains := make([]ChainResponse, len(s.Chains))
+	for i, chain := range s.Chains {
+		chains[i] = ChainResponse{
+			Name:         chain.Name,
+			ChainId:      chain.ChainId,
+			CurrentBlock: chain.Parser.GetCurrentBlock(),
+		}
+	}
+
+	log.Printf("/chains is called, num chains=%d", len(chains))
+
+	s.writeResponse(w, &GetChainsResponse{
+		Chains: chains,
+	})
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"log"
+	"net/http"
+)
+
+// WithSupervisor enables GET /v1/components API showing states and crash histories of supervised components
+func WithSupervisor(supervisor Supervisor) Option {
+	return func(s *EthTransactionsServer) {
+		s.Supervisor = supervisor
+	}
+}
+
+// handleGetComponents is a handler for GET /v1/components
+func (s *EthTransactionsServer) handleGetComponents(w http.ResponseWriter, r *http.Request) {
+	components := s.Supervisor.Components()
+
+	log.Printf("/v1/components is called, num components=%d", len(components))
+
+	s.writeResponse(w, &GetComponentsResponse{
+		Components: components,
+	})
+}
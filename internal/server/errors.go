@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// ErrorCode is a machine readable error code in error responses of /v1 API
+type ErrorCode string
+
+const (
+	ErrCodeInvalidRequest    ErrorCode = "invalid_request"
+	ErrCodeInvalidAddress    ErrorCode = "invalid_address"
+	ErrCodeInvalidHash       ErrorCode = "invalid_hash"
+	ErrCodeInvalidBlock      ErrorCode = "invalid_block"
+	ErrCodeInvalidABI        ErrorCode = "invalid_abi"
+	ErrCodeUnsupportedFormat ErrorCode = "unsupported_format"
+	ErrCodeUnauthorized      ErrorCode = "unauthorized"
+	ErrCodeForbidden         ErrorCode = "forbidden"
+	ErrCodeRateLimited       ErrorCode = "rate_limited"
+	ErrCodeQuotaExceeded     ErrorCode = "quota_exceeded"
+	ErrCodeNotFound          ErrorCode = "not_found"
+	ErrCodeConflict          ErrorCode = "conflict"
+	ErrCodeChainNotFound     ErrorCode = "chain_not_found"
+	ErrCodeMethodNotAllowed  ErrorCode = "method_not_allowed"
+	ErrCodeUnavailable       ErrorCode = "unavailable"
+	ErrCodeInternal          ErrorCode = "internal_error"
+)
+
+type errorStyleKey struct{}
+
+// withJSONErrors makes handlers respond errors in JSON envelope instead of plain text
+func withJSONErrors(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h(w, r.WithContext(context.WithValue(r.Context(), errorStyleKey{}, true)))
+	}
+}
+
+// fail writes an error response, in JSON envelope for /v1 API or in plain text for legacy API
+func (s *EthTransactionsServer) fail(w http.ResponseWriter, r *http.Request, status int, code ErrorCode, message string) {
+	if jsonErrors, _ := r.Context().Value(errorStyleKey{}).(bool); !jsonErrors {
+		http.Error(w, message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(&ErrorResponse{
+		Error: ErrorBody{
+			Code:    code,
+			Message: message,
+			Status:  status,
+		},
+	})
+}
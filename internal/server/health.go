@@ -0,0 +1,77 @@
+package server
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/pkg/parser"
+)
+
+// handleGetHealth is a handler for GET /health
+// It responds with 503 status unless parsers of all chains are healthy
+func (s *EthTransactionsServer) handleGetHealth(w http.ResponseWriter, r *http.Request) {
+	// validate request
+	if r.Method != http.MethodGet {
+		s.fail(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	chains := s.Chains
+	if len(chains) == 0 {
+		chains = []Chain{{Parser: s.Parser}}
+	}
+
+	response := &GetHealthResponse{
+		Status: parser.HealthStateHealthy,
+		Chains: make([]ChainHealthResponse, len(chains)),
+	}
+
+	for i, chain := range chains {
+		health := chain.Parser.Health()
+
+		response.Chains[i] = ChainHealthResponse{
+			Name:    chain.Name,
+			ChainId: chain.ChainId,
+			Health:  health,
+		}
+
+		// failed is worse than degraded
+		if health.State != parser.HealthStateHealthy && response.Status != parser.HealthStateFailed {
+			response.Status = health.State
+		}
+	}
+
+	log.Printf("/health is called, status=%s", response.Status)
+
+	s.writeResponseWithStatus(w, healthStatusCode(response.Status), response)
+}
+
+// handleGetChainHealth is a handler for GET /chains/{chain}/health
+func (s *EthTransactionsServer) handleGetChainHealth(w http.ResponseWriter, r *http.Request) {
+	// validate request
+	if r.Method != http.MethodGet {
+		s.fail(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	health := parser.Health()
+
+	log.Printf("/chains/%s/health is called, state=%s", r.PathValue("chain"), health.State)
+
+	s.writeResponseWithStatus(w, healthStatusCode(health.State), &health)
+}
+
+// healthStatusCode returns HTTP status for health checks of load balancers and orchestrators
+func healthStatusCode(state parser.HealthState) int {
+	if state == parser.HealthStateHealthy {
+		return http.StatusOK
+	}
+
+	return http.StatusServiceUnavailable
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/pkg/parser"
+)
+
+// seekTimeout bounds the wait for the parser to store fetched blocks before seeking
+const seekTimeout = 30 * time.Second
+
+// handleGetIngestion is a handler for GET /v1/admin/ingestion
+func (s *EthTransactionsServer) handleGetIngestion(w http.ResponseWriter, r *http.Request) {
+	prs, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	response := ingestionResponse(prs)
+
+	log.Printf("/v1/admin/ingestion is called, paused=%t, current block=%d", response.Paused, response.CurrentBlock)
+
+	s.writeResponse(w, response)
+}
+
+// handlePostPause is a handler for POST /v1/admin/ingestion/pause
+func (s *EthTransactionsServer) handlePostPause(w http.ResponseWriter, r *http.Request) {
+	prs, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	paused := prs.Pause()
+
+	log.Printf("/v1/admin/ingestion/pause is called, paused=%t", paused)
+
+	s.writeResponse(w, ingestionResponse(prs))
+}
+
+// handlePostResume is a handler for POST /v1/admin/ingestion/resume
+func (s *EthTransactionsServer) handlePostResume(w http.ResponseWriter, r *http.Request) {
+	prs, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	resumed := prs.Resume()
+
+	log.Printf("/v1/admin/ingestion/resume is called, resumed=%t", resumed)
+
+	s.writeResponse(w, ingestionResponse(prs))
+}
+
+// handlePostSeek is a handler for POST /v1/admin/ingestion/seek
+func (s *EthTransactionsServer) handlePostSeek(w http.ResponseWriter, r *http.Request) {
+	prs, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	// parse request body
+	request := &PostSeekRequest{}
+	if err := s.readRequestBody(r, request); err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), seekTimeout)
+	defer cancel()
+
+	deleted, err := prs.Seek(ctx, request.Height)
+	if errors.Is(err, parser.ErrInvalidHeight) {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidBlock, err.Error())
+		return
+	} else if errors.Is(err, parser.ErrReindexInProgress) {
+		s.fail(w, r, http.StatusConflict, ErrCodeConflict, err.Error())
+		return
+	} else if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+
+	log.Printf("/v1/admin/ingestion/seek is called, height=%d, deleted=%d", request.Height, deleted)
+
+	s.writeResponse(w, &PostSeekResponse{
+		IngestionResponse: *ingestionResponse(prs),
+		Deleted:           deleted,
+	})
+}
+
+// handlePostReindex is a handler for POST /v1/admin/reindex
+func (s *EthTransactionsServer) handlePostReindex(w http.ResponseWriter, r *http.Request) {
+	prs, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	// parse request body
+	request := &PostReindexRequest{}
+	if err := s.readRequestBody(r, request); err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// validate request body
+	var address *types.Address
+	if request.Address != "" {
+		parsed, err := parseAddress(request.Address)
+		if err != nil {
+			s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidAddress, err.Error())
+			return
+		}
+
+		address = &parsed
+	}
+
+	status, err := prs.Reindex(request.From, request.To, address)
+	if errors.Is(err, parser.ErrInvalidRange) {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidBlock, err.Error())
+		return
+	} else if errors.Is(err, parser.ErrReindexInProgress) {
+		s.fail(w, r, http.StatusConflict, ErrCodeConflict, err.Error())
+		return
+	} else if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+
+	log.Printf("/v1/admin/reindex is called, from=%d, to=%d, address=%s", status.From, status.To, status.Address)
+
+	s.writeResponseWithStatus(w, http.StatusAccepted, status)
+}
+
+// handleGetReindex is a handler for GET /v1/admin/reindex
+func (s *EthTransactionsServer) handleGetReindex(w http.ResponseWriter, r *http.Request) {
+	prs, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	status, ok := prs.ReindexStatus()
+	if !ok {
+		s.fail(w, r, http.StatusNotFound, ErrCodeNotFound, "reindex has never been requested")
+		return
+	}
+
+	log.Printf("/v1/admin/reindex is called, state=%s, next height=%d", status.State, status.NextHeight)
+
+	s.writeResponse(w, status)
+}
+
+// handleDeleteReindex is a handler for DELETE /v1/admin/reindex
+func (s *EthTransactionsServer) handleDeleteReindex(w http.ResponseWriter, r *http.Request) {
+	prs, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	if !prs.CancelReindex() {
+		s.fail(w, r, http.StatusNotFound, ErrCodeNotFound, "no reindex is running")
+		return
+	}
+
+	log.Printf("/v1/admin/reindex is called, cancelled")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ingestionResponse returns the state of ingestion of the parser
+func ingestionResponse(prs Parser) *IngestionResponse {
+	response := &IngestionResponse{
+		Paused:       prs.IsPaused(),
+		CurrentBlock: prs.GetCurrentBlock(),
+	}
+
+	if next, ok := prs.NextHeight(); ok {
+		response.NextHeight = &next
+	}
+
+	return response
+}
@@ -1,14 +1,75 @@
 package server
 
 import (
+	"context"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/abi"
 	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/pkg/parser"
 )
 
 type Parser interface {
 	// last parsed block
 	GetCurrentBlock() int
 	// add address to observer
-	Subscribe(address string) bool
+	Subscribe(address types.Address) bool
+	// remove address from observer
+	Unsubscribe(address types.Address) bool
+	// whether address is observed
+	IsSubscribed(address types.Address) bool
+	// add addresses to observer at once, returns the number of newly added ones
+	SubscribeMany(addresses []types.Address) int
 	// list of inbound or outbound transactions for an address
-	GetTransactions(address string) []types.Transaction
+	GetTransactions(address types.Address) []types.Transaction
+	// indexed transaction of given hash
+	GetTransactionByHash(hash types.Hash) (*types.IndexedTransaction, bool)
+	// indexed transactions in the block of given height
+	GetTransactionsByBlock(height uint64) []types.IndexedTransaction
+	// list of transactions for an address seen in mempool
+	GetPendingTransactions(address types.Address) []types.PendingTransaction
+	// list of transactions for an address in blocks whose timestamp is in [from, to), zero means unbounded
+	GetTransactionsByTimeRange(address types.Address, from, to time.Time) []types.Transaction
+	// iterate inbound or outbound transactions for an address one by one
+	IterateTransactions(address types.Address, fn func(*types.Transaction) error) error
+	// aggregated values, fees and counterparties of transactions for an address in blocks from..to inclusive
+	GetAddressAnalytics(address types.Address, from, to uint64, maxCounterparties int) *types.AddressAnalytics
+	// first and last blocks having indexed transactions whose timestamp is in [since, until)
+	GetBlockRangeByTime(since, until time.Time) (from uint64, to uint64, ok bool)
+	// summary of block at given height, only for blocks having indexed transactions
+	GetBlockHeader(height uint64) (*types.BlockHeader, bool)
+	// timestamp of block at given height
+	GetBlockTimestamp(height uint64) (time.Time, bool)
+	// balance and nonce of an address at or before given height, latest one if height is nil
+	GetAccountState(address types.Address, height *uint64) (*types.AccountState, bool)
+	// state of block ingestion
+	Health() parser.Health
+	// stop fetching new blocks until resumed, false if paused already
+	Pause() bool
+	// restart fetching blocks, false if not paused
+	Resume() bool
+	// whether fetching blocks is paused
+	IsPaused() bool
+	// height of the block to be processed next
+	NextHeight() (uint64, bool)
+	// process blocks from height next, data of blocks from height is deleted on rewind
+	Seek(ctx context.Context, height uint64) (int, error)
+	// delete and ingest again transactions in blocks from..to, only for the address if given
+	Reindex(from, to uint64, address *types.Address) (*parser.ReindexStatus, error)
+	// status of the last reindex job
+	ReindexStatus() (*parser.ReindexStatus, bool)
+	// cancel running reindex job, false if no job is running
+	CancelReindex() bool
+}
+
+type Supervisor interface {
+	// states and crash histories of supervised components
+	Components() []parser.ComponentStatus
+}
+
+type ABIRegistry interface {
+	// register contract ABI in JSON for an address
+	Register(address types.Address, abiJSON []byte) error
+	// decode transaction input, returns nil if no method matches
+	Decode(to types.Address, input types.Data) *abi.DecodedCall
 }
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// handleGetTransactionByHash is a handler for GET /transactions/{hash}
+func (s *EthTransactionsServer) handleGetTransactionByHash(w http.ResponseWriter, r *http.Request) {
+	// validate request
+	if r.Method != http.MethodGet {
+		s.fail(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	hash, err := parseHash(r.PathValue("hash"))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidHash, err.Error())
+		return
+	}
+
+	// get data
+	tx, ok := parser.GetTransactionByHash(hash)
+	if ok {
+		tx, ok = s.visibleTransaction(r, parser, tx)
+	}
+
+	log.Printf("/transactions/%s is called, found=%t", hash, ok)
+
+	if !ok {
+		s.fail(w, r, http.StatusNotFound, ErrCodeNotFound, "transaction is not indexed")
+		return
+	}
+
+	// return response
+	s.writeResponse(w, types.View(s.toIndexedTransactionResponse(parser, tx), viewOptions(r)))
+}
+
+// handleGetBlockTransactions is a handler for GET /blocks/{number}/transactions
+func (s *EthTransactionsServer) handleGetBlockTransactions(w http.ResponseWriter, r *http.Request) {
+	// validate request
+	if r.Method != http.MethodGet {
+		s.fail(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	height, err := strconv.ParseUint(r.PathValue("number"), 0, 64)
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidBlock, fmt.Sprintf("given block number is invalid: %v", err))
+		return
+	}
+
+	// blocks ahead of parser may have transactions to be indexed
+	if height > uint64(parser.GetCurrentBlock()) {
+		s.fail(w, r, http.StatusNotFound, ErrCodeNotFound, "block is not processed yet")
+		return
+	}
+
+	// get data
+	txs := parser.GetTransactionsByBlock(height)
+
+	log.Printf("/blocks/%d/transactions is called, num transactions=%d", height, len(txs))
+
+	response := &GetBlockTransactionsResponse{
+		BlockNumber:  types.NewQuantityFromUint64(height),
+		Transactions: make([]TransactionResponse, 0, len(txs)),
+	}
+
+	for i := range txs {
+		if tx, ok := s.visibleTransaction(r, parser, &txs[i]); ok {
+			response.Transactions = append(response.Transactions, *s.toIndexedTransactionResponse(parser, tx))
+		}
+	}
+
+	// return response
+	s.writeResponse(w, types.View(response, viewOptions(r)))
+}
+
+// toIndexedTransactionResponse attaches metadata to stored transaction
+func (s *EthTransactionsServer) toIndexedTransactionResponse(parser Parser, tx *types.IndexedTransaction) *TransactionResponse {
+	response := &TransactionResponse{
+		Transaction:      tx.Transaction,
+		Status:           types.TxStatusConfirmed,
+		MatchedAddresses: tx.MatchedAddresses,
+		DecodedInput:     s.decodeInput(&tx.Transaction),
+	}
+
+	attachBlockHeader(parser, response)
+
+	return response
+}
+
+// parseHash checks that given hash is 32 bytes hex and returns it in lower case
+func parseHash(raw string) (types.Hash, error) {
+	hash, err := types.ParseHash(raw)
+	if err != nil {
+		return "", fmt.Errorf("given hash is invalid: %s", raw)
+	}
+
+	return types.Hash(strings.ToLower(string(hash))), nil
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/auth"
+)
+
+// serverMetrics keeps counters exposed by /metrics in Prometheus text format
+type serverMetrics struct {
+	rateLimitedRequests sync.Map // group -> *[2]atomic.Uint64 of allowed and rejected requests
+	keyRequests         sync.Map // API key id -> *keyCounters
+	quotaRejections     atomic.Uint64
+}
+
+// keyCounters counts requests of an API key checked by rate limiter
+type keyCounters struct {
+	tenant   string
+	requests [2]atomic.Uint64 // allowed and rejected requests
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{}
+}
+
+// countRequest counts a request of the group checked by rate limiter
+func (m *serverMetrics) countRequest(group string, allowed bool) {
+	value, _ := m.rateLimitedRequests.LoadOrStore(group, &[2]atomic.Uint64{})
+	counters := value.(*[2]atomic.Uint64)
+
+	if allowed {
+		counters[0].Add(1)
+	} else {
+		counters[1].Add(1)
+	}
+}
+
+// countKeyRequest counts a request of the API key checked by rate limiter
+func (m *serverMetrics) countKeyRequest(key *auth.Key, allowed bool) {
+	value, _ := m.keyRequests.LoadOrStore(key.Id, &keyCounters{tenant: key.Tenant})
+	counters := value.(*keyCounters)
+
+	if allowed {
+		counters.requests[0].Add(1)
+	} else {
+		counters.requests[1].Add(1)
+	}
+}
+
+// countQuotaRejection counts a subscription rejected by daily quota
+func (m *serverMetrics) countQuotaRejection() {
+	m.quotaRejections.Add(1)
+}
+
+// handleGetMetrics is a handler for GET /metrics
+func (s *EthTransactionsServer) handleGetMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	s.writeMetrics(w)
+}
+
+// writeMetrics writes metrics in Prometheus text format
+func (s *EthTransactionsServer) writeMetrics(w io.Writer) {
+	groups := make([]string, 0)
+	s.metrics.rateLimitedRequests.Range(func(key, _ any) bool {
+		groups = append(groups, key.(string))
+		return true
+	})
+
+	sort.Strings(groups)
+
+	fmt.Fprintln(w, "# HELP api_rate_limit_requests_total Requests checked by rate limiter by route group and result.")
+	fmt.Fprintln(w, "# TYPE api_rate_limit_requests_total counter")
+	for _, group := range groups {
+		value, _ := s.metrics.rateLimitedRequests.Load(group)
+		counters := value.(*[2]atomic.Uint64)
+
+		fmt.Fprintf(w, "api_rate_limit_requests_total{group=%q,result=\"allowed\"} %d\n", group, counters[0].Load())
+		fmt.Fprintf(w, "api_rate_limit_requests_total{group=%q,result=\"limited\"} %d\n", group, counters[1].Load())
+	}
+
+	keyIds := make([]string, 0)
+	s.metrics.keyRequests.Range(func(key, _ any) bool {
+		keyIds = append(keyIds, key.(string))
+		return true
+	})
+
+	sort.Strings(keyIds)
+
+	fmt.Fprintln(w, "# HELP api_rate_limit_key_requests_total Requests checked by rate limiter by API key and result.")
+	fmt.Fprintln(w, "# TYPE api_rate_limit_key_requests_total counter")
+	for _, id := range keyIds {
+		value, _ := s.metrics.keyRequests.Load(id)
+		counters := value.(*keyCounters)
+
+		fmt.Fprintf(w, "api_rate_limit_key_requests_total{key=%q,tenant=%q,result=\"allowed\"} %d\n", id, counters.tenant, counters.requests[0].Load())
+		fmt.Fprintf(w, "api_rate_limit_key_requests_total{key=%q,tenant=%q,result=\"limited\"} %d\n", id, counters.tenant, counters.requests[1].Load())
+	}
+
+	fmt.Fprintln(w, "# HELP api_subscription_quota_rejections_total Subscription requests rejected by daily quota.")
+	fmt.Fprintln(w, "# TYPE api_subscription_quota_rejections_total counter")
+	fmt.Fprintf(w, "api_subscription_quota_rejections_total %d\n", s.metrics.quotaRejections.Load())
+
+	if s.limiter == nil {
+		return
+	}
+
+	// configured limits
+	limits := make(map[string]RateLimit, len(s.limiter.config.Groups)+1)
+	for group, limit := range s.limiter.config.Groups {
+		limits[group] = limit
+	}
+	if s.limiter.config.PerIP.Requests > 0 {
+		limits[RateLimitGroupIP] = s.limiter.config.PerIP
+	}
+
+	configured := make([]string, 0, len(limits))
+	for group := range limits {
+		configured = append(configured, group)
+	}
+
+	sort.Strings(configured)
+
+	fmt.Fprintln(w, "# HELP api_rate_limit_requests Requests allowed per client in a window by route group.")
+	fmt.Fprintln(w, "# TYPE api_rate_limit_requests gauge")
+	for _, group := range configured {
+		fmt.Fprintf(w, "api_rate_limit_requests{group=%q} %d\n", group, limits[group].Requests)
+	}
+
+	fmt.Fprintln(w, "# HELP api_rate_limit_window_seconds Length of rate limit window by route group.")
+	fmt.Fprintln(w, "# TYPE api_rate_limit_window_seconds gauge")
+	for _, group := range configured {
+		fmt.Fprintf(w, "api_rate_limit_window_seconds{group=%q} %g\n", group, limits[group].Window.Seconds())
+	}
+
+	fmt.Fprintln(w, "# HELP api_subscription_quota_daily Addresses a tenant can newly subscribe in a day, 0 means unlimited.")
+	fmt.Fprintln(w, "# TYPE api_subscription_quota_daily gauge")
+	fmt.Fprintf(w, "api_subscription_quota_daily %d\n", s.limiter.config.DailySubscriptionQuota)
+}
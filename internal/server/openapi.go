@@ -0,0 +1,436 @@
+package server
+
+//go:generate go run ../../cmd openapi -o openapi.json
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/auth"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/pkg/parser"
+)
+
+const (
+	OpenAPIVersion = "3.0.3"
+	APIVersion     = "1.0.0"
+)
+
+var (
+	//go:embed openapi.json
+	openAPIDocument []byte
+
+	//go:embed docs.html
+	docsPage []byte
+
+	pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+	// descriptions of path parameters
+	pathParamDescriptions = map[string]string{
+		"chain":   "Chain name or chain id in decimal",
+		"address": "Address in lower case or EIP-55 checksum case",
+		"hash":    "Transaction hash",
+		"number":  "Block number in decimal or hex",
+	}
+
+	// schemas of types which are not derived from Go types
+	knownSchemas = map[reflect.Type]map[string]interface{}{
+		reflect.TypeOf(types.Quantity("")): {
+			"type":        "string",
+			"description": "Hex encoded quantity, decimal string if decimal query parameter is true",
+			"example":     "0x1b4",
+		},
+		reflect.TypeOf(types.Hash("")): {
+			"type":    "string",
+			"pattern": "^0x[0-9a-fA-F]{64}$",
+		},
+		reflect.TypeOf(types.Address("")): {
+			"type":    "string",
+			"pattern": "^0x[0-9a-fA-F]{40}$",
+		},
+		reflect.TypeOf(types.Data("")): {
+			"type":    "string",
+			"pattern": "^0x([0-9a-fA-F]{2})*$",
+		},
+		reflect.TypeOf(time.Time{}): {
+			"type":   "string",
+			"format": "date-time",
+		},
+		reflect.TypeOf(json.RawMessage{}): {},
+	}
+
+	// values of string enums
+	enumValues = map[reflect.Type][]string{
+		reflect.TypeOf(types.TxStatus("")): {
+			string(types.TxStatusPending),
+			string(types.TxStatusConfirmed),
+			string(types.TxStatusDropped),
+			string(types.TxStatusReplaced),
+		},
+		reflect.TypeOf(parser.HealthState("")): {
+			string(parser.HealthStateHealthy),
+			string(parser.HealthStateDegraded),
+			string(parser.HealthStateFailed),
+		},
+		reflect.TypeOf(auth.Role("")): {
+			string(auth.RoleTenant),
+			string(auth.RoleAdmin),
+		},
+		reflect.TypeOf(ErrorCode("")): {
+			string(ErrCodeInvalidRequest),
+			string(ErrCodeInvalidAddress),
+			string(ErrCodeInvalidHash),
+			string(ErrCodeInvalidBlock),
+			string(ErrCodeInvalidABI),
+			string(ErrCodeUnsupportedFormat),
+			string(ErrCodeUnauthorized),
+			string(ErrCodeForbidden),
+			string(ErrCodeRateLimited),
+			string(ErrCodeQuotaExceeded),
+			string(ErrCodeNotFound),
+			string(ErrCodeChainNotFound),
+			string(ErrCodeMethodNotAllowed),
+			string(ErrCodeUnavailable),
+			string(ErrCodeInternal),
+		},
+	}
+)
+
+// OpenAPIDocument returns OpenAPI document served at /openapi.json
+// It's generated by GenerateOpenAPIDocument and embedded at build time
+func OpenAPIDocument() []byte {
+	return openAPIDocument
+}
+
+// GenerateOpenAPIDocument builds OpenAPI document from routes and request and response types
+func GenerateOpenAPIDocument() ([]byte, error) {
+	// handlers are never called
+	s := &EthTransactionsServer{}
+
+	builder := &schemaBuilder{schemas: map[string]interface{}{}}
+	paths := map[string]map[string]interface{}{}
+
+	addOperations := func(routes []route, base string, tag string, deprecated bool) {
+		for _, rt := range routes {
+			for _, prefix := range rt.prefixes() {
+				path := base + prefix + rt.Path
+				if paths[path] == nil {
+					paths[path] = map[string]interface{}{}
+				}
+
+				operation := builder.operation(&rt, path, tag)
+				if prefix != "" {
+					operation["operationId"] = rt.OperationId + "ForChain"
+				}
+
+				if deprecated {
+					operation["deprecated"] = true
+					operation["description"] = fmt.Sprintf("Deprecated in favor of %s", APIVersionPrefix+prefix+rt.Successor)
+				}
+
+				paths[path][strings.ToLower(rt.Method)] = operation
+			}
+		}
+	}
+
+	addOperations(s.v1Routes(), APIVersionPrefix, "v1", false)
+	addOperations(s.legacyRoutes(), "", "legacy", true)
+	addOperations(s.metaRoutes(), "", "meta", false)
+
+	builder.schemaOf(reflect.TypeOf(ErrorResponse{}))
+
+	document := map[string]interface{}{
+		"openapi": OpenAPIVersion,
+		"info": map[string]interface{}{
+			"title":       "simple-go-eth-block-aggregator API",
+			"version":     APIVersion,
+			"description": "REST API to query transactions of subscribed Ethereum addresses",
+		},
+		"tags": []map[string]interface{}{
+			{"name": "v1", "description": "Versioned API, errors are returned in JSON envelope"},
+			{"name": "legacy", "description": "Deprecated API without version, errors are returned in plain text"},
+			{"name": "meta", "description": "API documents and metrics"},
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": builder.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "API key, required only if the server is started with API keys",
+				},
+				"apiKeyHeader": map[string]interface{}{
+					"type": "apiKey",
+					"in":   "header",
+					"name": HeaderAPIKey,
+				},
+			},
+		},
+	}
+
+	encoded, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(encoded, '\n'), nil
+}
+
+// schemaBuilder builds JSON schemas of Go types, named structs are registered as components
+type schemaBuilder struct {
+	schemas map[string]interface{}
+}
+
+// operation builds OpenAPI operation object of the route
+func (b *schemaBuilder) operation(rt *route, path string, tag string) map[string]interface{} {
+	parameters := make([]map[string]interface{}, 0)
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name":        match[1],
+			"in":          "path",
+			"required":    true,
+			"description": pathParamDescriptions[match[1]],
+			"schema":      map[string]interface{}{"type": "string"},
+		})
+	}
+
+	for _, param := range rt.Query {
+		schema := map[string]interface{}{"type": param.Type}
+		if len(param.Enum) > 0 {
+			schema["enum"] = param.Enum
+		}
+
+		parameters = append(parameters, map[string]interface{}{
+			"name":        param.Name,
+			"in":          "query",
+			"description": param.Description,
+			"schema":      schema,
+		})
+	}
+
+	operation := map[string]interface{}{
+		"operationId": rt.OperationId,
+		"summary":     rt.Summary,
+		"tags":        []string{tag},
+		"parameters":  parameters,
+		"responses":   b.responses(rt, tag),
+		"security":    securityRequirements(rt.Access),
+	}
+
+	if rt.Request != nil {
+		content := map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": b.schemaOf(reflect.TypeOf(rt.Request))},
+		}
+
+		for _, mediaType := range rt.RequestMediaTypes {
+			content[mediaType] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+		}
+
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  content,
+		}
+	}
+
+	return operation
+}
+
+// securityRequirements returns OpenAPI security requirements of given access level
+func securityRequirements(access routeAccess) []map[string][]string {
+	if access == accessPublic {
+		return []map[string][]string{}
+	}
+
+	return []map[string][]string{
+		{"bearerAuth": {}},
+		{"apiKeyHeader": {}},
+	}
+}
+
+// responses builds OpenAPI responses object of the route
+func (b *schemaBuilder) responses(rt *route, tag string) map[string]interface{} {
+	responses := map[string]interface{}{}
+
+	mediaTypes := rt.MediaTypes
+	if len(mediaTypes) == 0 {
+		mediaTypes = []string{"application/json"}
+	}
+
+	for status, body := range rt.Responses {
+		response := map[string]interface{}{"description": http.StatusText(status)}
+
+		if body != nil {
+			content := map[string]interface{}{}
+			for _, mediaType := range mediaTypes {
+				schema := map[string]interface{}{"type": "string"}
+				if mediaType == "application/json" {
+					schema = b.schemaOf(reflect.TypeOf(body))
+				}
+
+				content[mediaType] = map[string]interface{}{"schema": schema}
+			}
+
+			response["content"] = content
+		}
+
+		responses[strconv.Itoa(status)] = response
+	}
+
+	// errors of versioned API are returned in JSON envelope
+	errorContent := map[string]interface{}{
+		"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+	}
+	if tag == "v1" {
+		errorContent = map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": b.schemaOf(reflect.TypeOf(ErrorResponse{}))},
+		}
+	}
+
+	responses["default"] = map[string]interface{}{
+		"description": "Error",
+		"content":     errorContent,
+	}
+
+	return responses
+}
+
+// schemaOf returns JSON schema of given type, or reference to it for named structs
+func (b *schemaBuilder) schemaOf(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if schema, ok := knownSchemas[t]; ok {
+		return schema
+	}
+
+	if values, ok := enumValues[t]; ok {
+		return map[string]interface{}{"type": "string", "enum": values}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Interface:
+		// any JSON value
+		return map[string]interface{}{}
+	case reflect.Struct:
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := b.schemas[t.Name()]; ok {
+			return ref
+		}
+
+		// register placeholder first for recursive types
+		b.schemas[t.Name()] = nil
+
+		properties := map[string]interface{}{}
+		required := make([]string, 0)
+		b.addFields(t, properties, &required)
+
+		schema := map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		}
+
+		if len(required) > 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+
+		b.schemas[t.Name()] = schema
+
+		return ref
+	default:
+		log.Panicf("unsupported type in OpenAPI document, type=%s", t)
+		return nil
+	}
+}
+
+// addFields adds JSON fields of struct to properties, fields of embedded structs are inlined as encoding/json does
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			b.addFields(field.Type, properties, required)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = withDescription(b.schemaOf(field.Type), field.Tag.Get("doc"))
+
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// withDescription returns a copy of schema described by the doc tag of field, followed by description of the type
+// Schemas referring to components are kept because siblings of $ref are ignored
+func withDescription(schema map[string]interface{}, description string) map[string]interface{} {
+	if _, ok := schema["$ref"]; ok || description == "" {
+		return schema
+	}
+
+	described := make(map[string]interface{}, len(schema)+1)
+	for key, value := range schema {
+		described[key] = value
+	}
+
+	if typeDescription, ok := schema["description"].(string); ok {
+		description += ". " + typeDescription
+	}
+
+	described["description"] = description
+
+	return described
+}
+
+// handleGetOpenAPI is a handler for GET /openapi.json
+func (s *EthTransactionsServer) handleGetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPIDocument)
+}
+
+// handleGetDocs is a handler for GET /docs
+func (s *EthTransactionsServer) handleGetDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(docsPage)
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestOpenAPIDocumentIsUpToDate fails if routes or request and response types are changed without running make openapi
+func TestOpenAPIDocumentIsUpToDate(t *testing.T) {
+	document, err := GenerateOpenAPIDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(document, OpenAPIDocument()) {
+		t.Fatal("internal/server/openapi.json is outdated, run make openapi")
+	}
+}
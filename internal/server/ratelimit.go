@@ -0,0 +1,331 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// rate limit groups of routes, routes in the same group share a counter
+	RateLimitGroupDefault       = "default"
+	RateLimitGroupTransactions  = "transactions"
+	RateLimitGroupSubscriptions = "subscriptions"
+	// RateLimitGroupIP counts requests per IP address before authentication
+	RateLimitGroupIP = "ip"
+
+	// interval to drop counters of windows which have ended
+	rateLimitSweepInterval = time.Minute
+)
+
+var ErrQuotaExceeded = errors.New("daily subscription quota is exceeded")
+
+// RateLimit allows Requests in each Window
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimitConfig configures limits of requests per client, a client is an API key or an IP address
+type RateLimitConfig struct {
+	// Groups maps rate limit group to its limit, routes in groups without limit use the default group
+	Groups map[string]RateLimit
+	// DailySubscriptionQuota limits addresses newly subscribed by a tenant in a day in UTC, 0 means unlimited
+	DailySubscriptionQuota int
+	// PerIP limits requests of each IP address before authentication so that invalid API keys are throttled too
+	// Routes not requiring API key are not limited by it, zero means unlimited
+	PerIP RateLimit
+}
+
+// WithRateLimits limits requests per client and route group
+func WithRateLimits(config RateLimitConfig) Option {
+	return func(s *EthTransactionsServer) {
+		s.limiter = newRequestLimiter(config)
+	}
+}
+
+// windowCounter counts requests in a fixed window
+type windowCounter struct {
+	start time.Time
+	count int
+}
+
+// requestLimiter counts requests per client and route group in fixed windows, and subscriptions per client per day
+type requestLimiter struct {
+	config RateLimitConfig
+
+	counters      map[string]map[string]*windowCounter // group -> client -> counter
+	subscriptions map[string]*windowCounter            // client -> addresses subscribed today
+	lastSweep     time.Time
+
+	mutex sync.Mutex
+	now   func() time.Time
+}
+
+func newRequestLimiter(config RateLimitConfig) *requestLimiter {
+	return &requestLimiter{
+		config:        config,
+		counters:      make(map[string]map[string]*windowCounter),
+		subscriptions: make(map[string]*windowCounter),
+		lastSweep:     time.Now(),
+		now:           time.Now,
+	}
+}
+
+// rateLimitResult is the state of counter after a request
+type rateLimitResult struct {
+	Group     string // group of the counter, default group if the group of route has no limit
+	Limit     RateLimit
+	Allowed   bool
+	Remaining int
+	Reset     time.Duration
+}
+
+// limitOf returns the limit of group, ok is false if requests of the group are not limited
+func (l *requestLimiter) limitOf(group string) (string, RateLimit, bool) {
+	if limit, ok := l.config.Groups[group]; ok && limit.Requests > 0 {
+		return group, limit, true
+	}
+
+	limit, ok := l.config.Groups[RateLimitGroupDefault]
+
+	return RateLimitGroupDefault, limit, ok && limit.Requests > 0
+}
+
+// windowOf returns the window of counters of group
+func (l *requestLimiter) windowOf(group string) time.Duration {
+	if group == RateLimitGroupIP {
+		return l.config.PerIP.Window
+	}
+
+	return l.config.Groups[group].Window
+}
+
+// allow counts a request of client in group and returns whether it's allowed
+func (l *requestLimiter) allow(group string, client string) (rateLimitResult, bool) {
+	group, limit, ok := l.limitOf(group)
+	if !ok {
+		return rateLimitResult{}, false
+	}
+
+	return l.count(group, limit, client), true
+}
+
+// allowIP counts a request from IP address before authentication and returns whether it's allowed
+func (l *requestLimiter) allowIP(ip string) (rateLimitResult, bool) {
+	if l.config.PerIP.Requests <= 0 {
+		return rateLimitResult{}, false
+	}
+
+	return l.count(RateLimitGroupIP, l.config.PerIP, "ip:"+ip), true
+}
+
+// count counts a request of client in the window of group
+func (l *requestLimiter) count(group string, limit RateLimit, client string) rateLimitResult {
+	now := l.now()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.sweep(now)
+
+	clients, ok := l.counters[group]
+	if !ok {
+		clients = make(map[string]*windowCounter)
+		l.counters[group] = clients
+	}
+
+	counter, ok := clients[client]
+	if !ok || now.Sub(counter.start) >= limit.Window {
+		counter = &windowCounter{start: now.Truncate(limit.Window)}
+		clients[client] = counter
+	}
+
+	result := rateLimitResult{
+		Group:   group,
+		Limit:   limit,
+		Allowed: counter.count < limit.Requests,
+		Reset:   counter.start.Add(limit.Window).Sub(now),
+	}
+
+	if result.Allowed {
+		counter.count++
+	}
+
+	result.Remaining = limit.Requests - counter.count
+
+	return result
+}
+
+// takeSubscriptions consumes daily quota of client by n addresses, nothing is consumed if quota is not enough
+func (l *requestLimiter) takeSubscriptions(client string, n int) error {
+	quota := l.config.DailySubscriptionQuota
+	if quota <= 0 {
+		return nil
+	}
+
+	now := l.now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	counter, ok := l.subscriptions[client]
+	if !ok || !counter.start.Equal(today) {
+		counter = &windowCounter{start: today}
+		l.subscriptions[client] = counter
+	}
+
+	if counter.count+n > quota {
+		return fmt.Errorf(
+			"%w: %d addresses are requested but %d of %d remain today",
+			ErrQuotaExceeded, n, quota-counter.count, quota,
+		)
+	}
+
+	counter.count += n
+
+	return nil
+}
+
+// sweep drops counters of windows which have ended, mutex must be held
+func (l *requestLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+
+	l.lastSweep = now
+
+	for group, clients := range l.counters {
+		window := l.windowOf(group)
+		for client, counter := range clients {
+			if now.Sub(counter.start) >= window {
+				delete(clients, client)
+			}
+		}
+	}
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	for client, counter := range l.subscriptions {
+		if counter.start.Before(today) {
+			delete(l.subscriptions, client)
+		}
+	}
+}
+
+// rateLimit wraps handler to limit requests of each client in the group
+// Counters are shared by routes in the same group, and RateLimit-* headers describe the counter
+func (s *EthTransactionsServer) rateLimit(group string, h http.HandlerFunc) http.HandlerFunc {
+	if s.limiter == nil {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, limited := s.limiter.allow(group, clientId(r))
+		if !limited {
+			h(w, r)
+			return
+		}
+
+		s.metrics.countRequest(result.Group, result.Allowed)
+		if key := requestKey(r); key != nil {
+			s.metrics.countKeyRequest(key, result.Allowed)
+		}
+
+		writeRateLimitHeaders(w, result)
+
+		if !result.Allowed {
+			s.fail(w, r, http.StatusTooManyRequests, ErrCodeRateLimited, fmt.Sprintf("rate limit of %s routes is exceeded", result.Group))
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+// rateLimitIP wraps handler to limit requests of each IP address, it's applied before authentication
+// Headers are written only for rejected requests, allowed ones have headers of the route group
+func (s *EthTransactionsServer) rateLimitIP(h http.HandlerFunc) http.HandlerFunc {
+	if s.limiter == nil || s.limiter.config.PerIP.Requests <= 0 {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, limited := s.limiter.allowIP(remoteIP(r))
+		if !limited {
+			h(w, r)
+			return
+		}
+
+		s.metrics.countRequest(result.Group, result.Allowed)
+
+		if !result.Allowed {
+			writeRateLimitHeaders(w, result)
+			s.fail(w, r, http.StatusTooManyRequests, ErrCodeRateLimited, "rate limit of the IP address is exceeded")
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+// writeRateLimitHeaders writes RateLimit-* headers describing the counter, and Retry-After if it's exceeded
+func writeRateLimitHeaders(w http.ResponseWriter, result rateLimitResult) {
+	reset := int(math.Ceil(result.Reset.Seconds()))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit.Requests))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(reset))
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", result.Limit.Requests, int(result.Limit.Window.Seconds())))
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(reset))
+	}
+}
+
+// takeSubscriptionQuota consumes daily subscription quota of the client of the request
+func (s *EthTransactionsServer) takeSubscriptionQuota(r *http.Request, n int) error {
+	if s.limiter == nil || n == 0 {
+		return nil
+	}
+
+	if err := s.limiter.takeSubscriptions(quotaClientId(r), n); err != nil {
+		s.metrics.countQuotaRejection()
+
+		return err
+	}
+
+	return nil
+}
+
+// clientId returns API key id of the request, or IP address if authentication is disabled
+func clientId(r *http.Request) string {
+	if key := requestKey(r); key != nil {
+		return "key:" + key.Id
+	}
+
+	return "ip:" + remoteIP(r)
+}
+
+// quotaClientId returns tenant of the request, or IP address if authentication is disabled
+func quotaClientId(r *http.Request) string {
+	if key := requestKey(r); key != nil {
+		return "tenant:" + key.Tenant
+	}
+
+	return "ip:" + remoteIP(r)
+}
+
+// remoteIP returns IP address of the peer, headers set by proxies are not trusted
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
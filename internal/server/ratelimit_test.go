@@ -0,0 +1,264 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/auth"
+)
+
+// fakeClock is a clock of rate limiter advanced by tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock(t *testing.T, s *testServer, now string) *fakeClock {
+	t.Helper()
+
+	parsed, err := time.Parse(time.RFC3339, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &fakeClock{now: parsed}
+	s.server.limiter.now = clock.Now
+
+	return clock
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// expectHeaders fails if any of the headers of the response doesn't have the value
+func expectHeaders(t *testing.T, res *http.Response, headers map[string]string) {
+	t.Helper()
+
+	for name, value := range headers {
+		if actual := res.Header.Get(name); actual != value {
+			t.Fatalf("expected %s header %q, but got %q", name, value, actual)
+		}
+	}
+}
+
+func TestRateLimitHeadersAndRetryAfter(t *testing.T) {
+	s := newTestServer(t, WithRateLimits(RateLimitConfig{
+		Groups: map[string]RateLimit{
+			RateLimitGroupDefault:      {Requests: 2, Window: time.Minute},
+			RateLimitGroupTransactions: {Requests: 1, Window: 10 * time.Second},
+		},
+	}))
+	clock := newFakeClock(t, s, "2024-05-01T00:00:30Z")
+
+	res := s.do(t, http.MethodGet, "/v1/blocks/current", nil, nil)
+	expectHeaders(t, res, map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "1",
+		"RateLimit-Reset":     "30",
+		"RateLimit-Policy":    "2;w=60",
+		"Retry-After":         "",
+	})
+
+	clock.Advance(10 * time.Second)
+
+	res = s.do(t, http.MethodGet, "/v1/blocks/current", nil, nil)
+	expectHeaders(t, res, map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": "20"})
+
+	response := &ErrorResponse{}
+	res = s.do(t, http.MethodGet, "/v1/blocks/current", nil, response)
+	if res.StatusCode != http.StatusTooManyRequests || response.Error.Code != ErrCodeRateLimited {
+		t.Fatalf("expected 429 %s, but got status=%d, error=%+v", ErrCodeRateLimited, res.StatusCode, response.Error)
+	}
+	expectHeaders(t, res, map[string]string{"RateLimit-Remaining": "0", "Retry-After": "20"})
+
+	// routes of other groups have their own counters
+	res = s.do(t, http.MethodGet, "/v1/addresses/"+anotherAddress+"/transactions", nil, nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected transactions group not to be limited, but got %d", res.StatusCode)
+	}
+	expectHeaders(t, res, map[string]string{"RateLimit-Limit": "1", "RateLimit-Policy": "1;w=10"})
+
+	// a new window starts after Retry-After
+	clock.Advance(20 * time.Second)
+
+	res = s.do(t, http.MethodGet, "/v1/blocks/current", nil, nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected request in the next window to be allowed, but got %d", res.StatusCode)
+	}
+	expectHeaders(t, res, map[string]string{"RateLimit-Remaining": "1", "RateLimit-Reset": "60"})
+}
+
+func TestDailySubscriptionQuotaResets(t *testing.T) {
+	s := newTestServer(t, WithRateLimits(RateLimitConfig{DailySubscriptionQuota: 2}))
+	clock := newFakeClock(t, s, "2024-05-01T23:59:00Z")
+
+	subscribe := func(address string) *http.Response {
+		return s.do(t, http.MethodPut, "/v1/subscriptions/"+address, nil, nil)
+	}
+
+	for i := 0; i < 2; i++ {
+		if res := subscribe(fmt.Sprintf("0x%040x", i)); res.StatusCode != http.StatusCreated {
+			t.Fatalf("expected subscription within quota, but got %d", res.StatusCode)
+		}
+	}
+
+	// subscribing again doesn't consume quota
+	if res := subscribe(fmt.Sprintf("0x%040x", 0)); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected subscribed address to be accepted, but got %d", res.StatusCode)
+	}
+
+	response := &ErrorResponse{}
+	res := s.do(t, http.MethodPut, "/v1/subscriptions/"+fmt.Sprintf("0x%040x", 2), nil, response)
+	if res.StatusCode != http.StatusTooManyRequests || response.Error.Code != ErrCodeQuotaExceeded {
+		t.Fatalf("expected 429 %s, but got status=%d, error=%+v", ErrCodeQuotaExceeded, res.StatusCode, response.Error)
+	}
+
+	// quota is reset at 00:00 in UTC
+	clock.Advance(time.Minute)
+
+	if res := subscribe(fmt.Sprintf("0x%040x", 2)); res.StatusCode != http.StatusCreated {
+		t.Fatalf("expected quota to be reset on the next day, but got %d", res.StatusCode)
+	}
+}
+
+func TestConcurrentSubscriptionsOfSameAddressesConsumeQuotaOnce(t *testing.T) {
+	keys := auth.NewStore()
+	s := newTestServer(t, WithAuth(keys), WithRateLimits(RateLimitConfig{DailySubscriptionQuota: 5}))
+	_, secret := createKey(t, keys, "a", auth.RoleTenant)
+
+	addresses := make([]string, 5)
+	for i := range addresses {
+		addresses[i] = fmt.Sprintf("0x%040x", i+1)
+	}
+
+	// requests counting the same addresses as new at once would exceed the quota
+	statuses := make([]int, 10)
+	wg := &sync.WaitGroup{}
+	for i := range statuses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req := s.request(t, http.MethodPost, "/v1/subscriptions", addresses)
+			req.Header.Set(HeaderAPIKey, secret)
+
+			res, err := s.api.Client().Do(req)
+			if err != nil {
+				return
+			}
+			defer res.Body.Close()
+
+			statuses[i] = res.StatusCode
+		}()
+	}
+	wg.Wait()
+
+	for _, status := range statuses {
+		if status != http.StatusOK {
+			t.Fatalf("expected all requests to be within quota, but got statuses %v", statuses)
+		}
+	}
+
+	// the quota is used up by the addresses
+	if res := s.doAs(t, secret, http.MethodPut, "/v1/subscriptions/"+anotherAddress, nil, nil); res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected quota to be used up, but got %d", res.StatusCode)
+	}
+}
+
+func TestPerIPRateLimitThrottlesInvalidKeys(t *testing.T) {
+	keys := auth.NewStore()
+	s := newTestServer(t, WithAuth(keys), WithRateLimits(RateLimitConfig{
+		PerIP: RateLimit{Requests: 2, Window: time.Minute},
+	}))
+	newFakeClock(t, s, "2024-05-01T00:00:00Z")
+	_, secret := createKey(t, keys, "a", auth.RoleTenant)
+
+	for i := 0; i < 2; i++ {
+		if res := s.doAs(t, auth.KeyPrefix+"guess", http.MethodGet, "/v1/blocks/current", nil, nil); res.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected 401 for invalid key, but got %d", res.StatusCode)
+		}
+	}
+
+	// the IP address is throttled before its key is checked
+	for _, key := range []string{auth.KeyPrefix + "guess", secret} {
+		response := &ErrorResponse{}
+		res := s.doAs(t, key, http.MethodGet, "/v1/blocks/current", nil, response)
+		if res.StatusCode != http.StatusTooManyRequests || response.Error.Code != ErrCodeRateLimited {
+			t.Fatalf("expected 429 %s, but got status=%d, error=%+v", ErrCodeRateLimited, res.StatusCode, response.Error)
+		}
+		expectHeaders(t, res, map[string]string{"RateLimit-Limit": "2", "Retry-After": "60"})
+	}
+
+	// routes without API key are not limited per IP address
+	if res := s.do(t, http.MethodGet, "/v1/health", nil, nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected health check not to be limited, but got %d", res.StatusCode)
+	}
+}
+
+func TestRateLimitMetricsPerKey(t *testing.T) {
+	keys := auth.NewStore()
+	s := newTestServer(t, WithAuth(keys), WithRateLimits(RateLimitConfig{
+		Groups:                 map[string]RateLimit{RateLimitGroupDefault: {Requests: 1, Window: time.Minute}},
+		DailySubscriptionQuota: 1,
+		PerIP:                  RateLimit{Requests: 100, Window: time.Minute},
+	}))
+	newFakeClock(t, s, "2024-05-01T00:00:00Z")
+
+	keyA, secretA := createKey(t, keys, "a", auth.RoleTenant)
+	keyB, secretB := createKey(t, keys, "b", auth.RoleTenant)
+
+	// a is limited at the second request, b is counted separately
+	s.doAs(t, secretA, http.MethodGet, "/v1/blocks/current", nil, nil)
+	s.doAs(t, secretA, http.MethodGet, "/v1/blocks/current", nil, nil)
+	s.doAs(t, secretB, http.MethodGet, "/v1/blocks/current", nil, nil)
+
+	// subscriptions group falls back to the limit of default group, so b is limited at its second request
+	res := s.doAs(t, secretB, http.MethodPost, "/v1/subscriptions", []string{anotherAddress, checksummedAddress}, nil)
+	if res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected b to be limited, but got %d", res.StatusCode)
+	}
+
+	res, err := s.api.Client().Get(s.api.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		fmt.Sprintf(`api_rate_limit_key_requests_total{key=%q,tenant="a",result="allowed"} 1`, keyA.Id),
+		fmt.Sprintf(`api_rate_limit_key_requests_total{key=%q,tenant="a",result="limited"} 1`, keyA.Id),
+		fmt.Sprintf(`api_rate_limit_key_requests_total{key=%q,tenant="b",result="allowed"} 1`, keyB.Id),
+		fmt.Sprintf(`api_rate_limit_key_requests_total{key=%q,tenant="b",result="limited"} 1`, keyB.Id),
+		// the request of metrics is counted by IP address without API key
+		`api_rate_limit_requests_total{group="default",result="allowed"} 3`,
+		`api_rate_limit_requests_total{group="default",result="limited"} 2`,
+		`api_rate_limit_requests_total{group="ip",result="allowed"} 4`,
+		`api_rate_limit_requests{group="ip"} 100`,
+		`api_subscription_quota_daily 1`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("expected metrics to have %q, but got\n%s", line, body)
+		}
+	}
+}
@@ -1,7 +1,13 @@
 package server
 
 import (
+	"encoding/json"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/abi"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/auth"
 	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/pkg/parser"
 )
 
 // PostSubscribeRequest is a request body for POST /subscribe API
@@ -21,5 +27,187 @@ type PostGetTransactionsRequest struct {
 
 // PostGetTransactionsResponse is a response body for POST /transactions API
 type PostGetTransactionsResponse struct {
-	Transactions []types.Transaction `json:"transactions"`
+	Transactions []TransactionResponse `json:"transactions"`
+	// PendingTransactions is returned only if includePending query parameter is true
+	PendingTransactions []TransactionResponse `json:"pendingTransactions,omitempty"`
+}
+
+// TransactionResponse is a transaction with metadata added by the server
+type TransactionResponse struct {
+	types.Transaction
+	Status      types.TxStatus `json:"status"`
+	FirstSeenAt *time.Time     `json:"firstSeenAt,omitempty"`
+	ReplacedBy  types.Hash     `json:"replacedBy,omitempty"`
+	// Timestamp is the timestamp of the block including the transaction
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	// MaxFee is effective gas price multiplied by gas limit, more than the fee actually paid unless all gas was used
+	MaxFee types.Quantity        `json:"maxFee,omitempty" doc:"Effective gas price multiplied by gas limit, the upper bound of the fee actually paid as gas used is not fetched"`
+	Block  *BlockSummaryResponse `json:"block,omitempty"`
+	// MatchedAddresses are subscribed addresses which caused the transaction to be indexed
+	MatchedAddresses []types.Address  `json:"matchedAddresses,omitempty"`
+	DecodedInput     *abi.DecodedCall `json:"decodedInput,omitempty"`
+}
+
+// BlockSummaryResponse is metadata of the block including the transaction
+type BlockSummaryResponse struct {
+	BaseFeePerGas types.Quantity `json:"baseFeePerGas,omitempty"`
+	Miner         types.Address  `json:"miner"`
+}
+
+// GetAddressAnalyticsResponse is a response body for GET /v1/addresses/{address}/analytics API
+type GetAddressAnalyticsResponse struct {
+	Address types.Address `json:"address"`
+	// FromBlock and ToBlock are the range of aggregated blocks, they are omitted if no block is in the time range
+	FromBlock *uint64    `json:"fromBlock,omitempty"`
+	ToBlock   *uint64    `json:"toBlock,omitempty"`
+	Since     *time.Time `json:"since,omitempty"`
+	Until     *time.Time `json:"until,omitempty"`
+	types.AddressAnalytics
+	// NetInflow is totalIn minus totalOut in decimal string, negative for net outflow. Fees are not subtracted
+	NetInflow string `json:"netInflow"`
+}
+
+// PostRegisterABIRequest is a request body for POST /abis API
+type PostRegisterABIRequest struct {
+	Address string          `json:"address"`
+	ABI     json.RawMessage `json:"abi"`
+}
+
+// PostRegisterABIResponse is a response body for POST /abis API
+type PostRegisterABIResponse struct {
+	Ok bool `json:"ok"`
+}
+
+// GetChainsResponse is a response body for GET /chains API
+type GetChainsResponse struct {
+	Chains []ChainResponse `json:"chains"`
+}
+
+// ChainResponse is a chain indexed by the server
+type ChainResponse struct {
+	Name         string `json:"name"`
+	ChainId      uint64 `json:"chainId"`
+	CurrentBlock int    `json:"currentBlock"`
+}
+
+// GetBalanceResponse is a response body for GET /balance API
+type GetBalanceResponse struct {
+	types.AccountState
+}
+
+// GetHealthResponse is a response body for GET /health API
+type GetHealthResponse struct {
+	// Status is the worst state of all chains
+	Status parser.HealthState    `json:"status"`
+	Chains []ChainHealthResponse `json:"chains"`
+}
+
+// ChainHealthResponse is the health of parser of a chain
+type ChainHealthResponse struct {
+	Name    string `json:"name,omitempty"`
+	ChainId uint64 `json:"chainId,omitempty"`
+	parser.Health
+}
+
+// IngestionResponse is a response body of /v1/admin/ingestion APIs
+type IngestionResponse struct {
+	Paused       bool `json:"paused"`
+	CurrentBlock int  `json:"currentBlock"`
+	// NextHeight is the height of the block to be processed next, omitted until the parser starts
+	NextHeight *uint64 `json:"nextHeight,omitempty"`
+}
+
+// PostSeekRequest is a request body for POST /v1/admin/ingestion/seek API
+type PostSeekRequest struct {
+	Height uint64 `json:"height"`
+}
+
+// PostSeekResponse is a response body for POST /v1/admin/ingestion/seek API
+type PostSeekResponse struct {
+	IngestionResponse
+	// Deleted is the number of transactions deleted by rewind
+	Deleted int `json:"deleted"`
+}
+
+// PostReindexRequest is a request body for POST /v1/admin/reindex API
+type PostReindexRequest struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+	// Address is the only address to reindex, all subscribed addresses are reindexed if empty
+	Address string `json:"address,omitempty"`
+}
+
+// GetComponentsResponse is a response body for GET /v1/components API
+type GetComponentsResponse struct {
+	Components []parser.ComponentStatus `json:"components"`
+}
+
+// PostBulkSubscribeRequest is a request body for POST /subscribe/bulk API
+// A JSON array of addresses or CSV is accepted as well
+type PostBulkSubscribeRequest struct {
+	Addresses []string `json:"addresses"`
+}
+
+// PostBulkSubscribeResponse is a response body for POST /subscribe/bulk API
+type PostBulkSubscribeResponse struct {
+	Subscribed        int `json:"subscribed"`
+	AlreadySubscribed int `json:"alreadySubscribed"`
+	NumInvalid        int `json:"numInvalid"`
+	// Invalid has first invalid addresses up to MaxInvalidAddressesInResponse
+	Invalid []InvalidAddress `json:"invalid"`
+}
+
+// InvalidAddress is an address rejected by POST /subscribe/bulk API
+type InvalidAddress struct {
+	// Index is the position of the address in the request
+	Index   int    `json:"index"`
+	Address string `json:"address"`
+	Error   string `json:"error"`
+}
+
+// GetBlockTransactionsResponse is a response body for GET /blocks/{number}/transactions API
+type GetBlockTransactionsResponse struct {
+	BlockNumber  types.Quantity        `json:"blockNumber"`
+	Transactions []TransactionResponse `json:"transactions"`
+}
+
+// ErrorResponse is a response body of errors in /v1 API
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes an error
+type ErrorBody struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	Status  int       `json:"status"`
+}
+
+// GetCurrentBlockResponse is a response body for GET /v1/blocks/current API
+type GetCurrentBlockResponse struct {
+	Height int `json:"height"`
+}
+
+// SubscriptionResponse is a response body for /v1/subscriptions/{address} API
+type SubscriptionResponse struct {
+	Address types.Address `json:"address"`
+}
+
+// PostAPIKeyRequest is a request body for POST /v1/admin/keys API
+type PostAPIKeyRequest struct {
+	Tenant string `json:"tenant"`
+	// Role is tenant or admin, tenant if empty
+	Role string `json:"role,omitempty"`
+}
+
+// PostAPIKeyResponse is a response body for POST /v1/admin/keys API
+type PostAPIKeyResponse struct {
+	auth.Key
+	// Secret is the API key, which is returned only once
+	Secret string `json:"key"`
+}
+
+// GetAPIKeysResponse is a response body for GET /v1/admin/keys API
+type GetAPIKeysResponse struct {
+	Keys []auth.Key `json:"keys"`
 }
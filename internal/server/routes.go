@@ -0,0 +1,378 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/pkg/parser"
+)
+
+const (
+	// chainPrefix is the prefix of routes scoped by chain name or id
+	chainPrefix = "/chains/{chain}"
+)
+
+// route is an API route, which is registered to mux and described in OpenAPI document
+type route struct {
+	Method      string
+	Path        string // relative to chain prefix if ChainScoped is true
+	Handler     http.HandlerFunc
+	OperationId string
+	Summary     string
+	// ChainScoped routes are served for the default chain and under /chains/{chain}
+	ChainScoped bool
+	Query       []queryParam
+	// Request is a value of request body type, nil if request has no body
+	Request interface{}
+	// RequestMediaTypes are accepted media types of request body other than application/json
+	RequestMediaTypes []string
+	// Responses maps status code to a value of response body type, nil value means no body
+	Responses map[int]interface{}
+	// MediaTypes are media types of response body, application/json is used if empty
+	MediaTypes []string
+	// Successor is the path of route in versioned API replacing the legacy route
+	Successor string
+	// RequiresABIRegistry routes are registered only if ABI registry is given
+	RequiresABIRegistry bool
+	// RequiresAuth routes are registered only if API keys are given
+	RequiresAuth bool
+	// RequiresSupervisor routes are registered only if supervisor is given
+	RequiresSupervisor bool
+	// Access is the level of access required if API keys are given
+	Access routeAccess
+	// RateLimitGroup is a group of routes sharing rate limit counters, RateLimitGroupDefault if empty
+	RateLimitGroup string
+}
+
+// queryParam is a query parameter of route
+type queryParam struct {
+	Name        string
+	Type        string // boolean, integer or string
+	Enum        []string
+	Description string
+}
+
+var (
+	viewQueryParams = []queryParam{
+		{Name: "decimal", Type: "boolean", Description: "Returns quantities in decimal string instead of hex"},
+		{Name: "checksum", Type: "boolean", Description: "Returns addresses in EIP-55 checksum case"},
+	}
+	transactionsQueryParams = append([]queryParam{
+		{Name: "format", Type: "string", Enum: []string{"json", "csv", "ndjson", "koinly", "cointracker"}, Description: "Output format, Accept header is used if omitted"},
+		{Name: "includePending", Type: "boolean", Description: "Includes transactions seen in mempool, only for json format"},
+		{Name: "since", Type: "string", Description: "Returns transactions in blocks at or after the time in RFC 3339, only for json format"},
+		{Name: "until", Type: "string", Description: "Returns transactions in blocks before the time in RFC 3339, only for json format"},
+	}, viewQueryParams...)
+	balanceQueryParams = append([]queryParam{
+		{Name: "block", Type: "integer", Description: "Returns the state at or before the block, latest one if omitted"},
+	}, viewQueryParams...)
+	analyticsQueryParams = append([]queryParam{
+		{Name: "fromBlock", Type: "integer", Description: "First block aggregated, 0 if omitted"},
+		{Name: "toBlock", Type: "integer", Description: "Last block aggregated, the last processed block if omitted"},
+		{Name: "since", Type: "string", Description: "Aggregates blocks at or after the time in RFC 3339, can't be given with block range"},
+		{Name: "until", Type: "string", Description: "Aggregates blocks before the time in RFC 3339, can't be given with block range"},
+		{Name: "limit", Type: "integer", Description: "Maximum number of counterparties ranked by volume, 10 if omitted, up to 100"},
+	}, viewQueryParams...)
+	transactionsMediaTypes = []string{"application/json", "text/csv", "application/x-ndjson"}
+)
+
+// v1Routes returns routes of versioned API
+func (s *EthTransactionsServer) v1Routes() []route {
+	return []route{
+		{
+			Method: http.MethodGet, Path: "/chains", Handler: s.handleGetChains,
+			OperationId: "getChains", Summary: "Returns indexed chains",
+			Responses: map[int]interface{}{http.StatusOK: GetChainsResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/health", Handler: s.handleGetHealth, Access: accessPublic,
+			OperationId: "getHealth", Summary: "Returns the state of parsers of all chains",
+			Responses: map[int]interface{}{http.StatusOK: GetHealthResponse{}, http.StatusServiceUnavailable: GetHealthResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: chainPrefix + "/health", Handler: s.handleGetChainHealth, Access: accessPublic,
+			OperationId: "getChainHealth", Summary: "Returns the state of parser of the chain",
+			Responses: map[int]interface{}{http.StatusOK: ChainHealthResponse{}, http.StatusServiceUnavailable: ChainHealthResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/components", Handler: s.handleGetComponents, RequiresSupervisor: true, Access: accessAdmin,
+			OperationId: "getComponents", Summary: "Returns states and crash histories of components restarted by supervisor",
+			Responses: map[int]interface{}{http.StatusOK: GetComponentsResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/blocks/current", Handler: s.handleV1GetCurrentBlock, ChainScoped: true,
+			OperationId: "getCurrentBlock", Summary: "Returns the height of the last processed block",
+			Responses: map[int]interface{}{http.StatusOK: GetCurrentBlockResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/blocks/{number}/transactions", Handler: s.handleGetBlockTransactions, ChainScoped: true,
+			OperationId: "getBlockTransactions", Summary: "Returns indexed transactions in the block",
+			Query:     viewQueryParams,
+			Responses: map[int]interface{}{http.StatusOK: GetBlockTransactionsResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/transactions/{hash}", Handler: s.handleGetTransactionByHash, ChainScoped: true,
+			OperationId: "getTransaction", Summary: "Returns the indexed transaction of the hash",
+			Query:     viewQueryParams,
+			Responses: map[int]interface{}{http.StatusOK: TransactionResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/addresses/{address}/transactions", Handler: s.handleV1GetAddressTransactions, ChainScoped: true,
+			OperationId: "getAddressTransactions", RateLimitGroup: RateLimitGroupTransactions, Summary: "Returns inbound and outbound transactions of the address",
+			Query:      transactionsQueryParams,
+			Responses:  map[int]interface{}{http.StatusOK: PostGetTransactionsResponse{}},
+			MediaTypes: transactionsMediaTypes,
+		},
+		{
+			Method: http.MethodGet, Path: "/addresses/{address}/analytics", Handler: s.handleGetAddressAnalytics, ChainScoped: true,
+			OperationId: "getAddressAnalytics", RateLimitGroup: RateLimitGroupTransactions, Summary: "Returns total values, fees and counterparties of transactions of the address in a block or time range",
+			Query:     analyticsQueryParams,
+			Responses: map[int]interface{}{http.StatusOK: GetAddressAnalyticsResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/addresses/{address}/balance", Handler: s.handleGetBalance, ChainScoped: true,
+			OperationId: "getAddressBalance", Summary: "Returns balance and nonce of the address",
+			Query:     balanceQueryParams,
+			Responses: map[int]interface{}{http.StatusOK: GetBalanceResponse{}},
+		},
+		{
+			Method: http.MethodPost, Path: "/subscriptions", Handler: s.handlePostBulkSubscribe, ChainScoped: true,
+			OperationId: "createSubscriptions", RateLimitGroup: RateLimitGroupSubscriptions, Summary: "Subscribes to addresses at once",
+			Request: PostBulkSubscribeRequest{}, RequestMediaTypes: []string{"text/csv"},
+			Responses: map[int]interface{}{http.StatusOK: PostBulkSubscribeResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/subscriptions/{address}", Handler: s.handleV1GetSubscription, ChainScoped: true,
+			OperationId: "getSubscription", Summary: "Returns the subscription of the address",
+			Responses: map[int]interface{}{http.StatusOK: SubscriptionResponse{}},
+		},
+		{
+			Method: http.MethodPut, Path: "/subscriptions/{address}", Handler: s.handleV1PutSubscription, ChainScoped: true,
+			OperationId: "putSubscription", RateLimitGroup: RateLimitGroupSubscriptions, Summary: "Subscribes to the address",
+			Responses: map[int]interface{}{http.StatusOK: SubscriptionResponse{}, http.StatusCreated: SubscriptionResponse{}},
+		},
+		{
+			Method: http.MethodDelete, Path: "/subscriptions/{address}", Handler: s.handleV1DeleteSubscription, ChainScoped: true,
+			OperationId: "deleteSubscription", RateLimitGroup: RateLimitGroupSubscriptions, Summary: "Unsubscribes from the address",
+			Responses: map[int]interface{}{http.StatusNoContent: nil},
+		},
+		{
+			Method: http.MethodPut, Path: "/abis/{address}", Handler: s.handleV1PutABI, RequiresABIRegistry: true, Access: accessAdmin,
+			OperationId: "putABI", Summary: "Registers contract ABI used to decode inputs of transactions sent to the address",
+			Request:   json.RawMessage{},
+			Responses: map[int]interface{}{http.StatusNoContent: nil},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/ingestion", Handler: s.handleGetIngestion, ChainScoped: true, RequiresAuth: true, Access: accessAdmin,
+			OperationId: "getIngestion", Summary: "Returns whether block ingestion is paused and the height processed next",
+			Responses: map[int]interface{}{http.StatusOK: IngestionResponse{}},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/ingestion/pause", Handler: s.handlePostPause, ChainScoped: true, RequiresAuth: true, Access: accessAdmin,
+			OperationId: "pauseIngestion", Summary: "Stops fetching new blocks until resumed, blocks fetched already are stored",
+			Responses: map[int]interface{}{http.StatusOK: IngestionResponse{}},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/ingestion/resume", Handler: s.handlePostResume, ChainScoped: true, RequiresAuth: true, Access: accessAdmin,
+			OperationId: "resumeIngestion", Summary: "Restarts fetching blocks",
+			Responses: map[int]interface{}{http.StatusOK: IngestionResponse{}},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/ingestion/seek", Handler: s.handlePostSeek, ChainScoped: true, RequiresAuth: true, Access: accessAdmin,
+			OperationId: "seekIngestion", Summary: "Sets the height processed next, transactions of blocks from the height are deleted on rewind",
+			Request:   PostSeekRequest{},
+			Responses: map[int]interface{}{http.StatusOK: PostSeekResponse{}},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/reindex", Handler: s.handlePostReindex, ChainScoped: true, RequiresAuth: true, Access: accessAdmin,
+			OperationId: "startReindex", Summary: "Deletes and ingests again transactions in processed blocks for an address or all subscribed addresses",
+			Request:   PostReindexRequest{},
+			Responses: map[int]interface{}{http.StatusAccepted: parser.ReindexStatus{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/reindex", Handler: s.handleGetReindex, ChainScoped: true, RequiresAuth: true, Access: accessAdmin,
+			OperationId: "getReindex", Summary: "Returns the progress of the last reindex",
+			Responses: map[int]interface{}{http.StatusOK: parser.ReindexStatus{}},
+		},
+		{
+			Method: http.MethodDelete, Path: "/admin/reindex", Handler: s.handleDeleteReindex, ChainScoped: true, RequiresAuth: true, Access: accessAdmin,
+			OperationId: "cancelReindex", Summary: "Cancels running reindex, transactions reindexed already are kept",
+			Responses: map[int]interface{}{http.StatusNoContent: nil},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/keys", Handler: s.handlePostAPIKey, RequiresAuth: true, Access: accessAdmin,
+			OperationId: "createAPIKey", Summary: "Issues API key for a tenant, the key is returned only once",
+			Request:   PostAPIKeyRequest{},
+			Responses: map[int]interface{}{http.StatusCreated: PostAPIKeyResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/keys", Handler: s.handleGetAPIKeys, RequiresAuth: true, Access: accessAdmin,
+			OperationId: "getAPIKeys", Summary: "Returns issued API keys without secrets",
+			Responses: map[int]interface{}{http.StatusOK: GetAPIKeysResponse{}},
+		},
+		{
+			Method: http.MethodDelete, Path: "/admin/keys/{id}", Handler: s.handleDeleteAPIKey, RequiresAuth: true, Access: accessAdmin,
+			OperationId: "revokeAPIKey", Summary: "Revokes API key",
+			Responses: map[int]interface{}{http.StatusNoContent: nil},
+		},
+	}
+}
+
+// legacyRoutes returns deprecated routes without version, which validate method in handlers
+func (s *EthTransactionsServer) legacyRoutes() []route {
+	return []route{
+		{
+			Method: http.MethodGet, Path: "/chains", Handler: s.handleGetChains, Successor: "/chains",
+			OperationId: "legacyGetChains", Summary: "Returns indexed chains",
+			Responses: map[int]interface{}{http.StatusOK: GetChainsResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/health", Handler: s.handleGetHealth, Successor: "/health", Access: accessPublic,
+			OperationId: "legacyGetHealth", Summary: "Returns the state of parsers of all chains",
+			Responses: map[int]interface{}{http.StatusOK: GetHealthResponse{}, http.StatusServiceUnavailable: GetHealthResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: chainPrefix + "/health", Handler: s.handleGetChainHealth, Successor: chainPrefix + "/health", Access: accessPublic,
+			OperationId: "legacyGetChainHealth", Summary: "Returns the state of parser of the chain",
+			Responses: map[int]interface{}{http.StatusOK: ChainHealthResponse{}, http.StatusServiceUnavailable: ChainHealthResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/current", Handler: s.handleGetCurrentBlock, ChainScoped: true, Successor: "/blocks/current",
+			OperationId: "legacyGetCurrentBlock", Summary: "Returns the height of the last processed block",
+			Responses: map[int]interface{}{http.StatusOK: 0},
+		},
+		{
+			Method: http.MethodPost, Path: "/subscribe", Handler: s.handlePostSubscribe, ChainScoped: true, Successor: "/subscriptions/{address}",
+			OperationId: "legacySubscribe", RateLimitGroup: RateLimitGroupSubscriptions, Summary: "Subscribes to the address, ok is false if it's already subscribed",
+			Request:   PostSubscribeRequest{},
+			Responses: map[int]interface{}{http.StatusOK: PostSubscribeResponse{}},
+		},
+		{
+			Method: http.MethodPost, Path: "/subscribe/bulk", Handler: s.handlePostBulkSubscribe, ChainScoped: true, Successor: "/subscriptions",
+			OperationId: "legacyBulkSubscribe", RateLimitGroup: RateLimitGroupSubscriptions, Summary: "Subscribes to addresses at once",
+			Request: PostBulkSubscribeRequest{}, RequestMediaTypes: []string{"text/csv"},
+			Responses: map[int]interface{}{http.StatusOK: PostBulkSubscribeResponse{}},
+		},
+		{
+			Method: http.MethodPost, Path: "/transactions", Handler: s.handlePostGetTransactions, ChainScoped: true, Successor: "/addresses/{address}/transactions",
+			OperationId: "legacyGetTransactions", RateLimitGroup: RateLimitGroupTransactions, Summary: "Returns inbound and outbound transactions of the address",
+			Query:      transactionsQueryParams,
+			Request:    PostGetTransactionsRequest{},
+			Responses:  map[int]interface{}{http.StatusOK: PostGetTransactionsResponse{}},
+			MediaTypes: transactionsMediaTypes,
+		},
+		{
+			Method: http.MethodGet, Path: "/transactions/{hash}", Handler: s.handleGetTransactionByHash, ChainScoped: true, Successor: "/transactions/{hash}",
+			OperationId: "legacyGetTransaction", Summary: "Returns the indexed transaction of the hash",
+			Query:     viewQueryParams,
+			Responses: map[int]interface{}{http.StatusOK: TransactionResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/blocks/{number}/transactions", Handler: s.handleGetBlockTransactions, ChainScoped: true, Successor: "/blocks/{number}/transactions",
+			OperationId: "legacyGetBlockTransactions", Summary: "Returns indexed transactions in the block",
+			Query:     viewQueryParams,
+			Responses: map[int]interface{}{http.StatusOK: GetBlockTransactionsResponse{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/balance", Handler: s.handleGetBalance, ChainScoped: true, Successor: "/addresses/{address}/balance",
+			OperationId: "legacyGetBalance", Summary: "Returns balance and nonce of the address",
+			Query:     append([]queryParam{{Name: "address", Type: "string", Description: "Address to look up"}}, balanceQueryParams...),
+			Responses: map[int]interface{}{http.StatusOK: GetBalanceResponse{}},
+		},
+		{
+			Method: http.MethodPost, Path: "/abis", Handler: s.handlePostRegisterABI, Successor: "/abis/{address}", RequiresABIRegistry: true, Access: accessAdmin,
+			OperationId: "legacyRegisterABI", Summary: "Registers contract ABI used to decode inputs of transactions sent to the address",
+			Request:   PostRegisterABIRequest{},
+			Responses: map[int]interface{}{http.StatusOK: PostRegisterABIResponse{}},
+		},
+	}
+}
+
+// metaRoutes returns routes serving API documents and metrics
+func (s *EthTransactionsServer) metaRoutes() []route {
+	return []route{
+		{
+			Method: http.MethodGet, Path: "/metrics", Handler: s.handleGetMetrics, Access: accessPublic,
+			OperationId: "getMetrics", Summary: "Returns metrics of rate limiting in Prometheus text format",
+			Responses:  map[int]interface{}{http.StatusOK: ""},
+			MediaTypes: []string{"text/plain"},
+		},
+		{
+			Method: http.MethodGet, Path: "/openapi.json", Handler: s.handleGetOpenAPI, Access: accessPublic,
+			OperationId: "getOpenAPI", Summary: "Returns OpenAPI document of this API",
+			Responses: map[int]interface{}{http.StatusOK: json.RawMessage{}},
+		},
+		{
+			Method: http.MethodGet, Path: "/docs", Handler: s.handleGetDocs, Access: accessPublic,
+			OperationId: "getDocs", Summary: "Returns HTML page rendering OpenAPI document",
+			Responses:  map[int]interface{}{http.StatusOK: ""},
+			MediaTypes: []string{"text/html"},
+		},
+	}
+}
+
+// registerRoutes registers all routes to mux
+func (s *EthTransactionsServer) registerRoutes(mux *http.ServeMux) {
+	for _, rt := range s.v1Routes() {
+		if !s.enables(&rt) {
+			continue
+		}
+
+		for _, prefix := range rt.prefixes() {
+			mux.HandleFunc(rt.Method+" "+APIVersionPrefix+prefix+rt.Path, withJSONErrors(s.wrap(&rt)))
+		}
+	}
+
+	// any other path under /v1 results in 404 or 405 in JSON envelope
+	mux.HandleFunc(APIVersionPrefix+"/", withJSONErrors(func(w http.ResponseWriter, r *http.Request) {
+		s.handleV1Unmatched(mux, w, r)
+	}))
+
+	// legacy routes accept any method so that handlers respond 405 in plain text as before
+	for _, rt := range s.legacyRoutes() {
+		if !s.enables(&rt) {
+			continue
+		}
+
+		for _, prefix := range rt.prefixes() {
+			mux.HandleFunc(prefix+rt.Path, deprecated(APIVersionPrefix+prefix+rt.Successor, s.wrap(&rt)))
+		}
+	}
+
+	for _, rt := range s.metaRoutes() {
+		mux.HandleFunc(rt.Method+" "+rt.Path, s.wrap(&rt))
+	}
+}
+
+// wrap applies authentication and rate limiting to the handler of route
+// Rate limiting of route group follows authentication so that requests are counted per API key, and requests
+// requiring API key are limited per IP address before authentication
+func (s *EthTransactionsServer) wrap(rt *route) http.HandlerFunc {
+	group := rt.RateLimitGroup
+	if group == "" {
+		group = RateLimitGroupDefault
+	}
+
+	h := s.authenticate(rt.Access, s.rateLimit(group, rt.Handler))
+	if s.Keys == nil || rt.Access == accessPublic {
+		return h
+	}
+
+	return s.rateLimitIP(h)
+}
+
+// enables returns true if optional features required by the route are given
+func (s *EthTransactionsServer) enables(rt *route) bool {
+	return (!rt.RequiresABIRegistry || s.ABIRegistry != nil) &&
+		(!rt.RequiresAuth || s.Keys != nil) &&
+		(!rt.RequiresSupervisor || s.Supervisor != nil)
+}
+
+// prefixes returns path prefixes the route is served under
+func (rt *route) prefixes() []string {
+	if rt.ChainScoped {
+		return []string{"", chainPrefix}
+	}
+
+	return []string{""}
+}
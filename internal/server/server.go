@@ -7,27 +7,63 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/abi"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/auth"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/export"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	// number of exported transactions written before flushing response
+	exportFlushInterval = 100
 )
 
 type EthTransactionsServer struct {
-	Parser  Parser
-	Server  *http.Server
-	ErrorCh chan error
+	Parser      Parser      // parser of default chain, used by routes without chain
+	Chains      []Chain     // chains accessible by /chains/{chain} routes
+	ABIRegistry ABIRegistry // optional
+	Supervisor  Supervisor  // optional
+	Keys        *auth.Store // optional, API keys are required if given
+	Server      *http.Server
+	ErrorCh     chan error
+
+	tenants *tenantSubscriptions // addresses subscribed by each tenant, used only if Keys is given
+	limiter *requestLimiter      // optional
+	metrics *serverMetrics
+
+	// quotaMu makes counting new addresses, taking quota and subscribing atomic, used only if quota is limited
+	quotaMu sync.Mutex
 }
 
-func New(parser Parser, port uint) *EthTransactionsServer {
+// Option configures optional features of EthTransactionsServer
+type Option func(*EthTransactionsServer)
+
+// WithABIRegistry enables decoding of transaction inputs and POST /abis API
+func WithABIRegistry(registry ABIRegistry) Option {
+	return func(s *EthTransactionsServer) {
+		s.ABIRegistry = registry
+	}
+}
+
+func New(parser Parser, port uint, opts ...Option) *EthTransactionsServer {
 	handler := http.NewServeMux()
 
 	srv := &EthTransactionsServer{
 		Parser:  parser,
+		metrics: newServerMetrics(),
 		Server:  &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: handler},
 		ErrorCh: make(chan error),
 	}
 
-	handler.HandleFunc("/current", srv.handleGetCurrentBlock)
-	handler.HandleFunc("/subscribe", srv.handlePostSubscribe)
-	handler.HandleFunc("/transactions", srv.handlePostGetTransactions)
+	for _, opt := range opts {
+		opt(srv)
+	}
+
+	srv.registerRoutes(handler)
 
 	return srv
 }
@@ -56,12 +92,18 @@ func (s *EthTransactionsServer) ErrCh() <-chan error {
 func (s *EthTransactionsServer) handleGetCurrentBlock(w http.ResponseWriter, r *http.Request) {
 	// validate request
 	if r.Method != http.MethodGet {
-		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		s.fail(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
 		return
 	}
 
 	// get data
-	height := s.Parser.GetCurrentBlock()
+	height := parser.GetCurrentBlock()
 
 	log.Printf("/current is called, height=%d", height)
 
@@ -73,27 +115,43 @@ func (s *EthTransactionsServer) handleGetCurrentBlock(w http.ResponseWriter, r *
 func (s *EthTransactionsServer) handlePostSubscribe(w http.ResponseWriter, r *http.Request) {
 	// validate request
 	if r.Method != http.MethodPost {
-		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		s.fail(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
 		return
 	}
 
 	// parse request body
 	request := &PostSubscribeRequest{}
 	if err := s.readRequestBody(r, request); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
 		return
 	}
 
 	// validate request body
-	if err := validateAddress(request.Address); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	address, err := parseAddress(request.Address)
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidAddress, err.Error())
 		return
 	}
 
 	// register
-	subscribed := s.Parser.Subscribe(request.Address)
+	added, err := s.subscribeAddresses(r, parser, []types.Address{address})
+	if errors.Is(err, ErrQuotaExceeded) {
+		s.fail(w, r, http.StatusTooManyRequests, ErrCodeQuotaExceeded, err.Error())
+		return
+	} else if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
 
-	log.Printf("/subscribe is called, address=%s, subscribed=%t", request.Address, subscribed)
+	subscribed := added > 0
+
+	log.Printf("/subscribe is called, address=%s, subscribed=%t", address, subscribed)
 
 	// return response
 	s.writeResponse(w, &PostSubscribeResponse{
@@ -105,34 +163,246 @@ func (s *EthTransactionsServer) handlePostSubscribe(w http.ResponseWriter, r *ht
 func (s *EthTransactionsServer) handlePostGetTransactions(w http.ResponseWriter, r *http.Request) {
 	// validate request
 	if r.Method != http.MethodPost {
-		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		s.fail(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
 		return
 	}
 
 	// parse request body
 	request := &PostGetTransactionsRequest{}
 	if err := s.readRequestBody(r, request); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
 		return
 	}
 
 	// validate request body
-	if err := validateAddress(request.Address); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	address, err := parseAddress(request.Address)
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidAddress, err.Error())
+		return
+	}
+
+	s.writeTransactions(w, r, parser, address)
+}
+
+// writeTransactions writes transactions of an address in format requested by query parameter or Accept header
+func (s *EthTransactionsServer) writeTransactions(
+	w http.ResponseWriter,
+	r *http.Request,
+	parser Parser,
+	address types.Address,
+) {
+	if !s.canReadAddress(r, parser, address) {
+		s.fail(w, r, http.StatusForbidden, ErrCodeForbidden, fmt.Sprintf("address %s is not subscribed by the tenant", address))
+		return
+	}
+
+	opts := viewOptions(r)
+
+	// choose output format by query parameter or Accept header
+	format, err := requestedFormat(r)
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeUnsupportedFormat, err.Error())
+		return
+	}
+
+	if format != export.FormatJSON {
+		s.streamTransactions(w, r, parser, address, format, opts)
+		return
+	}
+
+	since, until, err := timeRangeQuery(r)
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// get data
+	var transactions []types.Transaction
+	if since.IsZero() && until.IsZero() {
+		transactions = parser.GetTransactions(address)
+	} else {
+		transactions = parser.GetTransactionsByTimeRange(address, since, until)
+	}
+
+	log.Printf("/transactions is called, address=%s, num transactions=%d", address, len(transactions))
+
+	response := &PostGetTransactionsResponse{
+		Transactions: s.toTransactionResponses(parser, transactions),
+	}
+
+	if queryFlag(r, "includePending") {
+		response.PendingTransactions = s.toPendingTransactionResponses(parser.GetPendingTransactions(address))
+	}
+
+	// return response
+	s.writeResponse(w, types.View(response, opts))
+}
+
+// handlePostRegisterABI is a handler for POST /abis
+func (s *EthTransactionsServer) handlePostRegisterABI(w http.ResponseWriter, r *http.Request) {
+	// validate request
+	if r.Method != http.MethodPost {
+		s.fail(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	// parse request body
+	request := &PostRegisterABIRequest{}
+	if err := s.readRequestBody(r, request); err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// validate request body
+	address, err := parseAddress(request.Address)
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidAddress, err.Error())
 		return
 	}
 
 	// register
-	transactions := s.Parser.GetTransactions(request.Address)
+	if err := s.ABIRegistry.Register(address, request.ABI); err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidABI, err.Error())
+		return
+	}
 
-	log.Printf("/transactions is called, address=%s, num transactions=%d", request.Address, len(transactions))
+	log.Printf("/abis is called, address=%s", address)
 
 	// return response
-	s.writeResponse(w, &PostGetTransactionsResponse{
-		Transactions: transactions,
+	s.writeResponse(w, &PostRegisterABIResponse{
+		Ok: true,
 	})
 }
 
+// toTransactionResponses attaches metadata to transactions
+func (s *EthTransactionsServer) toTransactionResponses(parser Parser, txs []types.Transaction) []TransactionResponse {
+	if txs == nil {
+		return nil
+	}
+
+	responses := make([]TransactionResponse, len(txs))
+	for i, tx := range txs {
+		responses[i] = TransactionResponse{
+			Transaction:  tx,
+			Status:       types.TxStatusConfirmed,
+			DecodedInput: s.decodeInput(&tx),
+		}
+
+		attachBlockHeader(parser, &responses[i])
+	}
+
+	return responses
+}
+
+// attachBlockHeader sets timestamp, fee and block summary of confirmed transaction if the block header is stored
+func attachBlockHeader(parser Parser, response *TransactionResponse) {
+	header, ok := parser.GetBlockHeader(response.BlockNumber.Uint64())
+	if !ok {
+		return
+	}
+
+	timestamp := header.Time()
+	response.Timestamp = &timestamp
+	response.Block = &BlockSummaryResponse{
+		BaseFeePerGas: header.BaseFeePerGas,
+		Miner:         header.Miner,
+	}
+
+	if fee := types.MaxFee(&response.Transaction, header.BaseFeePerGas); fee != nil {
+		response.MaxFee = types.NewQuantity(fee)
+	}
+}
+
+// toPendingTransactionResponses attaches metadata to transactions seen in mempool
+func (s *EthTransactionsServer) toPendingTransactionResponses(txs []types.PendingTransaction) []TransactionResponse {
+	responses := make([]TransactionResponse, len(txs))
+	for i, tx := range txs {
+		firstSeenAt := tx.FirstSeenAt
+
+		responses[i] = TransactionResponse{
+			Transaction:  tx.Transaction,
+			Status:       tx.Status,
+			FirstSeenAt:  &firstSeenAt,
+			ReplacedBy:   tx.ReplacedBy,
+			DecodedInput: s.decodeInput(&tx.Transaction),
+		}
+	}
+
+	return responses
+}
+
+// decodeInput decodes transaction input if ABI registry is available
+func (s *EthTransactionsServer) decodeInput(tx *types.Transaction) *abi.DecodedCall {
+	if s.ABIRegistry == nil {
+		return nil
+	}
+
+	return s.ABIRegistry.Decode(tx.To, tx.Input)
+}
+
+// streamTransactions writes transactions for an address in given format without loading whole history
+func (s *EthTransactionsServer) streamTransactions(
+	w http.ResponseWriter,
+	r *http.Request,
+	parser Parser,
+	address types.Address,
+	format export.Format,
+	opts types.ViewOptions,
+) {
+	encoder, err := export.NewEncoder(format, w, address, parser.GetBlockTimestamp, opts)
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeUnsupportedFormat, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"transactions-%s.%s\"", address, format.FileExtension()),
+	)
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+
+	count := 0
+	err = parser.IterateTransactions(address, func(tx *types.Transaction) error {
+		if err := encoder.Encode(tx); err != nil {
+			return err
+		}
+
+		// push data to client periodically
+		count++
+		if count%exportFlushInterval == 0 {
+			if err := encoder.Flush(); err != nil {
+				return err
+			}
+
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+
+		return nil
+	})
+	if err == nil {
+		err = encoder.Flush()
+	}
+
+	// header has been sent already, so just log an error
+	if err != nil {
+		log.Printf("failed to export transactions, address=%s, format=%s: %v", address, format, err)
+		return
+	}
+
+	log.Printf("/transactions is called, address=%s, format=%s, num transactions=%d", address, format, count)
+}
+
 // readRequestBody is a helper function to read request body and map to given body object
 func (s *EthTransactionsServer) readRequestBody(
 	r *http.Request,
@@ -150,32 +420,79 @@ func (s *EthTransactionsServer) readRequestBody(
 func (s *EthTransactionsServer) writeResponse(
 	w http.ResponseWriter,
 	response interface{},
+) {
+	s.writeResponseWithStatus(w, http.StatusOK, response)
+}
+
+// writeResponseWithStatus is a helper function to write given data in json format with given status
+func (s *EthTransactionsServer) writeResponseWithStatus(
+	w http.ResponseWriter,
+	status int,
+	response interface{},
 ) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 	encoder := json.NewEncoder(w)
 	if err := encoder.Encode(&response); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
-// validateAddress checks that given address is correct format in Ethereum
-func validateAddress(address string) error {
-	if len(address) != 42 {
-		return errors.New("given address is not 20 bytes hex")
+// parseAddress checks that given address is correct format in Ethereum and returns it in canonical form
+// Mixed case address is accepted only if it has valid EIP-55 checksum
+func parseAddress(address string) (types.Address, error) {
+	normalized, err := types.NormalizeAddress(address)
+	if err != nil {
+		return "", fmt.Errorf("given address is invalid: %w", err)
+	}
+
+	return normalized, nil
+}
+
+// requestedFormat returns export format specified by format query parameter or Accept header
+func requestedFormat(r *http.Request) (export.Format, error) {
+	if raw := r.URL.Query().Get("format"); raw != "" {
+		return export.ParseFormat(raw)
 	}
-	if !isHex(address) {
-		return errors.New("given address isn't represented in hex")
+
+	return export.FormatFromAccept(r.Header.Get("Accept")), nil
+}
+
+// viewOptions returns representation options specified by decimal and checksum query parameters
+func viewOptions(r *http.Request) types.ViewOptions {
+	return types.ViewOptions{
+		DecimalQuantities: queryFlag(r, "decimal"),
+		ChecksumAddresses: queryFlag(r, "checksum"),
 	}
-	return nil
 }
 
-// isHex is a helper function to validate hex string
-func isHex(s string) bool {
-	hexPattern := `^0x[0-9a-fA-F]+$`
-	matched, err := regexp.MatchString(hexPattern, s)
-	if err != nil {
-		return false
+// timeRangeQuery returns the time range given by since and until query parameters in RFC 3339
+// Zero time is returned for the omitted parameter
+func timeRangeQuery(r *http.Request) (since time.Time, until time.Time, err error) {
+	query := r.URL.Query()
+
+	if raw := query.Get("since"); raw != "" {
+		if since, err = time.Parse(time.RFC3339, raw); err != nil {
+			return since, until, fmt.Errorf("given since is invalid, it must be in RFC 3339: %s", raw)
+		}
 	}
-	return matched
+
+	if raw := query.Get("until"); raw != "" {
+		if until, err = time.Parse(time.RFC3339, raw); err != nil {
+			return since, until, fmt.Errorf("given until is invalid, it must be in RFC 3339: %s", raw)
+		}
+	}
+
+	if !since.IsZero() && !until.IsZero() && !since.Before(until) {
+		return since, until, fmt.Errorf("since must be before until")
+	}
+
+	return since, until, nil
+}
+
+// queryFlag returns true if query parameter of given key is true
+func queryFlag(r *http.Request, key string) bool {
+	enabled, err := strconv.ParseBool(r.URL.Query().Get(key))
+
+	return err == nil && enabled
 }
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/testchain"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/txstorage"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/pkg/parser"
+)
+
+// testServer is an API server whose parser indexes a fake node
+type testServer struct {
+	api    *httptest.Server
+	server *EthTransactionsServer
+	chain  *testchain.Chain
+	parser *parser.Parser
+}
+
+// newTestServer starts a parser of a fake node and API server on it, they are stopped when the test finishes
+func newTestServer(t *testing.T, opts ...Option) *testServer {
+	t.Helper()
+
+	node := testchain.NewServer(testchain.NewChain(testchain.Config{TxsPerBlock: -1, WithdrawalsPerBlock: -1}))
+	rpc := httptest.NewServer(node)
+	t.Cleanup(rpc.Close)
+
+	client := jsonrpc.New(&http.Client{}, rpc.URL)
+	p := parser.New(client, txstorage.New(),
+		parser.WithLogger(log.New(io.Discard, "", 0)),
+		parser.WithPollingInterval(20*time.Millisecond),
+		parser.WithBalanceTracking(client),
+	)
+
+	if err := p.Start(big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		if err := p.Stop(ctx); err != nil {
+			t.Errorf("failed to stop parser: %v", err)
+		}
+	})
+
+	srv := New(p, 0, opts...)
+	api := httptest.NewServer(srv.Server.Handler)
+	t.Cleanup(api.Close)
+
+	return &testServer{api: api, server: srv, chain: node.Chain(), parser: p}
+}
+
+// do calls API and decodes JSON response into out if given, the response is returned with its body read
+func (s *testServer) do(t *testing.T, method, path string, body interface{}, out interface{}) *http.Response {
+	t.Helper()
+
+	return s.send(t, s.request(t, method, path, body), out)
+}
+
+// request returns a request of API with JSON encoded body if given, headers can be set before sending it
+func (s *testServer) request(t *testing.T, method, path string, body interface{}) *http.Request {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, s.api.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return req
+}
+
+// send sends request and decodes JSON response into out if given, the response is returned with its body read
+func (s *testServer) send(t *testing.T, req *http.Request, out interface{}) *http.Response {
+	t.Helper()
+
+	res, err := s.api.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			t.Fatalf("failed to decode response of %s %s: %v", req.Method, req.URL.Path, err)
+		}
+	}
+
+	return res
+}
+
+// waitForHeight waits until the parser processes the block at height
+func (s *testServer) waitForHeight(t *testing.T, height uint64) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for uint64(s.parser.GetCurrentBlock()) < height {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for parser to process block %d", height)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSubscribeAndGetTransactions(t *testing.T) {
+	s := newTestServer(t)
+
+	alice, bob := s.chain.Accounts()[0], s.chain.Accounts()[1]
+
+	subscribed := &PostBulkSubscribeResponse{}
+	res := s.do(t, http.MethodPost, "/v1/subscriptions", &PostBulkSubscribeRequest{Addresses: []string{string(alice)}}, subscribed)
+	if res.StatusCode != http.StatusOK || subscribed.Subscribed != 1 {
+		t.Fatalf("failed to subscribe, status=%d, response=%+v", res.StatusCode, subscribed)
+	}
+
+	sent, err := s.chain.Send(alice, bob, big.NewInt(1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := s.chain.Mine()
+	s.waitForHeight(t, block.Number.Uint64())
+
+	current := &GetCurrentBlockResponse{}
+	s.do(t, http.MethodGet, "/v1/blocks/current", nil, current)
+	if current.Height != int(block.Number.Uint64()) {
+		t.Fatalf("expected current block %d, but got %d", block.Number.Uint64(), current.Height)
+	}
+
+	txs := &PostGetTransactionsResponse{}
+	s.do(t, http.MethodGet, "/v1/addresses/"+string(alice)+"/transactions", nil, txs)
+	if len(txs.Transactions) != 1 || !txs.Transactions[0].Hash.Equal(sent.Hash) {
+		t.Fatalf("expected only %s of alice, but got %+v", sent.Hash, txs.Transactions)
+	}
+
+	tx := &TransactionResponse{}
+	res = s.do(t, http.MethodGet, "/v1/transactions/"+string(sent.Hash), nil, tx)
+	if res.StatusCode != http.StatusOK || !tx.BlockHash.Equal(block.Hash) {
+		t.Fatalf("expected %s in block %s, but got status=%d, response=%+v", sent.Hash, block.Hash, res.StatusCode, tx)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	s := newTestServer(t)
+
+	unknownHash := types.Hash("0x" + strings.Repeat("ab", 32))
+
+	for _, tt := range []struct {
+		name   string
+		method string
+		path   string
+		status int
+		code   ErrorCode
+	}{
+		{"invalid address", http.MethodGet, "/v1/addresses/0x1234/transactions", http.StatusBadRequest, ErrCodeInvalidAddress},
+		{"invalid hash", http.MethodGet, "/v1/transactions/0x1234", http.StatusBadRequest, ErrCodeInvalidHash},
+		{"unknown transaction", http.MethodGet, "/v1/transactions/" + string(unknownHash), http.StatusNotFound, ErrCodeNotFound},
+		{"unknown chain", http.MethodGet, "/v1/chains/unknown/blocks/current", http.StatusNotFound, ErrCodeChainNotFound},
+		{"invalid body", http.MethodPost, "/v1/subscriptions", http.StatusBadRequest, ErrCodeInvalidRequest},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &ErrorResponse{}
+			res := s.do(t, tt.method, tt.path, nil, response)
+
+			if res.StatusCode != tt.status || response.Error.Code != tt.code || response.Error.Status != tt.status {
+				t.Fatalf("expected %d %s, but got status=%d, error=%+v", tt.status, tt.code, res.StatusCode, response.Error)
+			}
+		})
+	}
+
+	// legacy API responds errors in plain text
+	res := s.do(t, http.MethodGet, "/transactions/0x1234", nil, nil)
+	if res.StatusCode != http.StatusBadRequest || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("expected plain text error of 400 status, but got status=%d, content type=%s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+}
+
+func TestExportFormatNegotiation(t *testing.T) {
+	s := newTestServer(t)
+
+	alice, bob := s.chain.Accounts()[0], s.chain.Accounts()[1]
+	for _, address := range []string{string(alice), anotherAddress} {
+		if res := s.do(t, http.MethodPut, "/v1/subscriptions/"+address, nil, nil); res.StatusCode != http.StatusCreated {
+			t.Fatalf("failed to subscribe %s, status=%d", address, res.StatusCode)
+		}
+	}
+
+	sent, err := s.chain.Send(alice, bob, big.NewInt(1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := s.chain.Mine()
+	s.waitForHeight(t, block.Number.Uint64())
+
+	// export returns the response with its whole body
+	export := func(address, query, accept string) (*http.Response, string) {
+		t.Helper()
+
+		req := s.request(t, http.MethodGet, "/v1/addresses/"+address+"/transactions"+query, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		res, err := s.api.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res, string(body)
+	}
+
+	for _, tt := range []struct {
+		name        string
+		query       string
+		accept      string
+		contentType string
+		firstLine   string
+		contains    string
+	}{
+		{"default", "", "", "application/json", `{"transactions":[`, string(sent.Hash)},
+		{"csv by Accept", "", "text/csv", "text/csv; charset=utf-8", "hash,block_number,timestamp", string(sent.Hash)},
+		{"ndjson by Accept", "", "application/x-ndjson", "application/x-ndjson", `{"blockHash":`, string(sent.Hash)},
+		{"format overrides Accept", "?format=koinly", "text/csv", "text/csv; charset=utf-8", "Date,Sent Amount", string(sent.Hash)},
+		{"cointracker", "?format=cointracker", "", "text/csv; charset=utf-8", "Date,Received Quantity", ",,,0.000000000000000001,ETH,"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := export(string(alice), tt.query, tt.accept)
+			if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != tt.contentType {
+				t.Fatalf("expected 200 in %s, but got status=%d, content type=%s", tt.contentType, res.StatusCode, res.Header.Get("Content-Type"))
+			}
+
+			if !strings.HasPrefix(body, tt.firstLine) || !strings.Contains(body, tt.contains) {
+				t.Fatalf("expected body starting with %q and having %q, but got\n%s", tt.firstLine, tt.contains, body)
+			}
+		})
+	}
+
+	// an address without transactions is exported as a CSV having only header
+	res, body := export(anotherAddress, "?format=csv", "")
+	if res.StatusCode != http.StatusOK || strings.Count(body, "\n") != 1 || !strings.HasPrefix(body, "hash,") {
+		t.Fatalf("expected only header of CSV, but got status=%d, body=%q", res.StatusCode, body)
+	}
+
+	response := &ErrorResponse{}
+	res = s.do(t, http.MethodGet, "/v1/addresses/"+string(alice)+"/transactions?format=xml", nil, response)
+	if res.StatusCode != http.StatusBadRequest || response.Error.Code != ErrCodeUnsupportedFormat {
+		t.Fatalf("expected 400 %s, but got status=%d, error=%+v", ErrCodeUnsupportedFormat, res.StatusCode, response.Error)
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// tenantSubscriptions keeps addresses subscribed by each tenant
+// Parsers observe the union of them, and tenants can read only data of their own addresses
+type tenantSubscriptions struct {
+	addresses map[string]map[Parser]map[types.Address]struct{} // tenant -> parser of chain -> addresses in lower case
+
+	mutex sync.RWMutex
+}
+
+func newTenantSubscriptions() *tenantSubscriptions {
+	return &tenantSubscriptions{
+		addresses: make(map[string]map[Parser]map[types.Address]struct{}),
+	}
+}
+
+// add adds addresses for the tenant and returns the number of addresses newly added
+func (t *tenantSubscriptions) add(tenant string, parser Parser, addresses []types.Address) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	chains, ok := t.addresses[tenant]
+	if !ok {
+		chains = make(map[Parser]map[types.Address]struct{})
+		t.addresses[tenant] = chains
+	}
+
+	set, ok := chains[parser]
+	if !ok {
+		set = make(map[types.Address]struct{})
+		chains[parser] = set
+	}
+
+	added := 0
+	for _, address := range addresses {
+		key := address.Lower()
+		if _, ok := set[key]; ok {
+			continue
+		}
+
+		set[key] = struct{}{}
+		added++
+	}
+
+	return added
+}
+
+// remove removes address of the tenant, orphaned is true if no other tenant subscribes to it
+func (t *tenantSubscriptions) remove(tenant string, parser Parser, address types.Address) (removed bool, orphaned bool) {
+	key := address.Lower()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	set := t.addresses[tenant][parser]
+	if _, ok := set[key]; !ok {
+		return false, false
+	}
+
+	delete(set, key)
+
+	return true, !t.isSubscribedByAnyone(parser, key)
+}
+
+// removeAll removes address from all tenants
+func (t *tenantSubscriptions) removeAll(parser Parser, address types.Address) {
+	key := address.Lower()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, chains := range t.addresses {
+		delete(chains[parser], key)
+	}
+}
+
+// contains returns true if the tenant subscribes to the address
+func (t *tenantSubscriptions) contains(tenant string, parser Parser, address types.Address) bool {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	_, ok := t.addresses[tenant][parser][address.Lower()]
+
+	return ok
+}
+
+// filter returns addresses subscribed by the tenant among given ones
+func (t *tenantSubscriptions) filter(tenant string, parser Parser, addresses []types.Address) []types.Address {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	set := t.addresses[tenant][parser]
+
+	filtered := make([]types.Address, 0, len(addresses))
+	for _, address := range addresses {
+		if _, ok := set[address.Lower()]; ok {
+			filtered = append(filtered, address)
+		}
+	}
+
+	return filtered
+}
+
+// isSubscribedByAnyone returns true if any tenant subscribes to the address in lower case, mutex must be held
+func (t *tenantSubscriptions) isSubscribedByAnyone(parser Parser, key types.Address) bool {
+	for _, chains := range t.addresses {
+		if _, ok := chains[parser][key]; ok {
+			return true
+		}
+	}
+
+	return false
+}
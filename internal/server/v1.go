@@ -0,0 +1,222 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	// APIVersionPrefix is the prefix of routes in versioned API
+	APIVersionPrefix = "/v1"
+)
+
+// methods probed to build Allow header of 405 responses
+var allowProbeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// handleV1GetCurrentBlock is a handler for GET /v1/blocks/current
+func (s *EthTransactionsServer) handleV1GetCurrentBlock(w http.ResponseWriter, r *http.Request) {
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	height := parser.GetCurrentBlock()
+
+	log.Printf("/v1/blocks/current is called, height=%d", height)
+
+	s.writeResponse(w, &GetCurrentBlockResponse{
+		Height: height,
+	})
+}
+
+// handleV1GetAddressTransactions is a handler for GET /v1/addresses/{address}/transactions
+func (s *EthTransactionsServer) handleV1GetAddressTransactions(w http.ResponseWriter, r *http.Request) {
+	// validate request
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	address, err := parseAddress(r.PathValue("address"))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidAddress, err.Error())
+		return
+	}
+
+	s.writeTransactions(w, r, parser, address)
+}
+
+// handleV1GetSubscription is a handler for GET /v1/subscriptions/{address}
+func (s *EthTransactionsServer) handleV1GetSubscription(w http.ResponseWriter, r *http.Request) {
+	// validate request
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	address, err := parseAddress(r.PathValue("address"))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidAddress, err.Error())
+		return
+	}
+
+	if !s.isSubscribed(r, parser, address) {
+		s.fail(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("address %s is not subscribed", address))
+		return
+	}
+
+	s.writeResponse(w, &SubscriptionResponse{
+		Address: address,
+	})
+}
+
+// handleV1PutSubscription is a handler for PUT /v1/subscriptions/{address}
+// It responds 201 if the address is newly subscribed and 200 if it's already subscribed
+func (s *EthTransactionsServer) handleV1PutSubscription(w http.ResponseWriter, r *http.Request) {
+	// validate request
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	address, err := parseAddress(r.PathValue("address"))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidAddress, err.Error())
+		return
+	}
+
+	// register
+	added, err := s.subscribeAddresses(r, parser, []types.Address{address})
+	if errors.Is(err, ErrQuotaExceeded) {
+		s.fail(w, r, http.StatusTooManyRequests, ErrCodeQuotaExceeded, err.Error())
+		return
+	} else if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+
+	subscribed := added > 0
+
+	log.Printf("/v1/subscriptions is called, address=%s, subscribed=%t", address, subscribed)
+
+	// return response
+	status := http.StatusOK
+	if subscribed {
+		status = http.StatusCreated
+		w.Header().Set("Location", r.URL.Path)
+	}
+
+	s.writeResponseWithStatus(w, status, &SubscriptionResponse{
+		Address: address,
+	})
+}
+
+// handleV1DeleteSubscription is a handler for DELETE /v1/subscriptions/{address}
+func (s *EthTransactionsServer) handleV1DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	// validate request
+	parser, err := s.resolveParser(r)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+		return
+	}
+
+	address, err := parseAddress(r.PathValue("address"))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidAddress, err.Error())
+		return
+	}
+
+	// unregister
+	if !s.unsubscribeAddress(r, parser, address) {
+		s.fail(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("address %s is not subscribed", address))
+		return
+	}
+
+	log.Printf("/v1/subscriptions is called, address=%s, unsubscribed=true", address)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleV1PutABI is a handler for PUT /v1/abis/{address}, request body is contract ABI in JSON
+func (s *EthTransactionsServer) handleV1PutABI(w http.ResponseWriter, r *http.Request) {
+	// validate request
+	address, err := parseAddress(r.PathValue("address"))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidAddress, err.Error())
+		return
+	}
+
+	// parse request body
+	abiJSON, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// register
+	if err := s.ABIRegistry.Register(address, abiJSON); err != nil {
+		s.fail(w, r, http.StatusBadRequest, ErrCodeInvalidABI, err.Error())
+		return
+	}
+
+	log.Printf("/v1/abis is called, address=%s", address)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleV1Unmatched responds 405 with Allow header if path exists for other methods, otherwise 404
+func (s *EthTransactionsServer) handleV1Unmatched(mux *http.ServeMux, w http.ResponseWriter, r *http.Request) {
+	allowed := make([]string, 0, len(allowProbeMethods))
+
+	for _, method := range allowProbeMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+
+		if _, pattern := mux.Handler(probe); pattern != "" && pattern != APIVersionPrefix+"/" {
+			allowed = append(allowed, method)
+		}
+	}
+
+	if len(allowed) == 0 {
+		s.fail(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("%s is not found", r.URL.Path))
+		return
+	}
+
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	s.fail(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, fmt.Sprintf("%s method is not allowed", r.Method))
+}
+
+// deprecated marks legacy route as deprecated in favor of given route of versioned API
+// Path parameters of the successor are filled by the ones of the request if they are given in path
+func deprecated(successor string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := pathParamPattern.ReplaceAllStringFunc(successor, func(param string) string {
+			if value := r.PathValue(param[1 : len(param)-1]); value != "" {
+				return value
+			}
+
+			return param
+		})
+
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", link))
+
+		h(w, r)
+	}
+}
@@ -0,0 +1,611 @@
+package testchain
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/crypto"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/rlp"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/trie"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/verify"
+)
+
+const (
+	DefaultChainId             uint64 = 1337
+	DefaultAccounts                   = 16
+	DefaultTxsPerBlock                = 4
+	DefaultWithdrawalsPerBlock        = 2
+	DefaultBlockTime                  = 12 * time.Second
+	DefaultGasLimit            uint64 = 30_000_000
+
+	// gas of a plain transfer
+	transferGas uint64 = 21_000
+	// fees are constant so that balances are easy to compute in tests
+	baseFeePerGas        uint64 = 1_000_000_000
+	maxPriorityFeePerGas uint64 = 1_000_000_000
+)
+
+var (
+	// DefaultGenesisTime is the timestamp of block 0 unless Config.GenesisTime is given
+	DefaultGenesisTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// InitialBalance is the balance of each generated account at genesis
+	InitialBalance = new(big.Int).Mul(big.NewInt(1_000), big.NewInt(1e18))
+
+	ErrUnknownSender       = errors.New("sender is not an account of the chain")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrInvalidReorgDepth   = errors.New("invalid reorg depth")
+
+	// keccak256 of RLP encoded empty list, which is the hash of empty uncles
+	emptyUnclesHash = crypto.Keccak256(rlp.EncodeList())
+)
+
+// Config configures the generated chain, zero values are replaced by defaults
+type Config struct {
+	ChainId     uint64
+	Seed        uint64        // seed of random values, same seed and operations generate the same chain
+	GenesisTime time.Time     // timestamp of block 0
+	BlockTime   time.Duration // difference of timestamps between blocks
+
+	// InitialHeight is the number of blocks mined by NewChain after genesis
+	InitialHeight uint64
+
+	// Accounts is the number of generated accounts which have balance at genesis and send transactions
+	Accounts int
+	// Addresses receive transactions and withdrawals in addition to generated accounts, e.g. addresses subscribed in tests
+	Addresses []types.Address
+
+	// TxsPerBlock is the number of random transactions in each block, negative means none
+	TxsPerBlock int
+	// WithdrawalsPerBlock is the number of random withdrawals in each block, negative means none
+	WithdrawalsPerBlock int
+
+	// ReorgEvery replaces the last ReorgDepth blocks every ReorgEvery mined blocks, 0 disables reorgs
+	ReorgEvery uint64
+	ReorgDepth uint64
+}
+
+// withDefaults returns the config whose zero values are replaced by defaults
+func (c Config) withDefaults() Config {
+	if c.ChainId == 0 {
+		c.ChainId = DefaultChainId
+	}
+
+	if c.GenesisTime.IsZero() {
+		c.GenesisTime = DefaultGenesisTime
+	}
+
+	if c.BlockTime <= 0 {
+		c.BlockTime = DefaultBlockTime
+	}
+
+	if c.Accounts <= 0 {
+		c.Accounts = DefaultAccounts
+	}
+
+	if c.TxsPerBlock == 0 {
+		c.TxsPerBlock = DefaultTxsPerBlock
+	}
+
+	if c.WithdrawalsPerBlock == 0 {
+		c.WithdrawalsPerBlock = DefaultWithdrawalsPerBlock
+	}
+
+	if c.ReorgEvery > 0 && c.ReorgDepth == 0 {
+		c.ReorgDepth = 1
+	}
+
+	return c
+}
+
+// account is the state of an address
+type account struct {
+	Balance *big.Int
+	Nonce   uint64
+}
+
+// state maps lowercase address to its account
+type state map[types.Address]account
+
+func (s state) get(address types.Address) account {
+	if acc, ok := s[address.Lower()]; ok {
+		return acc
+	}
+
+	return account{Balance: new(big.Int)}
+}
+
+func (s state) set(address types.Address, acc account) {
+	s[address.Lower()] = acc
+}
+
+func (s state) copy() state {
+	copied := make(state, len(s))
+	for address, acc := range s {
+		copied[address] = account{Balance: new(big.Int).Set(acc.Balance), Nonce: acc.Nonce}
+	}
+
+	return copied
+}
+
+// txLocation is the position of a mined transaction
+type txLocation struct {
+	Height uint64
+	Index  int
+}
+
+// Chain is a deterministic Ethereum chain generated in memory
+// Blocks have valid hashes, transactions roots and withdrawals roots, but signatures are random
+type Chain struct {
+	config Config
+
+	accounts  []types.Address // generated accounts which send transactions
+	receivers []types.Address // accounts and configured addresses
+	miner     types.Address
+
+	blocks  []*types.Block // index is height
+	states  []state        // state after the block of the same index
+	txIndex map[types.Hash]txLocation
+
+	// transactions which will be included in the next block
+	pending      []types.Transaction
+	pendingState state
+
+	// incremented by reorgs so that replaced blocks have different contents
+	fork uint64
+
+	mutex sync.RWMutex
+}
+
+// NewChain generates genesis and Config.InitialHeight blocks
+func NewChain(config Config) *Chain {
+	config = config.withDefaults()
+
+	c := &Chain{
+		config:  config,
+		miner:   deriveAddress(config.Seed, "miner", 0),
+		txIndex: make(map[types.Hash]txLocation),
+	}
+
+	genesis := make(state, config.Accounts)
+	for i := 0; i < config.Accounts; i++ {
+		address := deriveAddress(config.Seed, "account", uint64(i))
+		c.accounts = append(c.accounts, address)
+		genesis.set(address, account{Balance: new(big.Int).Set(InitialBalance)})
+	}
+
+	c.receivers = append(append(c.receivers, c.accounts...), config.Addresses...)
+
+	c.blocks = append(c.blocks, c.sealBlock(0, nil, nil, genesis))
+	c.states = append(c.states, genesis)
+	c.resetPending()
+
+	for i := uint64(0); i < config.InitialHeight; i++ {
+		c.appendBlock()
+	}
+
+	return c
+}
+
+// ChainId returns the chain id
+func (c *Chain) ChainId() uint64 {
+	return c.config.ChainId
+}
+
+// Accounts returns generated accounts, which have balance at genesis
+func (c *Chain) Accounts() []types.Address {
+	return append([]types.Address(nil), c.accounts...)
+}
+
+// Height returns the height of the latest block
+func (c *Chain) Height() uint64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return uint64(len(c.blocks) - 1)
+}
+
+// Block returns the block at height, nil if it's not mined yet
+// The returned block must not be modified
+func (c *Chain) Block(height uint64) *types.Block {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if height >= uint64(len(c.blocks)) {
+		return nil
+	}
+
+	return c.blocks[height]
+}
+
+// Transaction returns mined or pending transaction by hash, nil if it doesn't exist
+func (c *Chain) Transaction(hash types.Hash) *types.Transaction {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if location, ok := c.txIndex[hashKey(hash)]; ok {
+		tx := c.blocks[location.Height].Transactions[location.Index]
+		return &tx
+	}
+
+	for _, tx := range c.pending {
+		if tx.Hash.Equal(hash) {
+			tx := tx
+			return &tx
+		}
+	}
+
+	return nil
+}
+
+// Pending returns transactions which will be included in the next block
+func (c *Chain) Pending() []types.Transaction {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return append([]types.Transaction(nil), c.pending...)
+}
+
+// Balance returns the balance of address after the block at height, pending state is used if height is nil
+func (c *Chain) Balance(address types.Address, height *uint64) (*big.Int, error) {
+	acc, err := c.account(address, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).Set(acc.Balance), nil
+}
+
+// Nonce returns the nonce of address after the block at height, pending state is used if height is nil
+func (c *Chain) Nonce(address types.Address, height *uint64) (uint64, error) {
+	acc, err := c.account(address, height)
+	if err != nil {
+		return 0, err
+	}
+
+	return acc.Nonce, nil
+}
+
+func (c *Chain) account(address types.Address, height *uint64) (account, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if height == nil {
+		return c.pendingState.get(address), nil
+	}
+
+	if *height >= uint64(len(c.states)) {
+		return account{}, fmt.Errorf("block %d is not mined", *height)
+	}
+
+	return c.states[*height].get(address), nil
+}
+
+// Send adds a transaction of value from a generated account to pending transactions, it's mined in the next block
+// To may be empty for contract creation
+func (c *Chain) Send(from, to types.Address, value *big.Int, input types.Data) (*types.Transaction, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.isAccount(from) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSender, from)
+	}
+
+	if input == "" {
+		input = "0x"
+	}
+
+	height := uint64(len(c.blocks))
+	rng := c.rng(height, "send", uint64(len(c.pending)))
+
+	tx, err := c.addPending(rng, from, to, value, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// Mine appends a block of pending transactions and returns it
+// A reorg happens after the block if the height is a multiple of Config.ReorgEvery
+func (c *Chain) Mine() *types.Block {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.mine()
+}
+
+// Reorg replaces the last depth blocks with blocks of different contents, the height doesn't change
+// Transactions of replaced blocks are dropped, as if they were replaced by conflicting ones
+func (c *Chain) Reorg(depth uint64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.reorg(depth)
+}
+
+func (c *Chain) mine() *types.Block {
+	height := c.appendBlock()
+
+	if c.config.ReorgEvery > 0 && height%c.config.ReorgEvery == 0 && height > c.config.ReorgDepth {
+		// depth is validated by the condition above
+		_ = c.reorg(c.config.ReorgDepth)
+	}
+
+	return c.blocks[len(c.blocks)-1]
+}
+
+func (c *Chain) reorg(depth uint64) error {
+	tip := uint64(len(c.blocks) - 1)
+	if depth == 0 || depth > tip {
+		return fmt.Errorf("%w: depth=%d, height=%d", ErrInvalidReorgDepth, depth, tip)
+	}
+
+	for _, block := range c.blocks[tip-depth+1:] {
+		for _, tx := range block.Transactions {
+			delete(c.txIndex, hashKey(tx.Hash))
+		}
+	}
+
+	c.blocks = c.blocks[:tip-depth+1]
+	c.states = c.states[:tip-depth+1]
+	c.fork++
+	c.resetPending()
+
+	for i := uint64(0); i < depth; i++ {
+		c.appendBlock()
+	}
+
+	return nil
+}
+
+// appendBlock seals pending transactions and withdrawals into a new block, and returns its height
+func (c *Chain) appendBlock() uint64 {
+	height := uint64(len(c.blocks))
+
+	next := c.pendingState
+	withdrawals := c.generateWithdrawals(height, next)
+
+	block := c.sealBlock(height, c.pending, withdrawals, next)
+	for i := range block.Transactions {
+		c.txIndex[hashKey(block.Transactions[i].Hash)] = txLocation{Height: height, Index: i}
+	}
+
+	c.blocks = append(c.blocks, block)
+	c.states = append(c.states, next)
+	c.resetPending()
+
+	return height
+}
+
+// resetPending starts pending state from the latest state and generates random transactions of the next block
+func (c *Chain) resetPending() {
+	height := uint64(len(c.blocks))
+
+	c.pending = nil
+	c.pendingState = c.states[height-1].copy()
+
+	if c.config.TxsPerBlock < 0 {
+		return
+	}
+
+	rng := c.rng(height, "transactions", 0)
+	for i := 0; i < c.config.TxsPerBlock; i++ {
+		from := c.accounts[rng.IntN(len(c.accounts))]
+		to := c.receivers[rng.IntN(len(c.receivers))]
+		value := new(big.Int).SetUint64(rng.Uint64N(1e18))
+
+		// generated accounts have enough balance for a long time, skip the sender if it's drained
+		_, _ = c.addPending(rng, from, to, value, "0x")
+	}
+}
+
+// addPending signs transaction with random signature and appends it to pending transactions
+func (c *Chain) addPending(rng *rand.Rand, from, to types.Address, value *big.Int, input types.Data) (*types.Transaction, error) {
+	sender := c.pendingState.get(from)
+
+	gasPrice := baseFeePerGas + maxPriorityFeePerGas
+	cost := new(big.Int).Add(value, new(big.Int).SetUint64(transferGas*gasPrice))
+	if sender.Balance.Cmp(cost) < 0 {
+		return nil, fmt.Errorf("%w: address=%s, balance=%s, cost=%s", ErrInsufficientBalance, from, sender.Balance, cost)
+	}
+
+	tx := types.Transaction{
+		Type:                 types.NewQuantityFromUint64(verify.TxTypeDynamicFee),
+		ChainId:              types.NewQuantityFromUint64(c.config.ChainId),
+		Nonce:                types.NewQuantityFromUint64(sender.Nonce),
+		From:                 from.Lower(),
+		To:                   to.Lower(),
+		Value:                types.NewQuantity(value),
+		Input:                input,
+		Gas:                  types.NewQuantityFromUint64(transferGas),
+		GasPrice:             types.NewQuantityFromUint64(gasPrice),
+		MaxFeePerGas:         types.NewQuantityFromUint64(2*baseFeePerGas + maxPriorityFeePerGas),
+		MaxPriorityFeePerGas: types.NewQuantityFromUint64(maxPriorityFeePerGas),
+		AccessList:           []types.AccessListElement{},
+		YParity:              types.NewQuantityFromUint64(rng.Uint64N(2)),
+		R:                    types.NewQuantity(new(big.Int).SetBytes(randomBytes(rng, 32))),
+		S:                    types.NewQuantity(new(big.Int).SetBytes(randomBytes(rng, 32))),
+	}
+	tx.V = tx.YParity
+
+	envelope, err := verify.EncodeTransaction(&tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	tx.Hash = hashOf(crypto.Keccak256(envelope))
+
+	// apply transfer and fees, tips are paid to miner
+	sender.Balance = new(big.Int).Sub(sender.Balance, cost)
+	sender.Nonce++
+	c.pendingState.set(from, sender)
+
+	receiver := c.pendingState.get(to)
+	receiver.Balance = new(big.Int).Add(receiver.Balance, value)
+	c.pendingState.set(to, receiver)
+
+	miner := c.pendingState.get(c.miner)
+	miner.Balance = new(big.Int).Add(miner.Balance, new(big.Int).SetUint64(transferGas*maxPriorityFeePerGas))
+	c.pendingState.set(c.miner, miner)
+
+	c.pending = append(c.pending, tx)
+
+	return &tx, nil
+}
+
+// generateWithdrawals returns random withdrawals of block at height and applies them to state
+func (c *Chain) generateWithdrawals(height uint64, s state) []types.Withdrawal {
+	withdrawals := make([]types.Withdrawal, 0, max(c.config.WithdrawalsPerBlock, 0))
+
+	rng := c.rng(height, "withdrawals", 0)
+	for i := 0; i < c.config.WithdrawalsPerBlock; i++ {
+		address := c.receivers[rng.IntN(len(c.receivers))]
+		amount := rng.Uint64N(1e9) // in gwei
+
+		withdrawals = append(withdrawals, types.Withdrawal{
+			Index:          types.NewQuantityFromUint64(height*uint64(c.config.WithdrawalsPerBlock) + uint64(i)),
+			ValidatorIndex: types.NewQuantityFromUint64(rng.Uint64N(1_000_000)),
+			Address:        address.Lower(),
+			Amount:         types.NewQuantityFromUint64(amount),
+		})
+
+		acc := s.get(address)
+		acc.Balance = new(big.Int).Add(acc.Balance, new(big.Int).Mul(new(big.Int).SetUint64(amount), big.NewInt(1e9)))
+		s.set(address, acc)
+	}
+
+	return withdrawals
+}
+
+// sealBlock builds block at height on top of the latest block, and computes roots and hashes
+func (c *Chain) sealBlock(height uint64, txs []types.Transaction, withdrawals []types.Withdrawal, s state) *types.Block {
+	rng := c.rng(height, "header", 0)
+
+	parentHash := hashOf(make([]byte, 32))
+	if height > 0 {
+		parentHash = c.blocks[height-1].Hash
+	}
+
+	encodedTxs := make([][]byte, len(txs))
+	for i := range txs {
+		// encoding was checked when the transaction was added
+		encodedTxs[i], _ = verify.EncodeTransaction(&txs[i])
+	}
+
+	encodedWithdrawals := make([][]byte, len(withdrawals))
+	for i := range withdrawals {
+		encodedWithdrawals[i] = verify.EncodeWithdrawal(&withdrawals[i])
+	}
+
+	transactionsRoot := trie.ListRoot(encodedTxs)
+	receiptsRoot := trie.EmptyRoot
+	if len(txs) > 0 {
+		receiptsRoot = crypto.Keccak256([]byte("receipts"), transactionsRoot)
+	}
+
+	block := &types.Block{
+		ParentHash:       parentHash,
+		Sha3Uncles:       hashOf(emptyUnclesHash),
+		Miner:            c.miner,
+		StateRoot:        hashOf(s.root()),
+		TransactionsRoot: hashOf(transactionsRoot),
+		ReceiptsRoot:     hashOf(receiptsRoot),
+		LogsBloom:        types.Data("0x" + hex.EncodeToString(make([]byte, 256))),
+		Difficulty:       types.NewQuantityFromUint64(0),
+		Number:           types.NewQuantityFromUint64(height),
+		GasLimit:         types.NewQuantityFromUint64(DefaultGasLimit),
+		GasUsed:          types.NewQuantityFromUint64(uint64(len(txs)) * transferGas),
+		Timestamp:        types.NewQuantityFromUint64(uint64(c.config.GenesisTime.Unix()) + height*uint64(c.config.BlockTime.Seconds())),
+		ExtraData:        types.Data("0x" + hex.EncodeToString([]byte("testchain"))),
+		MixHash:          hashOf(randomBytes(rng, 32)),
+		Nonce:            types.Data("0x0000000000000000"),
+		BaseFeePerGas:    types.NewQuantityFromUint64(baseFeePerGas),
+		WithdrawalsRoot:  hashOf(trie.ListRoot(encodedWithdrawals)),
+		TotalDifficulty:  types.NewQuantityFromUint64(0),
+		Uncles:           []types.Hash{},
+		Withdrawals:      withdrawals,
+		Transactions:     make([]types.Transaction, len(txs)),
+	}
+
+	// header fields are always encodable
+	header, _ := verify.EncodeHeader(block)
+	block.Hash = hashOf(crypto.Keccak256(header))
+	block.Size = types.NewQuantityFromUint64(uint64(len(header) + len(rlp.EncodeList(encodedTxs...)) + len(rlp.EncodeList(encodedWithdrawals...))))
+
+	for i, tx := range txs {
+		tx.BlockHash = block.Hash
+		tx.BlockNumber = block.Number
+		tx.TransactionIndex = types.NewQuantityFromUint64(uint64(i))
+		block.Transactions[i] = tx
+	}
+
+	return block
+}
+
+// root returns a deterministic digest of the state, it's not a Merkle Patricia trie root
+func (s state) root() []byte {
+	keys := make([][]byte, 0, len(s))
+	values := make([][]byte, 0, len(s))
+	for address, acc := range s {
+		keys = append(keys, address.Bytes())
+		values = append(values, rlp.EncodeList(rlp.EncodeBig(new(big.Int).SetUint64(acc.Nonce)), rlp.EncodeBig(acc.Balance)))
+	}
+
+	return trie.Root(keys, values)
+}
+
+func (c *Chain) isAccount(address types.Address) bool {
+	for _, acc := range c.accounts {
+		if acc.Equal(address) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rng returns random source determined by seed, height, current fork and purpose
+func (c *Chain) rng(height uint64, purpose string, n uint64) *rand.Rand {
+	digest := crypto.Keccak256([]byte(purpose), binary.BigEndian.AppendUint64(nil, n), binary.BigEndian.AppendUint64(nil, c.fork))
+
+	return rand.New(rand.NewPCG(c.config.Seed^binary.BigEndian.Uint64(digest), height))
+}
+
+// deriveAddress returns deterministic address for the seed, kind and index
+func deriveAddress(seed uint64, kind string, index uint64) types.Address {
+	digest := crypto.Keccak256(
+		[]byte("testchain"),
+		binary.BigEndian.AppendUint64(nil, seed),
+		[]byte(kind),
+		binary.BigEndian.AppendUint64(nil, index),
+	)
+
+	return types.Address("0x" + hex.EncodeToString(digest[12:]))
+}
+
+func randomBytes(rng *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(rng.Uint32())
+	}
+
+	return b
+}
+
+func hashOf(b []byte) types.Hash {
+	return types.Hash("0x" + hex.EncodeToString(b))
+}
+
+// hashKey returns lowercase hash used as a key of transaction index
+func hashKey(h types.Hash) types.Hash {
+	return types.Hash(strings.ToLower(string(h)))
+}
@@ -0,0 +1,550 @@
+package testchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	// CodeLimitExceeded is the JSON-RPC error code of EIP-1474 for throttled requests
+	CodeLimitExceeded = -32005
+)
+
+// Faults makes the server slow or unreliable, requests fail randomly but deterministically by seed of the chain
+type Faults struct {
+	Latency       time.Duration // delay before every response
+	ErrorRate     float64       // ratio of requests answered with internal JSON-RPC error
+	RateLimitRate float64       // ratio of requests answered with 429 status
+	RetryAfter    time.Duration // Retry-After header of 429 responses, omitted if zero
+	Methods       []string      // methods affected by latency and errors, all methods if empty
+}
+
+// Failure is a response returned instead of the result of a request
+type Failure struct {
+	Status     int           // HTTP status, JSON-RPC error is returned with 200 status if zero
+	RetryAfter time.Duration // Retry-After header, omitted if zero
+	RpcCode    int           // code of JSON-RPC error (default: internal error)
+	Message    string        // message of JSON-RPC error
+}
+
+// RateLimited returns failure of 429 status with Retry-After header
+func RateLimited(retryAfter time.Duration) Failure {
+	return Failure{Status: http.StatusTooManyRequests, RetryAfter: retryAfter, Message: "too many requests"}
+}
+
+// InternalError returns failure of JSON-RPC internal error with 200 status
+func InternalError() Failure {
+	return Failure{RpcCode: jsonrpc.CodeInternalError, Message: "internal error"}
+}
+
+// Option is a functional option of Server
+type Option func(*Server)
+
+// WithFaults makes the server slow or unreliable from start
+func WithFaults(faults Faults) Option {
+	return func(s *Server) {
+		s.faults = faults
+	}
+}
+
+// WithMiningInterval mines a block every interval while the server is running
+func WithMiningInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.miningInterval = interval
+	}
+}
+
+// Server serves the chain by Ethereum JSON-RPC over HTTP
+// It can be started on a local port by Start, or used as http.Handler, e.g. by httptest.NewServer
+type Server struct {
+	chain *Chain
+
+	faults         Faults
+	failures       []Failure // returned by next requests before faults
+	miningInterval time.Duration
+
+	filters      map[string]map[types.Hash]struct{} // filter id -> hashes of pending transactions already returned
+	nextFilterId uint64
+	calls        map[string]int // method -> number of requests
+	rng          *rand.Rand
+
+	listener   net.Listener
+	httpServer *http.Server
+	stopCh     chan struct{}
+	wg         sync.WaitGroup
+
+	mutex sync.Mutex
+}
+
+// NewServer creates JSON-RPC server of the chain
+func NewServer(chain *Chain, opts ...Option) *Server {
+	s := &Server{
+		chain:   chain,
+		filters: make(map[string]map[types.Hash]struct{}),
+		calls:   make(map[string]int),
+		rng:     rand.New(rand.NewPCG(chain.config.Seed, 0)),
+		stopCh:  make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Chain returns the chain served by the server
+func (s *Server) Chain() *Chain {
+	return s.chain
+}
+
+// Start listens on addr, e.g. "127.0.0.1:0" for a random port, and serves requests in background
+func (s *Server) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s.listener = listener
+	s.httpServer = &http.Server{Handler: s}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("test chain server was terminated with error: %v", err)
+		}
+	}()
+
+	if s.miningInterval > 0 {
+		s.wg.Add(1)
+		go s.mineLoop()
+	}
+
+	return nil
+}
+
+// URL returns the URL of JSON-RPC endpoint, it's empty until Start is called
+func (s *Server) URL() string {
+	if s.listener == nil {
+		return ""
+	}
+
+	return "http://" + s.listener.Addr().String()
+}
+
+// Stop stops mining and shuts down the HTTP server
+func (s *Server) Stop(ctx context.Context) error {
+	close(s.stopCh)
+
+	var err error
+	if s.httpServer != nil {
+		err = s.httpServer.Shutdown(ctx)
+	}
+
+	s.wg.Wait()
+
+	return err
+}
+
+// Close stops the server immediately
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	return s.Stop(ctx)
+}
+
+// mineLoop mines a block every mining interval until the server stops
+func (s *Server) mineLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.miningInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.chain.Mine()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// SetFaults replaces faults of the server
+func (s *Server) SetFaults(faults Faults) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = faults
+}
+
+// InjectFailures makes next n requests fail with the failure
+func (s *Server) InjectFailures(n int, failure Failure) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, failure)
+	}
+}
+
+// Calls returns the number of requests of the method, including failed ones
+func (s *Server) Calls(method string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.calls[method]
+}
+
+// ServeHTTP handles a JSON-RPC request, batch requests are not supported
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &jsonrpc.JsonRpcRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeResponse(w, http.StatusOK, &jsonrpc.JsonRpcResponse{
+			Jsonrpc: jsonrpc.DefaultJsonRpcVersion,
+			Error:   &jsonrpc.JsonRpcError{Code: jsonrpc.CodeParseError, Message: "parse error"},
+		})
+		return
+	}
+
+	latency, failure := s.nextFailure(req.Method)
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if failure != nil {
+		writeFailure(w, req, failure)
+		return
+	}
+
+	res := &jsonrpc.JsonRpcResponse{Jsonrpc: jsonrpc.DefaultJsonRpcVersion, Id: req.Id}
+
+	result, rpcErr := s.handle(req)
+	if rpcErr != nil {
+		res.Error = rpcErr
+	} else {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			res.Error = &jsonrpc.JsonRpcError{Code: jsonrpc.CodeInternalError, Message: err.Error()}
+		} else {
+			res.Result = encoded
+		}
+	}
+
+	writeResponse(w, http.StatusOK, res)
+}
+
+// nextFailure counts the request and returns its latency and failure, failure is nil if the request succeeds
+func (s *Server) nextFailure(method string) (time.Duration, *Failure) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls[method]++
+
+	if len(s.failures) > 0 {
+		failure := s.failures[0]
+		s.failures = s.failures[1:]
+
+		return s.faults.Latency, &failure
+	}
+
+	if !s.faults.affects(method) {
+		return 0, nil
+	}
+
+	// draw always so that the sequence of failures doesn't depend on rates
+	draw := s.rng.Float64()
+	switch {
+	case draw < s.faults.RateLimitRate:
+		failure := RateLimited(s.faults.RetryAfter)
+		return s.faults.Latency, &failure
+	case draw < s.faults.RateLimitRate+s.faults.ErrorRate:
+		failure := InternalError()
+		return s.faults.Latency, &failure
+	}
+
+	return s.faults.Latency, nil
+}
+
+// affects returns true if faults are injected to the method
+func (f Faults) affects(method string) bool {
+	if len(f.Methods) == 0 {
+		return true
+	}
+
+	for _, m := range f.Methods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// handle returns result of the request
+func (s *Server) handle(req *jsonrpc.JsonRpcRequest) (interface{}, *jsonrpc.JsonRpcError) {
+	switch req.Method {
+	case jsonrpc.MethodEthChainId:
+		return types.NewQuantityFromUint64(s.chain.ChainId()), nil
+	case jsonrpc.MethodEthBlockNumber:
+		return types.NewQuantityFromUint64(s.chain.Height()), nil
+	case jsonrpc.MethodEthGetBlockByNumber:
+		return s.getBlockByNumber(req.Params)
+	case jsonrpc.MethodEthGetTransactionByHash:
+		return s.getTransactionByHash(req.Params)
+	case jsonrpc.MethodEthGetBalance:
+		return s.getAccountQuantity(req.Params, func(address types.Address, height *uint64) (*big.Int, error) {
+			return s.chain.Balance(address, height)
+		})
+	case jsonrpc.MethodEthGetTransactionCount:
+		return s.getAccountQuantity(req.Params, func(address types.Address, height *uint64) (*big.Int, error) {
+			nonce, err := s.chain.Nonce(address, height)
+			return new(big.Int).SetUint64(nonce), err
+		})
+	case jsonrpc.MethodEthNewPendingTransactionFilter:
+		return s.newPendingTransactionFilter(), nil
+	case jsonrpc.MethodEthGetFilterChanges:
+		return s.getFilterChanges(req.Params)
+	default:
+		return nil, &jsonrpc.JsonRpcError{
+			Code:    jsonrpc.CodeMethodNotFound,
+			Message: fmt.Sprintf("the method %s does not exist/is not available", req.Method),
+		}
+	}
+}
+
+func (s *Server) getBlockByNumber(params []interface{}) (interface{}, *jsonrpc.JsonRpcError) {
+	if len(params) != 2 {
+		return nil, invalidParams("expected block number and full transactions flag")
+	}
+
+	tag, _ := params[0].(string)
+	fullTxs, ok := params[1].(bool)
+	if !ok {
+		return nil, invalidParams("full transactions flag must be boolean")
+	}
+
+	height, pending, err := s.resolveBlock(tag)
+	if err != nil {
+		return nil, invalidParams(err.Error())
+	}
+
+	// pending block is not built in advance
+	if pending {
+		return nil, nil
+	}
+
+	block := s.chain.Block(height)
+	if block == nil {
+		return nil, nil
+	}
+
+	if fullTxs {
+		return block, nil
+	}
+
+	return blockWithTxHashes(block), nil
+}
+
+func (s *Server) getTransactionByHash(params []interface{}) (interface{}, *jsonrpc.JsonRpcError) {
+	if len(params) != 1 {
+		return nil, invalidParams("expected transaction hash")
+	}
+
+	raw, _ := params[0].(string)
+	hash, err := types.ParseHash(raw)
+	if err != nil {
+		return nil, invalidParams(err.Error())
+	}
+
+	if tx := s.chain.Transaction(hash); tx != nil {
+		return tx, nil
+	}
+
+	return nil, nil
+}
+
+func (s *Server) getAccountQuantity(
+	params []interface{},
+	get func(address types.Address, height *uint64) (*big.Int, error),
+) (interface{}, *jsonrpc.JsonRpcError) {
+	if len(params) != 2 {
+		return nil, invalidParams("expected address and block number")
+	}
+
+	raw, _ := params[0].(string)
+	address, err := types.ParseAddress(raw)
+	if err != nil {
+		return nil, invalidParams(err.Error())
+	}
+
+	tag, _ := params[1].(string)
+	height, pending, err := s.resolveBlock(tag)
+	if err != nil {
+		return nil, invalidParams(err.Error())
+	}
+
+	var at *uint64
+	if !pending {
+		at = &height
+	}
+
+	value, err := get(address, at)
+	if err != nil {
+		return nil, &jsonrpc.JsonRpcError{Code: jsonrpc.CodeServerError, Message: "header not found"}
+	}
+
+	return types.NewQuantity(value), nil
+}
+
+func (s *Server) newPendingTransactionFilter() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.nextFilterId++
+	id := "0x" + strconv.FormatUint(s.nextFilterId, 16)
+	s.filters[id] = make(map[types.Hash]struct{})
+
+	return id
+}
+
+func (s *Server) getFilterChanges(params []interface{}) (interface{}, *jsonrpc.JsonRpcError) {
+	if len(params) != 1 {
+		return nil, invalidParams("expected filter id")
+	}
+
+	id, _ := params[0].(string)
+
+	pending := s.chain.Pending()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	seen, ok := s.filters[id]
+	if !ok {
+		return nil, &jsonrpc.JsonRpcError{Code: jsonrpc.CodeInvalidParams, Message: "filter not found"}
+	}
+
+	hashes := make([]types.Hash, 0)
+	for _, tx := range pending {
+		key := hashKey(tx.Hash)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		hashes = append(hashes, tx.Hash)
+	}
+
+	return hashes, nil
+}
+
+// resolveBlock returns height of block parameter, pending is true for pending tag
+func (s *Server) resolveBlock(tag string) (height uint64, pending bool, err error) {
+	switch tag {
+	case "latest", "safe", "finalized":
+		return s.chain.Height(), false, nil
+	case "earliest":
+		return 0, false, nil
+	case "pending":
+		return 0, true, nil
+	}
+
+	quantity, err := types.ParseQuantity(tag)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid block number %q: %w", tag, err)
+	}
+
+	value := quantity.Big()
+	if !value.IsUint64() {
+		return 0, false, fmt.Errorf("block number is too large: %s", tag)
+	}
+
+	return value.Uint64(), false, nil
+}
+
+// blockWithTxHashes returns block whose transactions are replaced by hashes
+func blockWithTxHashes(block *types.Block) interface{} {
+	hashes := make([]types.Hash, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		hashes[i] = tx.Hash
+	}
+
+	return struct {
+		*types.Block
+		Transactions []types.Hash `json:"transactions"`
+	}{block, hashes}
+}
+
+func invalidParams(message string) *jsonrpc.JsonRpcError {
+	return &jsonrpc.JsonRpcError{Code: jsonrpc.CodeInvalidParams, Message: message}
+}
+
+// writeFailure writes response of injected failure
+func writeFailure(w http.ResponseWriter, req *jsonrpc.JsonRpcRequest, failure *Failure) {
+	if failure.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(failure.RetryAfter.Seconds()+0.999)))
+	}
+
+	code := failure.RpcCode
+	if code == 0 {
+		code = jsonrpc.CodeInternalError
+		if failure.Status == http.StatusTooManyRequests {
+			code = CodeLimitExceeded
+		}
+	}
+
+	status := failure.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if status != http.StatusOK {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		fmt.Fprintln(w, strings.TrimSpace(failure.Message))
+		return
+	}
+
+	writeResponse(w, status, &jsonrpc.JsonRpcResponse{
+		Jsonrpc: jsonrpc.DefaultJsonRpcVersion,
+		Id:      req.Id,
+		Error:   &jsonrpc.JsonRpcError{Code: code, Message: failure.Message},
+	})
+}
+
+func writeResponse(w http.ResponseWriter, status int, res *jsonrpc.JsonRpcResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Printf("failed to write JSON-RPC response: %v", err)
+	}
+}
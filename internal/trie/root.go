@@ -0,0 +1,155 @@
+package trie
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/crypto"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/rlp"
+)
+
+// branchWidth is the number of children in branch node
+const branchWidth = 16
+
+// EmptyRoot is the root hash of the trie without entries, keccak256(rlp(""))
+var EmptyRoot = crypto.Keccak256(rlp.EncodeBytes(nil))
+
+// entry is a key value pair in the trie, key is represented in nibbles
+type entry struct {
+	key   []byte
+	value []byte
+}
+
+// Root computes the root hash of Merkle-Patricia trie which contains given key value pairs
+// It builds the trie at once in memory, so it's suitable for small tries such as transactions trie
+func Root(keys, values [][]byte) []byte {
+	if len(keys) == 0 {
+		return EmptyRoot
+	}
+
+	entries := make([]entry, len(keys))
+	for i := range keys {
+		entries[i] = entry{key: toNibbles(keys[i]), value: values[i]}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	// root is always hashed even if the encoded node is shorter than 32 bytes
+	return crypto.Keccak256(encodeNode(entries, 0))
+}
+
+// ListRoot computes the root hash of trie keyed by RLP encoded index, which is used for transactions and withdrawals
+func ListRoot(values [][]byte) []byte {
+	keys := make([][]byte, len(values))
+	for i := range values {
+		keys[i] = rlp.EncodeUint64(uint64(i))
+	}
+
+	return Root(keys, values)
+}
+
+// encodeNode encodes the node which contains given sorted entries whose keys share first depth nibbles
+func encodeNode(entries []entry, depth int) []byte {
+	// leaf
+	if len(entries) == 1 {
+		return rlp.EncodeList(
+			rlp.EncodeBytes(compactEncode(entries[0].key[depth:], true)),
+			rlp.EncodeBytes(entries[0].value),
+		)
+	}
+
+	// extension
+	prefixLength := commonPrefixLength(entries, depth)
+	if prefixLength > 0 {
+		return rlp.EncodeList(
+			rlp.EncodeBytes(compactEncode(entries[0].key[depth:depth+prefixLength], false)),
+			reference(encodeNode(entries, depth+prefixLength)),
+		)
+	}
+
+	// branch
+	items := make([][]byte, 0, branchWidth+1)
+	value := rlp.EncodeBytes(nil)
+
+	rest := entries
+	if len(rest[0].key) == depth {
+		// entry terminating at this node is stored in value slot, it comes first as the shortest key
+		value = rlp.EncodeBytes(rest[0].value)
+		rest = rest[1:]
+	}
+
+	for nibble := byte(0); nibble < branchWidth; nibble++ {
+		end := 0
+		for end < len(rest) && rest[end].key[depth] == nibble {
+			end++
+		}
+
+		if end == 0 {
+			items = append(items, rlp.EncodeBytes(nil))
+			continue
+		}
+
+		items = append(items, reference(encodeNode(rest[:end], depth+1)))
+		rest = rest[end:]
+	}
+
+	return rlp.EncodeList(append(items, value)...)
+}
+
+// reference returns how child node is referred from parent
+// Node shorter than 32 bytes is embedded, otherwise referred by its hash
+func reference(encoded []byte) []byte {
+	if len(encoded) < 32 {
+		return encoded
+	}
+
+	return rlp.EncodeBytes(crypto.Keccak256(encoded))
+}
+
+// commonPrefixLength returns the length of common prefix of keys after depth
+func commonPrefixLength(entries []entry, depth int) int {
+	first, last := entries[0].key[depth:], entries[len(entries)-1].key[depth:]
+
+	// entries are sorted, so common prefix of first and last is shared by all
+	length := 0
+	for length < len(first) && length < len(last) && first[length] == last[length] {
+		length++
+	}
+
+	return length
+}
+
+// toNibbles splits each byte into two 4 bits values
+func toNibbles(key []byte) []byte {
+	nibbles := make([]byte, len(key)*2)
+	for i, b := range key {
+		nibbles[i*2] = b >> 4
+		nibbles[i*2+1] = b & 0x0f
+	}
+
+	return nibbles
+}
+
+// compactEncode encodes nibbles with hex prefix, which has flags of leaf and odd length
+func compactEncode(nibbles []byte, isLeaf bool) []byte {
+	flag := byte(0)
+	if isLeaf {
+		flag = 2
+	}
+
+	var packed []byte
+	if len(nibbles)%2 == 1 {
+		packed = append(packed, (flag+1)<<4|nibbles[0])
+		nibbles = nibbles[1:]
+	} else {
+		packed = append(packed, flag<<4)
+	}
+
+	for i := 0; i < len(nibbles); i += 2 {
+		packed = append(packed, nibbles[i]<<4|nibbles[i+1])
+	}
+
+	return packed
+}
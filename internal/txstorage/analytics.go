@@ -0,0 +1,296 @@
+package txstorage
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// addressAnalytics is aggregated values of transactions associated with an address for each block
+type addressAnalytics struct {
+	heights []uint64 // Block numbers of blocks in ascending order
+	blocks  map[uint64]*blockAnalytics
+}
+
+// blockAnalytics is aggregated values of transactions associated with an address in a block
+type blockAnalytics struct {
+	txCount        int
+	inboundCount   int
+	outboundCount  int
+	totalIn        *big.Int
+	totalOut       *big.Int
+	maxFeesPaid    *big.Int
+	counterparties map[types.Address]*counterpartyAnalytics // Address in lower case -> volume
+}
+
+// counterpartyAnalytics is the value transferred between an address and its counterparty
+type counterpartyAnalytics struct {
+	txCount  int
+	sent     *big.Int
+	received *big.Int
+}
+
+// GetAddressAnalytics aggregates transactions associated with given address in blocks from..to inclusive
+// Counterparties are limited to maxCounterparties, all of them are returned if it's not positive
+func (s *InMemoryTransactionStorage) GetAddressAnalytics(
+	target types.Address,
+	from, to uint64,
+	maxCounterparties int,
+) *types.AddressAnalytics {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	total := newBlockAnalytics()
+
+	if analytics, ok := s.analytics[target.Lower()]; ok && from <= to {
+		start := sort.Search(len(analytics.heights), func(i int) bool {
+			return analytics.heights[i] >= from
+		})
+
+		for _, height := range analytics.heights[start:] {
+			if height > to {
+				break
+			}
+
+			total.merge(analytics.blocks[height])
+		}
+	}
+
+	return total.result(maxCounterparties)
+}
+
+// recordAnalytics adds the transaction to aggregates of its matched addresses
+// The fee is computed by base fee of the block header stored already, and kept to be subtracted on deletion
+func (s *InMemoryTransactionStorage) recordAnalytics(tx *types.IndexedTransaction) {
+	if tx.BlockNumber.IsEmpty() {
+		return
+	}
+
+	var baseFeePerGas types.Quantity
+	if header, ok := s.blockHeaders[tx.BlockNumber.Uint64()]; ok {
+		baseFeePerGas = header.BaseFeePerGas
+	}
+
+	fee := types.MaxFee(&tx.Transaction, baseFeePerGas)
+	s.txMaxFees[tx.Hash] = fee
+
+	s.applyAnalytics(&tx.Transaction, fee, 1, tx.MatchedAddresses)
+}
+
+// unrecordAnalytics subtracts the transaction from aggregates of its matched addresses
+func (s *InMemoryTransactionStorage) unrecordAnalytics(tx *types.IndexedTransaction) {
+	if tx.BlockNumber.IsEmpty() {
+		return
+	}
+
+	fee := s.txMaxFees[tx.Hash]
+	delete(s.txMaxFees, tx.Hash)
+
+	s.applyAnalytics(&tx.Transaction, fee, -1, tx.MatchedAddresses)
+}
+
+// applyAnalytics adds the transaction to aggregates of given addresses if sign is 1, or subtracts it if sign is -1
+// Only sender or recipient among the addresses is aggregated, so that addresses never subscribed have no aggregates
+// A transaction sent to the sender itself is counted once, and has no counterparty
+func (s *InMemoryTransactionStorage) applyAnalytics(tx *types.Transaction, fee *big.Int, sign int, addresses []types.Address) {
+	if tx.BlockNumber.IsEmpty() {
+		return
+	}
+
+	height := tx.BlockNumber.Uint64()
+	from, to := tx.From.Lower(), tx.To.Lower()
+
+	isSender, isRecipient := false, false
+	for _, address := range addresses {
+		isSender = isSender || (!from.IsEmpty() && address.Lower() == from)
+		isRecipient = isRecipient || (!to.IsEmpty() && address.Lower() == to)
+	}
+
+	value := tx.Value.Big()
+	if value == nil {
+		value = new(big.Int)
+	}
+
+	if isSender {
+		sender := s.blockAnalyticsOf(from, height)
+		sender.txCount += sign
+		sender.outboundCount += sign
+		addSigned(sender.totalOut, value, sign)
+
+		if fee != nil {
+			addSigned(sender.maxFeesPaid, fee, sign)
+		}
+
+		if !to.IsEmpty() && to != from {
+			counterparty := sender.counterparty(to)
+			counterparty.txCount += sign
+			addSigned(counterparty.sent, value, sign)
+		}
+	}
+
+	if isRecipient {
+		recipient := s.blockAnalyticsOf(to, height)
+		if to != from {
+			recipient.txCount += sign
+		}
+
+		recipient.inboundCount += sign
+		addSigned(recipient.totalIn, value, sign)
+
+		if !from.IsEmpty() && to != from {
+			counterparty := recipient.counterparty(from)
+			counterparty.txCount += sign
+			addSigned(counterparty.received, value, sign)
+		}
+	}
+
+	// pruned after both are updated because sender and recipient share the block for self transfer
+	s.pruneBlockAnalytics(from, height)
+	s.pruneBlockAnalytics(to, height)
+}
+
+// blockAnalyticsOf returns aggregates of the address in the block, it's created if not exist
+func (s *InMemoryTransactionStorage) blockAnalyticsOf(address types.Address, height uint64) *blockAnalytics {
+	analytics, ok := s.analytics[address]
+	if !ok {
+		analytics = &addressAnalytics{blocks: make(map[uint64]*blockAnalytics)}
+		s.analytics[address] = analytics
+	}
+
+	block, ok := analytics.blocks[height]
+	if !ok {
+		// keep heights sorted, transactions are inserted in block order in most cases
+		idx := sort.Search(len(analytics.heights), func(i int) bool {
+			return analytics.heights[i] >= height
+		})
+
+		analytics.heights = append(analytics.heights, 0)
+		copy(analytics.heights[idx+1:], analytics.heights[idx:])
+		analytics.heights[idx] = height
+
+		block = newBlockAnalytics()
+		analytics.blocks[height] = block
+	}
+
+	return block
+}
+
+// pruneBlockAnalytics deletes aggregates of the address in the block and counterparties which have no transaction
+func (s *InMemoryTransactionStorage) pruneBlockAnalytics(address types.Address, height uint64) {
+	analytics, ok := s.analytics[address]
+	if !ok {
+		return
+	}
+
+	block, ok := analytics.blocks[height]
+	if !ok {
+		return
+	}
+
+	for counterparty, volume := range block.counterparties {
+		if volume.txCount <= 0 {
+			delete(block.counterparties, counterparty)
+		}
+	}
+
+	if block.txCount > 0 {
+		return
+	}
+
+	delete(analytics.blocks, height)
+
+	idx := sort.Search(len(analytics.heights), func(i int) bool {
+		return analytics.heights[i] >= height
+	})
+	analytics.heights = append(analytics.heights[:idx], analytics.heights[idx+1:]...)
+
+	if len(analytics.heights) == 0 {
+		delete(s.analytics, address)
+	}
+}
+
+func newBlockAnalytics() *blockAnalytics {
+	return &blockAnalytics{
+		totalIn:        new(big.Int),
+		totalOut:       new(big.Int),
+		maxFeesPaid:    new(big.Int),
+		counterparties: make(map[types.Address]*counterpartyAnalytics),
+	}
+}
+
+// counterparty returns the volume with the counterparty, it's created if not exist
+func (b *blockAnalytics) counterparty(address types.Address) *counterpartyAnalytics {
+	volume, ok := b.counterparties[address]
+	if !ok {
+		volume = &counterpartyAnalytics{sent: new(big.Int), received: new(big.Int)}
+		b.counterparties[address] = volume
+	}
+
+	return volume
+}
+
+// merge adds aggregates of other block
+func (b *blockAnalytics) merge(other *blockAnalytics) {
+	b.txCount += other.txCount
+	b.inboundCount += other.inboundCount
+	b.outboundCount += other.outboundCount
+	b.totalIn.Add(b.totalIn, other.totalIn)
+	b.totalOut.Add(b.totalOut, other.totalOut)
+	b.maxFeesPaid.Add(b.maxFeesPaid, other.maxFeesPaid)
+
+	for address, volume := range other.counterparties {
+		merged := b.counterparty(address)
+		merged.txCount += volume.txCount
+		merged.sent.Add(merged.sent, volume.sent)
+		merged.received.Add(merged.received, volume.received)
+	}
+}
+
+// result returns aggregates with counterparties ranked by volume, ties are ordered by address
+func (b *blockAnalytics) result(maxCounterparties int) *types.AddressAnalytics {
+	counterparties := make([]types.CounterpartyVolume, 0, len(b.counterparties))
+	volumes := make(map[types.Address]*big.Int, len(b.counterparties))
+
+	for address, volume := range b.counterparties {
+		volumes[address] = new(big.Int).Add(volume.sent, volume.received)
+		counterparties = append(counterparties, types.CounterpartyVolume{
+			Address:  address,
+			TxCount:  volume.txCount,
+			Sent:     types.NewQuantity(volume.sent),
+			Received: types.NewQuantity(volume.received),
+			Volume:   types.NewQuantity(volumes[address]),
+		})
+	}
+
+	sort.Slice(counterparties, func(i, j int) bool {
+		if cmp := volumes[counterparties[i].Address].Cmp(volumes[counterparties[j].Address]); cmp != 0 {
+			return cmp > 0
+		}
+
+		return counterparties[i].Address < counterparties[j].Address
+	})
+
+	if maxCounterparties > 0 && len(counterparties) > maxCounterparties {
+		counterparties = counterparties[:maxCounterparties]
+	}
+
+	return &types.AddressAnalytics{
+		TxCount:        b.txCount,
+		InboundCount:   b.inboundCount,
+		OutboundCount:  b.outboundCount,
+		TotalIn:        types.NewQuantity(b.totalIn),
+		TotalOut:       types.NewQuantity(b.totalOut),
+		MaxFeesPaid:    types.NewQuantity(b.maxFeesPaid),
+		Counterparties: counterparties,
+	}
+}
+
+// addSigned adds value to total if sign is positive, otherwise subtracts it
+func addSigned(total, value *big.Int, sign int) {
+	if sign > 0 {
+		total.Add(total, value)
+	} else {
+		total.Sub(total, value)
+	}
+}
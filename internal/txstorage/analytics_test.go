@@ -0,0 +1,67 @@
+package txstorage
+
+import (
+	"testing"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+func TestAnalyticsOnlyOfMatchedAddresses(t *testing.T) {
+	s := New()
+
+	tx := pendingTx("0x01", alice, 1)
+	tx.BlockNumber = "0x1"
+	tx.Value = "0x64"
+
+	matched := func(addresses ...types.Address) []*types.IndexedTransaction {
+		return []*types.IndexedTransaction{{Transaction: *tx, MatchedAddresses: addresses}}
+	}
+
+	if err := s.InsertTransactions(matched(alice.Lower())); err != nil {
+		t.Fatal(err)
+	}
+
+	if sender := s.GetAddressAnalytics(alice, 0, 1, 0); sender.OutboundCount != 1 || len(sender.Counterparties) != 1 {
+		t.Fatalf("expected outbound transaction of alice to bob, but got %+v", sender)
+	}
+
+	// the recipient isn't subscribed
+	if recipient := s.GetAddressAnalytics(bob, 0, 1, 0); recipient.TxCount != 0 || len(s.analytics) != 1 {
+		t.Fatalf("expected no aggregates of bob, but got %+v", recipient)
+	}
+
+	// the recipient is aggregated once it's matched by reindexing
+	if err := s.InsertTransactions(matched(bob)); err != nil {
+		t.Fatal(err)
+	}
+
+	if recipient := s.GetAddressAnalytics(bob, 0, 1, 0); recipient.InboundCount != 1 || recipient.TotalIn.Uint64() != 100 {
+		t.Fatalf("expected inbound transaction of bob, but got %+v", recipient)
+	}
+
+	if sender := s.GetAddressAnalytics(alice, 0, 1, 0); sender.TxCount != 1 {
+		t.Fatalf("expected alice not to be counted twice, but got %+v", sender)
+	}
+
+	// unmatching the recipient keeps aggregates of the sender
+	address := bob
+	if _, err := s.DeleteBlockRange(1, 1, &address); err != nil {
+		t.Fatal(err)
+	}
+
+	if recipient := s.GetAddressAnalytics(bob, 0, 1, 0); recipient.TxCount != 0 {
+		t.Fatalf("expected no aggregates of unmatched bob, but got %+v", recipient)
+	}
+
+	if sender := s.GetAddressAnalytics(alice, 0, 1, 0); sender.TxCount != 1 || sender.TotalOut.Uint64() != 100 {
+		t.Fatalf("expected aggregates of alice to be kept, but got %+v", sender)
+	}
+
+	if _, err := s.DeleteBlockRange(1, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if sender := s.GetAddressAnalytics(alice, 0, 1, 0); sender.TxCount != 0 || len(s.txMaxFees) != 0 {
+		t.Fatalf("expected no aggregates after deletion, but got %+v", sender)
+	}
+}
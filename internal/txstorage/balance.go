@@ -0,0 +1,95 @@
+package txstorage
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// InsertAccountState stores balance and nonce of an account at a block
+// The state at the same block is overwritten
+func (s *InMemoryTransactionStorage) InsertAccountState(state *types.AccountState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	key := state.Address.Lower()
+	height := state.BlockNumber.Uint64()
+	history := s.accountStates[key]
+
+	// keep history sorted by block number, states are inserted in order in most cases
+	idx := sort.Search(len(history), func(i int) bool {
+		return history[i].BlockNumber.Uint64() >= height
+	})
+
+	if idx < len(history) && history[idx].BlockNumber.Uint64() == height {
+		history[idx] = *state
+		return nil
+	}
+
+	history = append(history, types.AccountState{})
+	copy(history[idx+1:], history[idx:])
+	history[idx] = *state
+
+	s.accountStates[key] = history
+
+	return nil
+}
+
+// DeleteAccountStates deletes states of all accounts at blocks from..to inclusive
+// States aren't fetched again by reindex, so this is called only when the chain is rewound
+func (s *InMemoryTransactionStorage) DeleteAccountStates(from, to uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	for key, history := range s.accountStates {
+		history = slices.DeleteFunc(history, func(state types.AccountState) bool {
+			height := state.BlockNumber.Uint64()
+			return height >= from && height <= to
+		})
+
+		if len(history) == 0 {
+			delete(s.accountStates, key)
+		} else {
+			s.accountStates[key] = history
+		}
+	}
+
+	return nil
+}
+
+// GetAccountState returns the latest state of an account at or before given height
+// It returns the latest known state if height is nil
+func (s *InMemoryTransactionStorage) GetAccountState(address types.Address, height *uint64) (*types.AccountState, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	history := s.accountStates[address.Lower()]
+	if len(history) == 0 {
+		return nil, false
+	}
+
+	idx := len(history) - 1
+	if height != nil {
+		// index of the first state after height
+		idx = sort.Search(len(history), func(i int) bool {
+			return history[i].BlockNumber.Uint64() > *height
+		}) - 1
+	}
+
+	if idx < 0 {
+		return nil, false
+	}
+
+	state := history[idx]
+
+	return &state, true
+}
@@ -0,0 +1,77 @@
+package txstorage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+func accountState(address types.Address, height, balance uint64) *types.AccountState {
+	return &types.AccountState{
+		Address:     address,
+		BlockNumber: types.NewQuantityFromUint64(height),
+		Balance:     types.NewQuantityFromUint64(balance),
+		Nonce:       types.NewQuantityFromUint64(height),
+	}
+}
+
+func TestGetAccountStateAtHeight(t *testing.T) {
+	s := New()
+
+	// states are inserted out of order and the one at 20 is overwritten
+	for _, state := range []*types.AccountState{
+		accountState(alice, 20, 1),
+		accountState(alice, 10, 100),
+		accountState(alice, 30, 300),
+		accountState(alice.Lower(), 20, 200),
+		accountState(bob, 15, 999),
+	} {
+		if err := s.InsertAccountState(state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	height := func(h uint64) *uint64 { return &h }
+
+	for _, tt := range []struct {
+		height  *uint64
+		found   bool
+		block   uint64
+		balance string
+	}{
+		{nil, true, 30, "300"},
+		{height(5), false, 0, ""},
+		{height(10), true, 10, "100"},
+		{height(15), true, 10, "100"},
+		{height(20), true, 20, "200"},
+		{height(29), true, 20, "200"},
+		{height(1000), true, 30, "300"},
+	} {
+		state, ok := s.GetAccountState(alice, tt.height)
+		if ok != tt.found {
+			t.Fatalf("expected found=%t at %v, but got %+v", tt.found, tt.height, state)
+		}
+
+		if ok && (state.BlockNumber.Uint64() != tt.block || state.Balance.Decimal() != tt.balance) {
+			t.Fatalf("expected balance %s at block %d for height %v, but got %+v", tt.balance, tt.block, tt.height, state)
+		}
+	}
+
+	if _, ok := s.GetAccountState(types.Address("0x"+strings.Repeat("cc", 20)), nil); ok {
+		t.Fatal("expected no state of unknown address")
+	}
+
+	// deleted range falls back to the state before it
+	if err := s.DeleteAccountStates(15, 25); err != nil {
+		t.Fatal(err)
+	}
+
+	if state, ok := s.GetAccountState(alice, height(29)); !ok || state.BlockNumber.Uint64() != 10 {
+		t.Fatalf("expected state at block 10 after deleting 15..25, but got %+v", state)
+	}
+
+	if state, ok := s.GetAccountState(bob, nil); ok {
+		t.Fatalf("expected all states of bob to be deleted, but got %+v", state)
+	}
+}
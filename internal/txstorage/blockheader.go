@@ -0,0 +1,146 @@
+package txstorage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// InsertBlockHeader stores the summary of block having indexed transactions
+// The header at the same height is overwritten, e.g. by reindexing
+func (s *InMemoryTransactionStorage) InsertBlockHeader(header *types.BlockHeader) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	height := header.Number.Uint64()
+	copied := *header
+
+	if _, ok := s.blockHeaders[height]; !ok {
+		// keep heights sorted, headers are inserted in order in most cases
+		idx := sort.Search(len(s.headerHeights), func(i int) bool {
+			return s.headerHeights[i] >= height
+		})
+
+		s.headerHeights = append(s.headerHeights, 0)
+		copy(s.headerHeights[idx+1:], s.headerHeights[idx:])
+		s.headerHeights[idx] = height
+	}
+
+	s.blockHeaders[height] = &copied
+
+	return nil
+}
+
+// GetBlockHeader returns the summary of block at given height
+func (s *InMemoryTransactionStorage) GetBlockHeader(height uint64) (*types.BlockHeader, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	header, ok := s.blockHeaders[height]
+	if !ok {
+		return nil, false
+	}
+
+	copied := *header
+
+	return &copied, true
+}
+
+// GetBlockHeadersByTimeRange returns headers of blocks whose timestamp is in [from, to) in block order
+// Zero from or to means unbounded. Timestamps are assumed not to decrease as height increases
+func (s *InMemoryTransactionStorage) GetBlockHeadersByTimeRange(from, to time.Time) []types.BlockHeader {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	start, end := s.headerIndexRange(from, to)
+	if start >= end {
+		return nil
+	}
+
+	headers := make([]types.BlockHeader, 0, end-start)
+	for _, height := range s.headerHeights[start:end] {
+		headers = append(headers, *s.blockHeaders[height])
+	}
+
+	return headers
+}
+
+// GetTransactionsByTimeRange returns transactions associated with given address in blocks whose timestamp is in [from, to)
+// Zero from or to means unbounded. Transactions are in inserted order
+func (s *InMemoryTransactionStorage) GetTransactionsByTimeRange(target types.Address, from, to time.Time) []types.Transaction {
+	key := target.Lower()
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	txs := make([]types.Transaction, 0)
+	for _, hash := range s.txHashesByAddress[key] {
+		tx := s.txMap[hash]
+
+		header, ok := s.blockHeaders[tx.BlockNumber.Uint64()]
+		if !ok || !inTimeRange(header.Time(), from, to) {
+			continue
+		}
+
+		txs = append(txs, tx.Transaction)
+	}
+
+	return txs
+}
+
+// GetBlockRangeByTime returns the first and last blocks whose timestamp is in [since, until) among blocks having
+// indexed transactions, ok is false if no block is in the range. Zero since or until means unbounded
+func (s *InMemoryTransactionStorage) GetBlockRangeByTime(since, until time.Time) (from uint64, to uint64, ok bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	start, end := s.headerIndexRange(since, until)
+	if start >= end {
+		return 0, 0, false
+	}
+
+	return s.headerHeights[start], s.headerHeights[end-1], true
+}
+
+// headerIndexRange returns the range of indexes in headerHeights whose timestamp is in [from, to)
+func (s *InMemoryTransactionStorage) headerIndexRange(from, to time.Time) (int, int) {
+	start, end := 0, len(s.headerHeights)
+
+	if !from.IsZero() {
+		start = sort.Search(len(s.headerHeights), func(i int) bool {
+			return !s.blockHeaders[s.headerHeights[i]].Time().Before(from)
+		})
+	}
+
+	if !to.IsZero() {
+		end = sort.Search(len(s.headerHeights), func(i int) bool {
+			return !s.blockHeaders[s.headerHeights[i]].Time().Before(to)
+		})
+	}
+
+	return start, end
+}
+
+// deleteBlockHeader deletes the header at given height
+func (s *InMemoryTransactionStorage) deleteBlockHeader(height uint64) {
+	if _, ok := s.blockHeaders[height]; !ok {
+		return
+	}
+
+	delete(s.blockHeaders, height)
+
+	idx := sort.Search(len(s.headerHeights), func(i int) bool {
+		return s.headerHeights[i] >= height
+	})
+	s.headerHeights = append(s.headerHeights[:idx], s.headerHeights[idx+1:]...)
+}
+
+// inTimeRange returns true if t is in [from, to), zero from or to means unbounded
+func inTimeRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
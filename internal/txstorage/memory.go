@@ -1,67 +1,212 @@
 package txstorage
 
 import (
-	"strings"
+	"errors"
+	"math/big"
+	"slices"
 	"sync"
 
 	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
 )
 
 type InMemoryTransactionStorage struct {
-	txMap             map[string]*types.Transaction
-	txHashesByAddress map[string][]string // Address -> []TransactionHash
+	txMap             map[types.Hash]*types.IndexedTransaction
+	txHashesByAddress map[types.Address][]types.Hash // Address in lower case -> []TransactionHash
+	txHashesByBlock   map[uint64][]types.Hash        // Block number -> []TransactionHash in block order
+	pendingTxMap      map[types.Hash]*types.PendingTransaction
+	pendingTxsByNonce map[senderNonce][]types.Hash           // Sender and nonce -> hashes of pending transactions
+	accountStates     map[types.Address][]types.AccountState // Address in lower case -> states sorted by block number
+	blockHeaders      map[uint64]*types.BlockHeader          // Block number -> header, only for blocks having indexed transactions
+	headerHeights     []uint64                               // Block numbers of blockHeaders in ascending order
+	analytics         map[types.Address]*addressAnalytics    // Address in lower case -> aggregates per block
+	txMaxFees         map[types.Hash]*big.Int                // Maximum fee counted in analytics for each transaction
+	closed            bool                                   // writes fail after Close, reads are still served
 
 	mutex sync.RWMutex
 }
 
+// ErrClosed is returned by writes after the storage is closed
+var ErrClosed = errors.New("storage is closed")
+
 func New() *InMemoryTransactionStorage {
 	return &InMemoryTransactionStorage{
-		txMap:             make(map[string]*types.Transaction),
-		txHashesByAddress: make(map[string][]string),
+		txMap:             make(map[types.Hash]*types.IndexedTransaction),
+		txHashesByAddress: make(map[types.Address][]types.Hash),
+		txHashesByBlock:   make(map[uint64][]types.Hash),
+		pendingTxMap:      make(map[types.Hash]*types.PendingTransaction),
+		pendingTxsByNonce: make(map[senderNonce][]types.Hash),
+		accountStates:     make(map[types.Address][]types.AccountState),
+		blockHeaders:      make(map[uint64]*types.BlockHeader),
+		analytics:         make(map[types.Address]*addressAnalytics),
+		txMaxFees:         make(map[types.Hash]*big.Int),
 	}
 }
 
-// InsertTransactions stores given transactions and associate from and to account with its transaction
-func (s *InMemoryTransactionStorage) InsertTransactions(txs []*types.Transaction) error {
+// Close rejects further writes, data in memory is kept for reads until the process exits
+func (s *InMemoryTransactionStorage) Close() error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	s.closed = true
+
+	return nil
+}
+
+// InsertTransactions stores given transactions and associate from and to account and block with its transaction
+// Pending transactions included in the block are promoted, and the ones sharing nonce are marked as replaced
+// Matched addresses are merged if the transaction is stored already, e.g. by reindexing
+// Analytics of sender and recipient are updated, so block header should be inserted before to compute fees
+func (s *InMemoryTransactionStorage) InsertTransactions(txs []*types.IndexedTransaction) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
 	for _, tx := range txs {
+		if stored, ok := s.txMap[tx.Hash]; ok {
+			s.mergeMatchedAddresses(stored, tx.MatchedAddresses)
+			continue
+		}
+
 		s.txMap[tx.Hash] = tx
 		s.appendsTxHashForAddress(tx.From, tx.Hash)
-		s.appendsTxHashForAddress(tx.To, tx.Hash)
+
+		// self transfer is indexed once
+		if tx.To.Lower() != tx.From.Lower() {
+			s.appendsTxHashForAddress(tx.To, tx.Hash)
+		}
+
+		if !tx.BlockNumber.IsEmpty() {
+			height := tx.BlockNumber.Uint64()
+			s.txHashesByBlock[height] = append(s.txHashesByBlock[height], tx.Hash)
+		}
+
+		s.resolvePendingTransaction(&tx.Transaction)
+		s.recordAnalytics(tx)
 	}
 
 	return nil
 }
 
+// GetTransactionByHash returns the transaction of given hash
+func (s *InMemoryTransactionStorage) GetTransactionByHash(hash types.Hash) (*types.IndexedTransaction, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	tx, ok := s.txMap[hash]
+	if !ok {
+		return nil, false
+	}
+
+	copied := *tx
+
+	return &copied, true
+}
+
+// GetTransactionsByBlock returns transactions stored for the block of given height in block order
+func (s *InMemoryTransactionStorage) GetTransactionsByBlock(height uint64) []types.IndexedTransaction {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	txHashes := s.txHashesByBlock[height]
+	if len(txHashes) == 0 {
+		return nil
+	}
+
+	txs := make([]types.IndexedTransaction, len(txHashes))
+	for idx, hash := range txHashes {
+		txs[idx] = *s.txMap[hash]
+	}
+
+	return txs
+}
+
 // GetTransactionsByAddress returns list of transactions associated with given address
-func (s *InMemoryTransactionStorage) GetTransactionsByAddress(target string) []types.Transaction {
-	target = strings.ToLower(target)
+func (s *InMemoryTransactionStorage) GetTransactionsByAddress(target types.Address) []types.Transaction {
+	key := target.Lower()
 
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	blockHashes := s.txHashesByAddress[target]
+	blockHashes := s.txHashesByAddress[key]
 	if len(blockHashes) == 0 {
 		return nil
 	}
 
 	txs := make([]types.Transaction, len(blockHashes))
 	for idx, hash := range blockHashes {
-		txs[idx] = *s.txMap[hash]
+		txs[idx] = s.txMap[hash].Transaction
 	}
 
 	return txs
 }
 
+// IterateTransactionsByAddress calls fn for each transaction associated with given address in inserted order
+// It holds only a snapshot of transaction hashes so that callers can stream large histories
+func (s *InMemoryTransactionStorage) IterateTransactionsByAddress(
+	target types.Address,
+	fn func(*types.Transaction) error,
+) error {
+	key := target.Lower()
+
+	s.mutex.RLock()
+	txHashes := s.txHashesByAddress[key]
+	s.mutex.RUnlock()
+
+	for _, hash := range txHashes {
+		s.mutex.RLock()
+		stored, ok := s.txMap[hash]
+		var tx types.Transaction
+		if ok {
+			tx = stored.Transaction
+		}
+		s.mutex.RUnlock()
+
+		// deleted after snapshot
+		if !ok {
+			continue
+		}
+
+		if err := fn(&tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mergeMatchedAddresses adds addresses to matched addresses of stored transaction without duplicates
+// The slice is copied because readers may hold a copy of the transaction
+func (s *InMemoryTransactionStorage) mergeMatchedAddresses(stored *types.IndexedTransaction, addresses []types.Address) {
+	merged := slices.Clone(stored.MatchedAddresses)
+	added := make([]types.Address, 0)
+	for _, address := range addresses {
+		if !slices.Contains(merged, address) {
+			merged = append(merged, address)
+			added = append(added, address)
+		}
+	}
+
+	stored.MatchedAddresses = merged
+
+	// aggregates of the transaction are added for newly matched addresses
+	s.applyAnalytics(&stored.Transaction, s.txMaxFees[stored.Hash], 1, added)
+}
+
 // appendsTxHashForAddress appends tx hash list for target account
-func (s *InMemoryTransactionStorage) appendsTxHashForAddress(account string, txHash string) {
-	account = strings.ToLower(account)
+func (s *InMemoryTransactionStorage) appendsTxHashForAddress(account types.Address, txHash types.Hash) {
+	// to is empty for contract creation
+	if account.IsEmpty() {
+		return
+	}
+
+	account = account.Lower()
 
 	_, ok := s.txHashesByAddress[account]
 	if !ok {
-		s.txHashesByAddress[account] = make([]string, 0)
+		s.txHashesByAddress[account] = make([]types.Hash, 0)
 	}
 
 	s.txHashesByAddress[account] = append(s.txHashesByAddress[account], txHash)
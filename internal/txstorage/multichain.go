@@ -0,0 +1,66 @@
+package txstorage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MultiChainStorage is a storage shared by parsers of multiple chains
+// Transactions are separated by chain id so that the same address on different chains doesn't collide
+type MultiChainStorage struct {
+	chains map[uint64]*InMemoryTransactionStorage // ChainId -> Storage
+
+	mutex sync.Mutex
+}
+
+func NewMultiChain() *MultiChainStorage {
+	return &MultiChainStorage{
+		chains: make(map[uint64]*InMemoryTransactionStorage),
+	}
+}
+
+// ForChain returns the storage for given chain, it's created at first call
+func (s *MultiChainStorage) ForChain(chainId uint64) *InMemoryTransactionStorage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	storage, ok := s.chains[chainId]
+	if !ok {
+		storage = New()
+		s.chains[chainId] = storage
+	}
+
+	return storage
+}
+
+// Close closes storages of all chains
+func (s *MultiChainStorage) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	errs := make([]error, 0)
+	for id, storage := range s.chains {
+		if err := storage.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close storage of chain %d: %w", id, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ChainIds returns ids of chains which have storage in ascending order
+func (s *MultiChainStorage) ChainIds() []uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ids := make([]uint64, 0, len(s.chains))
+	for id := range s.chains {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
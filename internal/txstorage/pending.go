@@ -0,0 +1,163 @@
+package txstorage
+
+import (
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// senderNonce is a key of pending transactions which can replace each other
+type senderNonce struct {
+	sender types.Address // in lower case
+	nonce  uint64
+}
+
+func senderNonceOf(tx *types.Transaction) senderNonce {
+	return senderNonce{sender: tx.From.Lower(), nonce: tx.Nonce.Uint64()}
+}
+
+// InsertPendingTransaction stores a transaction seen in mempool with pending status
+// It's ignored if the transaction has been already confirmed or tracked
+func (s *InMemoryTransactionStorage) InsertPendingTransaction(tx *types.Transaction) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if _, confirmed := s.txMap[tx.Hash]; confirmed {
+		return nil
+	}
+
+	if _, tracked := s.pendingTxMap[tx.Hash]; tracked {
+		return nil
+	}
+
+	s.pendingTxMap[tx.Hash] = &types.PendingTransaction{
+		Transaction: *tx,
+		Status:      types.TxStatusPending,
+		FirstSeenAt: time.Now().UTC(),
+	}
+
+	key := senderNonceOf(tx)
+	s.pendingTxsByNonce[key] = append(s.pendingTxsByNonce[key], tx.Hash)
+
+	return nil
+}
+
+// GetPendingTransactionsByAddress returns pending, dropped and replaced transactions associated with given address
+func (s *InMemoryTransactionStorage) GetPendingTransactionsByAddress(target types.Address) []types.PendingTransaction {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var txs []types.PendingTransaction
+	for _, tx := range s.pendingTxMap {
+		if tx.From.Equal(target) || tx.To.Equal(target) {
+			txs = append(txs, *tx)
+		}
+	}
+
+	return txs
+}
+
+// GetPendingTransactionHashes returns hashes of transactions in pending status first seen before given time
+func (s *InMemoryTransactionStorage) GetPendingTransactionHashes(seenBefore time.Time) []types.Hash {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var hashes []types.Hash
+	for hash, tx := range s.pendingTxMap {
+		if tx.Status == types.TxStatusPending && tx.FirstSeenAt.Before(seenBefore) {
+			hashes = append(hashes, hash)
+		}
+	}
+
+	return hashes
+}
+
+// MarkPendingTransactionDropped changes status of pending transaction to dropped
+func (s *InMemoryTransactionStorage) MarkPendingTransactionDropped(hash types.Hash) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if tx, ok := s.pendingTxMap[hash]; ok && tx.Status == types.TxStatusPending {
+		tx.Status = types.TxStatusDropped
+	}
+
+	return nil
+}
+
+// ConfirmPendingTransaction resolves a pending transaction included in a block which isn't stored
+// It happens when the addresses of the transaction are unsubscribed before it's mined
+func (s *InMemoryTransactionStorage) ConfirmPendingTransaction(confirmed *types.Transaction) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	s.resolvePendingTransaction(confirmed)
+
+	return nil
+}
+
+// PrunePendingTransactions removes dropped and replaced transactions first seen before given time
+func (s *InMemoryTransactionStorage) PrunePendingTransactions(seenBefore time.Time) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pruned := 0
+	for hash, tx := range s.pendingTxMap {
+		if tx.Status != types.TxStatusPending && tx.FirstSeenAt.Before(seenBefore) {
+			delete(s.pendingTxMap, hash)
+			s.unindexPendingTransaction(&tx.Transaction)
+			pruned++
+		}
+	}
+
+	return pruned
+}
+
+// unindexPendingTransaction removes the transaction from pendingTxsByNonce, the caller must hold the lock
+func (s *InMemoryTransactionStorage) unindexPendingTransaction(tx *types.Transaction) {
+	key := senderNonceOf(tx)
+
+	hashes := s.pendingTxsByNonce[key]
+	for i, hash := range hashes {
+		if hash == tx.Hash {
+			hashes = append(hashes[:i], hashes[i+1:]...)
+			break
+		}
+	}
+
+	if len(hashes) == 0 {
+		delete(s.pendingTxsByNonce, key)
+	} else {
+		s.pendingTxsByNonce[key] = hashes
+	}
+}
+
+// resolvePendingTransaction updates pending transactions by a confirmed transaction
+// The caller must hold the lock
+func (s *InMemoryTransactionStorage) resolvePendingTransaction(confirmed *types.Transaction) {
+	// promoted to confirmed
+	delete(s.pendingTxMap, confirmed.Hash)
+
+	// other transactions with the same sender and nonce can't be included anymore
+	key := senderNonceOf(confirmed)
+	for _, hash := range s.pendingTxsByNonce[key] {
+		if tx, ok := s.pendingTxMap[hash]; ok && tx.Status == types.TxStatusPending {
+			tx.Status = types.TxStatusReplaced
+			tx.ReplacedBy = confirmed.Hash
+		}
+	}
+
+	// none of them is pending anymore, they are kept in pendingTxMap until pruned
+	delete(s.pendingTxsByNonce, key)
+}
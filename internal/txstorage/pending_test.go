@@ -0,0 +1,80 @@
+package txstorage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	alice = types.Address("0xaAaAaAaaAaAaAaaAaAAAAAAAAaaaAaAaAaaAaaAa")
+	bob   = types.Address("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+)
+
+func pendingTx(hash types.Hash, from types.Address, nonce uint64) *types.Transaction {
+	return &types.Transaction{
+		Hash:  hash,
+		From:  from,
+		To:    bob,
+		Nonce: types.NewQuantityFromUint64(nonce),
+	}
+}
+
+func TestConfirmedTransactionReplacesPendingOnesOfSameNonce(t *testing.T) {
+	s := New()
+
+	for _, tx := range []*types.Transaction{
+		pendingTx("0x01", alice, 1),
+		pendingTx("0x02", alice, 1),
+		pendingTx("0x03", alice, 2),
+		pendingTx("0x04", bob, 1),
+	} {
+		if err := s.InsertPendingTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// sender in another case is the same account
+	confirmed := pendingTx("0x01", alice.Lower(), 1)
+	confirmed.BlockNumber = "0x1"
+	if err := s.InsertTransactions([]*types.IndexedTransaction{{Transaction: *confirmed, MatchedAddresses: []types.Address{alice.Lower()}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := make(map[types.Hash]types.PendingTransaction)
+	for _, tx := range s.GetPendingTransactionsByAddress(bob) {
+		statuses[tx.Hash] = tx
+	}
+
+	if _, ok := statuses["0x01"]; ok {
+		t.Fatal("expected confirmed transaction not to be pending")
+	}
+
+	if tx := statuses["0x02"]; tx.Status != types.TxStatusReplaced || tx.ReplacedBy != "0x01" {
+		t.Fatalf("expected 0x02 replaced by 0x01, but got %+v", tx)
+	}
+
+	for _, hash := range []types.Hash{"0x03", "0x04"} {
+		if tx := statuses[hash]; tx.Status != types.TxStatusPending {
+			t.Fatalf("expected %s still pending, but got %+v", hash, tx)
+		}
+	}
+
+	if len(s.pendingTxsByNonce) != 2 {
+		t.Fatalf("expected only pending transactions indexed by nonce, but got %v", s.pendingTxsByNonce)
+	}
+
+	// pruned transactions are removed from index as well
+	if err := s.MarkPendingTransactionDropped("0x03"); err != nil {
+		t.Fatal(err)
+	}
+
+	if pruned := s.PrunePendingTransactions(time.Now().Add(time.Second)); pruned != 2 {
+		t.Fatalf("expected replaced and dropped transactions to be pruned, but got %d", pruned)
+	}
+
+	if len(s.pendingTxMap) != 1 || len(s.pendingTxsByNonce) != 1 {
+		t.Fatalf("expected only 0x04 left, but got %v, %v", s.pendingTxMap, s.pendingTxsByNonce)
+	}
+}
@@ -0,0 +1,99 @@
+package txstorage
+
+import (
+	"slices"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// DeleteBlockRange deletes transactions and block headers in blocks from..to inclusive,
+// so that they can be ingested again. Headers are kept for blocks still having transactions
+// Account states are kept, they're deleted by DeleteAccountStates
+// If address is given, only the address is unmatched from transactions, and transactions still matched
+// to other addresses are kept. It returns the number of transactions deleted or unmatched
+func (s *InMemoryTransactionStorage) DeleteBlockRange(from, to uint64, address *types.Address) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return 0, ErrClosed
+	}
+
+	var target types.Address
+	if address != nil {
+		target = address.Lower()
+	}
+
+	deleted := 0
+	for height, txHashes := range s.txHashesByBlock {
+		if height < from || height > to {
+			continue
+		}
+
+		kept := make([]types.Hash, 0, len(txHashes))
+		for _, hash := range txHashes {
+			tx := s.txMap[hash]
+
+			if address != nil {
+				idx := slices.Index(tx.MatchedAddresses, target)
+				if idx < 0 {
+					kept = append(kept, hash)
+					continue
+				}
+
+				deleted++
+				tx.MatchedAddresses = slices.Delete(slices.Clone(tx.MatchedAddresses), idx, idx+1)
+				s.applyAnalytics(&tx.Transaction, s.txMaxFees[hash], -1, []types.Address{target})
+
+				if len(tx.MatchedAddresses) > 0 {
+					kept = append(kept, hash)
+					continue
+				}
+			} else {
+				deleted++
+			}
+
+			s.unrecordAnalytics(tx)
+			delete(s.txMap, hash)
+			s.removeTxHashForAddress(tx.From, hash)
+			s.removeTxHashForAddress(tx.To, hash)
+		}
+
+		if len(kept) == 0 {
+			delete(s.txHashesByBlock, height)
+			s.deleteBlockHeader(height)
+		} else {
+			s.txHashesByBlock[height] = kept
+		}
+	}
+
+	if address == nil {
+		for height := range s.blockHeaders {
+			if height >= from && height <= to {
+				s.deleteBlockHeader(height)
+			}
+		}
+	}
+
+	return deleted, nil
+}
+
+// removeTxHashForAddress removes tx hash from the list of target account
+// The list is copied because IterateTransactionsByAddress may hold the old one
+func (s *InMemoryTransactionStorage) removeTxHashForAddress(account types.Address, txHash types.Hash) {
+	if account.IsEmpty() {
+		return
+	}
+
+	account = account.Lower()
+
+	txHashes := slices.DeleteFunc(slices.Clone(s.txHashesByAddress[account]), func(hash types.Hash) bool {
+		return hash.Equal(txHash)
+	})
+
+	if len(txHashes) == 0 {
+		delete(s.txHashesByAddress, account)
+	} else {
+		s.txHashesByAddress[account] = txHashes
+	}
+}
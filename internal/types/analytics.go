@@ -0,0 +1,26 @@
+package types
+
+// AddressAnalytics is aggregated values of transactions associated with an address
+type AddressAnalytics struct {
+	TxCount int `json:"txCount"`
+	// InboundCount and OutboundCount both include transactions sent to the address itself
+	InboundCount  int      `json:"inboundCount"`
+	OutboundCount int      `json:"outboundCount"`
+	TotalIn       Quantity `json:"totalIn"`
+	TotalOut      Quantity `json:"totalOut"`
+	// MaxFeesPaid is the sum of maximum fees of outbound transactions, see MaxFee
+	MaxFeesPaid Quantity `json:"maxFeesPaid" doc:"Sum of maxFee of outbound transactions, the upper bound of fees actually paid"`
+	// Counterparties are ranked by volume in descending order
+	Counterparties []CounterpartyVolume `json:"counterparties"`
+}
+
+// CounterpartyVolume is the value transferred between an address and its counterparty
+type CounterpartyVolume struct {
+	Address Address `json:"address"`
+	TxCount int     `json:"txCount"`
+	// Sent is the value sent to the counterparty, and Received is the value received from it
+	Sent     Quantity `json:"sent"`
+	Received Quantity `json:"received"`
+	// Volume is the sum of Sent and Received
+	Volume Quantity `json:"volume"`
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/crypto"
+)
+
+var ErrInvalidChecksum = errors.New("address has invalid EIP-55 checksum")
+
+// NormalizeAddress validates given address and returns it in canonical (lower case) form
+// All lower case and all upper case addresses are accepted, mixed case address must have valid EIP-55 checksum
+func NormalizeAddress(s string) (Address, error) {
+	if err := validateAddress(s); err != nil {
+		return "", err
+	}
+
+	address := Address(s)
+	if !address.IsSingleCase() && address.Checksum() != address {
+		return "", ErrInvalidChecksum
+	}
+
+	return address.Lower(), nil
+}
+
+// IsSingleCase returns true if hex letters in the address are all lower case or all upper case
+func (a Address) IsSingleCase() bool {
+	digits := strings.TrimPrefix(string(a), "0x")
+
+	return digits == strings.ToLower(digits) || digits == strings.ToUpper(digits)
+}
+
+// Checksum returns the address in EIP-55 mixed case checksum encoding
+func (a Address) Checksum() Address {
+	if a.IsEmpty() {
+		return a
+	}
+
+	digits := []byte(strings.ToLower(strings.TrimPrefix(string(a), "0x")))
+	hash := hex.EncodeToString(crypto.Keccak256(digits))
+
+	// letter is capitalized if corresponding nibble of hash is 8 or more
+	for i, c := range digits {
+		if c >= 'a' && c <= 'f' && hash[i] >= '8' {
+			digits[i] = c - 'a' + 'A'
+		}
+	}
+
+	return Address("0x" + string(digits))
+}
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// checksummedAddresses are the test vectors of EIP-55
+var checksummedAddresses = []Address{
+	"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+	"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+	"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+	"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+}
+
+func TestChecksum(t *testing.T) {
+	for _, expected := range checksummedAddresses {
+		for _, address := range []Address{expected.Lower(), Address("0x" + strings.ToUpper(string(expected[2:])))} {
+			if actual := address.Checksum(); actual != expected {
+				t.Errorf("expected checksum of %s to be %s, but got %s", address, expected, actual)
+			}
+		}
+	}
+
+	if actual := Address("").Checksum(); actual != "" {
+		t.Errorf("expected empty address to be kept, but got %s", actual)
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	for _, checksummed := range checksummedAddresses {
+		for _, address := range []string{
+			string(checksummed),
+			string(checksummed.Lower()),
+			"0x" + strings.ToUpper(string(checksummed[2:])),
+		} {
+			if actual, err := NormalizeAddress(address); err != nil || actual != checksummed.Lower() {
+				t.Errorf("expected %s to be normalized to %s, but got address=%s, err=%v", address, checksummed.Lower(), actual, err)
+			}
+		}
+	}
+}
+
+func TestNormalizeAddressRejectsBadChecksum(t *testing.T) {
+	for _, checksummed := range checksummedAddresses {
+		// flip case of the last letter
+		digits := []byte(checksummed)
+		for i := len(digits) - 1; i >= 2; i-- {
+			if c := digits[i]; c >= 'a' && c <= 'f' {
+				digits[i] = c - 'a' + 'A'
+				break
+			} else if c >= 'A' && c <= 'F' {
+				digits[i] = c - 'A' + 'a'
+				break
+			}
+		}
+
+		if _, err := NormalizeAddress(string(digits)); !errors.Is(err, ErrInvalidChecksum) {
+			t.Errorf("expected %v for %s, but got %v", ErrInvalidChecksum, digits, err)
+		}
+	}
+
+	for _, invalid := range []string{"", "0x1234", "5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "0x" + strings.Repeat("zz", 20)} {
+		if _, err := NormalizeAddress(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
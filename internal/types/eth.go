@@ -2,68 +2,80 @@ package types
 
 // Block is Ethereum Block Structure (same as JSON-RPC schema)
 type Block struct {
-	BaseFeePerGas         string        `json:"baseFeePerGas"`
-	BlobGasUsed           string        `json:"blobGasUsed"`
-	Difficulty            string        `json:"difficulty"`
-	ExcessBlobGas         string        `json:"excessBlobGas"`
-	ExtraData             string        `json:"extraData"`
-	GasLimit              string        `json:"gasLimit"`
-	GasUsed               string        `json:"gasUsed"`
-	Hash                  string        `json:"hash"`
-	LogsBloom             string        `json:"logsBloom"`
-	Miner                 string        `json:"miner"`
-	MixHash               string        `json:"mixHash"`
-	Nonce                 string        `json:"nonce"`
-	Number                string        `json:"number"`
-	ParentBeaconBlockRoot string        `json:"parentBeaconBlockRoot"`
-	ParentHash            string        `json:"parentHash"`
-	ReceiptsRoot          string        `json:"receiptsRoot"`
-	Sha3Uncles            string        `json:"sha3Uncles"`
-	Size                  string        `json:"size"`
-	StateRoot             string        `json:"stateRoot"`
-	Timestamp             string        `json:"timestamp"`
-	TotalDifficulty       string        `json:"totalDifficulty"`
+	BaseFeePerGas         Quantity      `json:"baseFeePerGas"`
+	BlobGasUsed           Quantity      `json:"blobGasUsed"`
+	Difficulty            Quantity      `json:"difficulty"`
+	ExcessBlobGas         Quantity      `json:"excessBlobGas"`
+	ExtraData             Data          `json:"extraData"`
+	GasLimit              Quantity      `json:"gasLimit"`
+	GasUsed               Quantity      `json:"gasUsed"`
+	Hash                  Hash          `json:"hash"`
+	LogsBloom             Data          `json:"logsBloom"`
+	Miner                 Address       `json:"miner"`
+	MixHash               Hash          `json:"mixHash"`
+	Nonce                 Data          `json:"nonce"`
+	Number                Quantity      `json:"number"`
+	ParentBeaconBlockRoot Hash          `json:"parentBeaconBlockRoot"`
+	ParentHash            Hash          `json:"parentHash"`
+	ReceiptsRoot          Hash          `json:"receiptsRoot"`
+	RequestsHash          Hash          `json:"requestsHash,omitempty"`
+	Sha3Uncles            Hash          `json:"sha3Uncles"`
+	Size                  Quantity      `json:"size"`
+	StateRoot             Hash          `json:"stateRoot"`
+	Timestamp             Quantity      `json:"timestamp"`
+	TotalDifficulty       Quantity      `json:"totalDifficulty"`
 	Transactions          []Transaction `json:"transactions"`
-	TransactionsRoot      string        `json:"transactionsRoot"`
-	Uncles                []interface{} `json:"uncles"`
+	TransactionsRoot      Hash          `json:"transactionsRoot"`
+	Uncles                []Hash        `json:"uncles"`
 	Withdrawals           []Withdrawal  `json:"withdrawals"`
-	WithdrawalsRoot       string        `json:"withdrawalsRoot"`
+	WithdrawalsRoot       Hash          `json:"withdrawalsRoot"`
 }
 
 type Withdrawal struct {
-	Index          string `json:"index"`
-	ValidatorIndex string `json:"validatorIndex"`
-	Address        string `json:"address"`
-	Amount         string `json:"amount"`
+	Index          Quantity `json:"index"`
+	ValidatorIndex Quantity `json:"validatorIndex"`
+	Address        Address  `json:"address"`
+	Amount         Quantity `json:"amount"`
 }
 
 // Transaction is Ethereum Transaction Structure (same as JSON-RPC schema)
 type Transaction struct {
-	BlockHash            string              `json:"blockHash"`
-	BlockNumber          string              `json:"blockNumber"`
-	From                 string              `json:"from"`
-	Gas                  string              `json:"gas"`
-	GasPrice             string              `json:"gasPrice"`
-	MaxFeePerGas         string              `json:"maxFeePerGas,omitempty"`
-	MaxPriorityFeePerGas string              `json:"maxPriorityFeePerGas,omitempty"`
-	Hash                 string              `json:"hash"`
-	Input                string              `json:"input"`
-	Nonce                string              `json:"nonce"`
-	To                   string              `json:"to"`
-	TransactionIndex     string              `json:"transactionIndex"`
-	Value                string              `json:"value"`
-	Type                 string              `json:"type"`
+	BlockHash            Hash                `json:"blockHash"`
+	BlockNumber          Quantity            `json:"blockNumber"`
+	From                 Address             `json:"from"`
+	Gas                  Quantity            `json:"gas"`
+	GasPrice             Quantity            `json:"gasPrice"`
+	MaxFeePerGas         Quantity            `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas Quantity            `json:"maxPriorityFeePerGas,omitempty"`
+	Hash                 Hash                `json:"hash"`
+	Input                Data                `json:"input"`
+	Nonce                Quantity            `json:"nonce"`
+	To                   Address             `json:"to"`
+	TransactionIndex     Quantity            `json:"transactionIndex"`
+	Value                Quantity            `json:"value"`
+	Type                 Quantity            `json:"type"`
 	AccessList           []AccessListElement `json:"accessList,omitempty"`
-	ChainId              string              `json:"chainId,omitempty"`
-	V                    string              `json:"v"`
-	R                    string              `json:"r"`
-	S                    string              `json:"s"`
-	YParity              string              `json:"yParity,omitempty"`
-	MaxFeePerBlobGas     string              `json:"maxFeePerBlobGas,omitempty"`
-	BlobVersionedHashes  []string            `json:"blobVersionedHashes,omitempty"`
+	ChainId              Quantity            `json:"chainId,omitempty"`
+	V                    Quantity            `json:"v"`
+	R                    Quantity            `json:"r"`
+	S                    Quantity            `json:"s"`
+	YParity              Quantity            `json:"yParity,omitempty"`
+	MaxFeePerBlobGas     Quantity            `json:"maxFeePerBlobGas,omitempty"`
+	BlobVersionedHashes  []Hash              `json:"blobVersionedHashes,omitempty"`
+	AuthorizationList    []Authorization     `json:"authorizationList,omitempty"`
 }
 
 type AccessListElement struct {
-	Address     string   `json:"address"`
-	StorageKeys []string `json:"storageKeys"`
+	Address     Address `json:"address"`
+	StorageKeys []Hash  `json:"storageKeys"`
+}
+
+// Authorization is an element of authorization list in EIP-7702 transaction
+type Authorization struct {
+	ChainId Quantity `json:"chainId"`
+	Address Address  `json:"address"`
+	Nonce   Quantity `json:"nonce"`
+	YParity Quantity `json:"yParity"`
+	R       Quantity `json:"r"`
+	S       Quantity `json:"s"`
 }
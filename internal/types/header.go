@@ -0,0 +1,60 @@
+package types
+
+import (
+	"math/big"
+	"time"
+)
+
+// BlockHeader is a summary of block kept with transactions indexed from the block
+type BlockHeader struct {
+	Number        Quantity `json:"number"`
+	Hash          Hash     `json:"hash"`
+	Timestamp     Quantity `json:"timestamp"`
+	BaseFeePerGas Quantity `json:"baseFeePerGas,omitempty"`
+	Miner         Address  `json:"miner"`
+}
+
+// NewBlockHeader returns the summary of given block
+func NewBlockHeader(block *Block) *BlockHeader {
+	return &BlockHeader{
+		Number:        block.Number,
+		Hash:          block.Hash,
+		Timestamp:     block.Timestamp,
+		BaseFeePerGas: block.BaseFeePerGas,
+		Miner:         block.Miner.Lower(),
+	}
+}
+
+// Time returns the block timestamp in UTC
+func (h *BlockHeader) Time() time.Time {
+	return time.Unix(int64(h.Timestamp.Uint64()), 0).UTC()
+}
+
+// EffectiveGasPrice returns the price per gas paid by the transaction in the block
+// It's min(maxFeePerGas, baseFeePerGas + maxPriorityFeePerGas) for EIP-1559 transactions, otherwise gasPrice
+// It returns nil if prices are missing
+func EffectiveGasPrice(tx *Transaction, baseFeePerGas Quantity) *big.Int {
+	baseFee, maxFee, priorityFee := baseFeePerGas.Big(), tx.MaxFeePerGas.Big(), tx.MaxPriorityFeePerGas.Big()
+	if baseFee == nil || maxFee == nil || priorityFee == nil {
+		return tx.GasPrice.Big()
+	}
+
+	price := new(big.Int).Add(baseFee, priorityFee)
+	if price.Cmp(maxFee) > 0 {
+		return maxFee
+	}
+
+	return price
+}
+
+// MaxFee returns the maximum fee of the transaction, which is effective gas price multiplied by gas limit
+// Blocks don't have gas used by each transaction, so it's more than the fee actually paid unless all gas was used
+// It returns nil if price or gas is missing
+func MaxFee(tx *Transaction, baseFeePerGas Quantity) *big.Int {
+	price, gas := EffectiveGasPrice(tx, baseFeePerGas), tx.Gas.Big()
+	if price == nil || gas == nil {
+		return nil
+	}
+
+	return new(big.Int).Mul(price, gas)
+}
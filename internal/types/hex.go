@@ -0,0 +1,319 @@
+package types
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+const (
+	// AddressLength is the length of address in bytes
+	AddressLength = 20
+	// HashLength is the length of hash in bytes
+	HashLength = 32
+)
+
+var (
+	ErrEmptyValue = errors.New("empty value")
+
+	quantityPattern = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+	dataPattern     = regexp.MustCompile(`^0x([0-9a-fA-F]{2})*$`)
+)
+
+// Quantity is an unsigned integer encoded in hex (e.g. 0x1a)
+// Empty Quantity means the field is null or absent in JSON-RPC response
+type Quantity string
+
+// Hash is a 32 bytes hash encoded in hex
+type Hash string
+
+// Address is a 20 bytes account address encoded in hex
+type Address string
+
+// Data is an arbitrary length byte array encoded in hex
+type Data string
+
+// NewQuantity creates Quantity from big.Int
+func NewQuantity(value *big.Int) Quantity {
+	if value == nil {
+		return ""
+	}
+
+	return Quantity("0x" + value.Text(16))
+}
+
+// NewQuantityFromUint64 creates Quantity from uint64
+func NewQuantityFromUint64(value uint64) Quantity {
+	return NewQuantity(new(big.Int).SetUint64(value))
+}
+
+// ParseQuantity validates and returns Quantity
+func ParseQuantity(s string) (Quantity, error) {
+	if err := validateQuantity(s); err != nil {
+		return "", err
+	}
+
+	return Quantity(s), nil
+}
+
+// IsEmpty returns true if the quantity is null or absent
+func (q Quantity) IsEmpty() bool {
+	return q == ""
+}
+
+// Big returns the value as big.Int, it returns nil if the quantity is empty or malformed
+func (q Quantity) Big() *big.Int {
+	if q.IsEmpty() {
+		return nil
+	}
+
+	value, ok := new(big.Int).SetString(string(q), 0)
+	if !ok {
+		return nil
+	}
+
+	return value
+}
+
+// Uint64 returns the value as uint64, it returns 0 if the quantity is empty or malformed
+func (q Quantity) Uint64() uint64 {
+	value := q.Big()
+	if value == nil {
+		return 0
+	}
+
+	return value.Uint64()
+}
+
+// Decimal returns the value in decimal string, it returns empty string if the quantity is empty
+func (q Quantity) Decimal() string {
+	value := q.Big()
+	if value == nil {
+		return ""
+	}
+
+	return value.String()
+}
+
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return marshalHex(string(q))
+}
+
+func (q *Quantity) UnmarshalJSON(input []byte) error {
+	s, err := unmarshalHex(input, validateQuantity)
+	if err != nil {
+		return fmt.Errorf("invalid quantity: %w", err)
+	}
+
+	*q = Quantity(s)
+
+	return nil
+}
+
+// ParseHash validates and returns Hash
+func ParseHash(s string) (Hash, error) {
+	if err := validateHash(s); err != nil {
+		return "", err
+	}
+
+	return Hash(s), nil
+}
+
+// Bytes returns the hash in bytes
+func (h Hash) Bytes() []byte {
+	return decodeHex(string(h))
+}
+
+// Equal compares hashes regardless of letter case
+func (h Hash) Equal(other Hash) bool {
+	return strings.EqualFold(string(h), string(other))
+}
+
+func (h Hash) MarshalJSON() ([]byte, error) {
+	return marshalHex(string(h))
+}
+
+func (h *Hash) UnmarshalJSON(input []byte) error {
+	s, err := unmarshalHex(input, validateHash)
+	if err != nil {
+		return fmt.Errorf("invalid hash: %w", err)
+	}
+
+	*h = Hash(s)
+
+	return nil
+}
+
+// ParseAddress validates and returns Address
+func ParseAddress(s string) (Address, error) {
+	if err := validateAddress(s); err != nil {
+		return "", err
+	}
+
+	return Address(s), nil
+}
+
+// IsEmpty returns true if the address is null or absent (e.g. to of contract creation)
+func (a Address) IsEmpty() bool {
+	return a == ""
+}
+
+// Bytes returns the address in bytes
+func (a Address) Bytes() []byte {
+	return decodeHex(string(a))
+}
+
+// Lower returns the address in lower case, which is used as a key of maps
+func (a Address) Lower() Address {
+	return Address(strings.ToLower(string(a)))
+}
+
+// Equal compares addresses by its bytes, so checksummed and lower case addresses are equal
+func (a Address) Equal(other Address) bool {
+	return bytes.Equal(a.Bytes(), other.Bytes())
+}
+
+func (a Address) MarshalJSON() ([]byte, error) {
+	return marshalHex(string(a))
+}
+
+func (a *Address) UnmarshalJSON(input []byte) error {
+	s, err := unmarshalHex(input, validateAddress)
+	if err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+
+	*a = Address(s)
+
+	return nil
+}
+
+// ParseData validates and returns Data
+func ParseData(s string) (Data, error) {
+	if err := validateData(s); err != nil {
+		return "", err
+	}
+
+	return Data(s), nil
+}
+
+// Bytes returns the data in bytes
+func (d Data) Bytes() []byte {
+	return decodeHex(string(d))
+}
+
+// Len returns the length of data in bytes
+func (d Data) Len() int {
+	if len(d) < 2 {
+		return 0
+	}
+
+	return (len(d) - 2) / 2
+}
+
+func (d Data) MarshalJSON() ([]byte, error) {
+	return marshalHex(string(d))
+}
+
+func (d *Data) UnmarshalJSON(input []byte) error {
+	s, err := unmarshalHex(input, validateData)
+	if err != nil {
+		return fmt.Errorf("invalid data: %w", err)
+	}
+
+	*d = Data(s)
+
+	return nil
+}
+
+// validateQuantity checks that given string is a hex encoded integer
+func validateQuantity(s string) error {
+	if s == "" {
+		return ErrEmptyValue
+	}
+	if !quantityPattern.MatchString(s) {
+		return fmt.Errorf("%q is not hex encoded integer", s)
+	}
+
+	return nil
+}
+
+// validateHash checks that given string is a hex encoded 32 bytes
+func validateHash(s string) error {
+	return validateFixedData(s, HashLength)
+}
+
+// validateAddress checks that given string is a hex encoded 20 bytes
+func validateAddress(s string) error {
+	return validateFixedData(s, AddressLength)
+}
+
+// validateData checks that given string is a hex encoded byte array
+func validateData(s string) error {
+	if s == "" {
+		return ErrEmptyValue
+	}
+	if !dataPattern.MatchString(s) {
+		return fmt.Errorf("%q is not hex encoded bytes", s)
+	}
+
+	return nil
+}
+
+// validateFixedData checks that given string is a hex encoded byte array of given length
+func validateFixedData(s string, length int) error {
+	if err := validateData(s); err != nil {
+		return err
+	}
+	if len(s) != 2+length*2 {
+		return fmt.Errorf("%q is not %d bytes", s, length)
+	}
+
+	return nil
+}
+
+// decodeHex decodes 0x-prefixed hex, it returns nil if given string is malformed
+func decodeHex(s string) []byte {
+	if !strings.HasPrefix(s, "0x") {
+		return nil
+	}
+
+	b, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return nil
+	}
+
+	return b
+}
+
+// marshalHex encodes hex string in JSON, empty value is encoded as null
+func marshalHex(s string) ([]byte, error) {
+	if s == "" {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(s)
+}
+
+// unmarshalHex decodes hex string in JSON and validates it, null is decoded as empty value
+func unmarshalHex(input []byte, validate func(string) error) (string, error) {
+	if string(input) == "null" {
+		return "", nil
+	}
+
+	var s string
+	if err := json.Unmarshal(input, &s); err != nil {
+		return "", err
+	}
+
+	if err := validate(s); err != nil {
+		return "", err
+	}
+
+	return s, nil
+}
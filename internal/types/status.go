@@ -0,0 +1,40 @@
+package types
+
+import "time"
+
+// TxStatus is a status of transaction tracked by the aggregator
+type TxStatus string
+
+const (
+	// TxStatusPending is a transaction seen in mempool but not included in a block yet
+	TxStatusPending TxStatus = "pending"
+	// TxStatusConfirmed is a transaction included in a processed block
+	TxStatusConfirmed TxStatus = "confirmed"
+	// TxStatusDropped is a pending transaction which disappeared from mempool without being included
+	TxStatusDropped TxStatus = "dropped"
+	// TxStatusReplaced is a pending transaction whose nonce was used by another included transaction
+	TxStatusReplaced TxStatus = "replaced"
+)
+
+// PendingTransaction is a transaction seen in mempool
+type PendingTransaction struct {
+	Transaction
+	Status      TxStatus  `json:"status"`
+	FirstSeenAt time.Time `json:"firstSeenAt"`
+	// ReplacedBy is the hash of included transaction having the same sender and nonce
+	ReplacedBy Hash `json:"replacedBy,omitempty"`
+}
+
+// AccountState is the balance and nonce of an account at a block
+type AccountState struct {
+	Address     Address  `json:"address"`
+	BlockNumber Quantity `json:"blockNumber"`
+	Balance     Quantity `json:"balance"`
+	Nonce       Quantity `json:"nonce"`
+}
+
+// IndexedTransaction is a confirmed transaction with subscribed addresses which caused it to be indexed
+type IndexedTransaction struct {
+	Transaction
+	MatchedAddresses []Address `json:"matchedAddresses"`
+}
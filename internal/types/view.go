@@ -0,0 +1,162 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+var (
+	quantityType  = reflect.TypeOf(Quantity(""))
+	addressType   = reflect.TypeOf(Address(""))
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// ViewOptions specifies how values are represented in responses
+type ViewOptions struct {
+	// DecimalQuantities represents every Quantity in decimal string instead of hex
+	DecimalQuantities bool
+	// ChecksumAddresses represents every Address in EIP-55 checksum encoding
+	ChecksumAddresses bool
+}
+
+// IsDefault returns true if no option is enabled
+func (o ViewOptions) IsDefault() bool {
+	return !o.DecimalQuantities && !o.ChecksumAddresses
+}
+
+// View returns a value which is encoded in JSON same as given value except for representations chosen by options
+func View(v interface{}, opts ViewOptions) interface{} {
+	if opts.IsDefault() {
+		return v
+	}
+
+	return opts.value(reflect.ValueOf(v))
+}
+
+// value converts given value to a generic value recursively
+func (o ViewOptions) value(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Type() {
+	case quantityType:
+		q := v.Interface().(Quantity)
+		if q.IsEmpty() {
+			return nil
+		}
+		if o.DecimalQuantities {
+			return q.Decimal()
+		}
+
+		return q
+	case addressType:
+		a := v.Interface().(Address)
+		if o.ChecksumAddresses {
+			return a.Checksum()
+		}
+
+		return a
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+
+		return o.value(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Type().Implements(marshalerType) {
+			return v.Interface()
+		}
+
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = o.value(v.Index(i))
+		}
+
+		return values
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+
+		values := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = o.value(iter.Value())
+		}
+
+		return values
+	case reflect.Struct:
+		if v.Type().Implements(marshalerType) {
+			return v.Interface()
+		}
+
+		values := make(map[string]interface{}, v.NumField())
+		o.structFields(v, values)
+
+		return values
+	default:
+		return v.Interface()
+	}
+}
+
+// structFields puts exported fields of struct into map by its JSON name, embedded structs are flattened
+func (o ViewOptions) structFields(v reflect.Value, values map[string]interface{}) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldValue := v.Field(i)
+
+		// flatten embedded struct without JSON name
+		if field.Anonymous && name == "" {
+			if fieldValue.Kind() == reflect.Pointer {
+				if fieldValue.IsNil() {
+					continue
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			if fieldValue.Kind() == reflect.Struct {
+				o.structFields(fieldValue, values)
+				continue
+			}
+		}
+
+		if strings.Contains(opts, "omitempty") && isEmptyValue(fieldValue) {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		values[name] = o.value(fieldValue)
+	}
+}
+
+// isEmptyValue reports whether the value is omitted by omitempty option of encoding/json
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,233 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/rlp"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// Transaction types defined by EIP-2718 envelopes
+const (
+	TxTypeLegacy     = 0x00
+	TxTypeAccessList = 0x01
+	TxTypeDynamicFee = 0x02
+	TxTypeBlob       = 0x03
+	TxTypeSetCode    = 0x04
+)
+
+var ErrUnsupportedTxType = errors.New("unsupported transaction type")
+
+// EncodeHeader returns RLP encoded block header, whose keccak256 hash is the block hash
+// Fields added by hard forks are included only if they are present in the block
+func EncodeHeader(block *types.Block) ([]byte, error) {
+	fields := [][]byte{
+		encodeHash(block.ParentHash),
+		encodeHash(block.Sha3Uncles),
+		encodeAddress(block.Miner),
+		encodeHash(block.StateRoot),
+		encodeHash(block.TransactionsRoot),
+		encodeHash(block.ReceiptsRoot),
+		encodeData(block.LogsBloom),
+		encodeQuantity(block.Difficulty),
+		encodeQuantity(block.Number),
+		encodeQuantity(block.GasLimit),
+		encodeQuantity(block.GasUsed),
+		encodeQuantity(block.Timestamp),
+		encodeData(block.ExtraData),
+		encodeHash(block.MixHash),
+		encodeData(block.Nonce),
+	}
+
+	// optional fields must be present continuously from the first one
+	optionals := []struct {
+		name    string
+		present bool
+		encoded []byte
+	}{
+		{"baseFeePerGas", !block.BaseFeePerGas.IsEmpty(), encodeQuantity(block.BaseFeePerGas)},
+		{"withdrawalsRoot", block.WithdrawalsRoot != "", encodeHash(block.WithdrawalsRoot)},
+		{"blobGasUsed", !block.BlobGasUsed.IsEmpty(), encodeQuantity(block.BlobGasUsed)},
+		{"excessBlobGas", !block.ExcessBlobGas.IsEmpty(), encodeQuantity(block.ExcessBlobGas)},
+		{"parentBeaconBlockRoot", block.ParentBeaconBlockRoot != "", encodeHash(block.ParentBeaconBlockRoot)},
+		{"requestsHash", block.RequestsHash != "", encodeHash(block.RequestsHash)},
+	}
+
+	missing := ""
+	for _, field := range optionals {
+		if !field.present {
+			missing = field.name
+			continue
+		}
+		if missing != "" {
+			return nil, fmt.Errorf("header has %s but %s is missing", field.name, missing)
+		}
+
+		fields = append(fields, field.encoded)
+	}
+
+	return rlp.EncodeList(fields...), nil
+}
+
+// EncodeTransaction returns the transaction in EIP-2718 envelope
+// It's RLP encoded list for legacy transaction, or type byte followed by RLP encoded list for typed transaction
+func EncodeTransaction(tx *types.Transaction) ([]byte, error) {
+	txType := tx.Type.Uint64()
+
+	switch txType {
+	case TxTypeLegacy:
+		return rlp.EncodeList(
+			encodeQuantity(tx.Nonce),
+			encodeQuantity(tx.GasPrice),
+			encodeQuantity(tx.Gas),
+			encodeAddress(tx.To),
+			encodeQuantity(tx.Value),
+			encodeData(tx.Input),
+			encodeQuantity(tx.V),
+			encodeQuantity(tx.R),
+			encodeQuantity(tx.S),
+		), nil
+	case TxTypeAccessList:
+		return typedEnvelope(txType,
+			encodeQuantity(tx.ChainId),
+			encodeQuantity(tx.Nonce),
+			encodeQuantity(tx.GasPrice),
+			encodeQuantity(tx.Gas),
+			encodeAddress(tx.To),
+			encodeQuantity(tx.Value),
+			encodeData(tx.Input),
+			encodeAccessList(tx.AccessList),
+			encodeQuantity(yParity(tx)),
+			encodeQuantity(tx.R),
+			encodeQuantity(tx.S),
+		), nil
+	case TxTypeDynamicFee:
+		return typedEnvelope(txType,
+			encodeQuantity(tx.ChainId),
+			encodeQuantity(tx.Nonce),
+			encodeQuantity(tx.MaxPriorityFeePerGas),
+			encodeQuantity(tx.MaxFeePerGas),
+			encodeQuantity(tx.Gas),
+			encodeAddress(tx.To),
+			encodeQuantity(tx.Value),
+			encodeData(tx.Input),
+			encodeAccessList(tx.AccessList),
+			encodeQuantity(yParity(tx)),
+			encodeQuantity(tx.R),
+			encodeQuantity(tx.S),
+		), nil
+	case TxTypeBlob:
+		return typedEnvelope(txType,
+			encodeQuantity(tx.ChainId),
+			encodeQuantity(tx.Nonce),
+			encodeQuantity(tx.MaxPriorityFeePerGas),
+			encodeQuantity(tx.MaxFeePerGas),
+			encodeQuantity(tx.Gas),
+			encodeAddress(tx.To),
+			encodeQuantity(tx.Value),
+			encodeData(tx.Input),
+			encodeAccessList(tx.AccessList),
+			encodeQuantity(tx.MaxFeePerBlobGas),
+			encodeHashes(tx.BlobVersionedHashes),
+			encodeQuantity(yParity(tx)),
+			encodeQuantity(tx.R),
+			encodeQuantity(tx.S),
+		), nil
+	case TxTypeSetCode:
+		return typedEnvelope(txType,
+			encodeQuantity(tx.ChainId),
+			encodeQuantity(tx.Nonce),
+			encodeQuantity(tx.MaxPriorityFeePerGas),
+			encodeQuantity(tx.MaxFeePerGas),
+			encodeQuantity(tx.Gas),
+			encodeAddress(tx.To),
+			encodeQuantity(tx.Value),
+			encodeData(tx.Input),
+			encodeAccessList(tx.AccessList),
+			encodeAuthorizationList(tx.AuthorizationList),
+			encodeQuantity(yParity(tx)),
+			encodeQuantity(tx.R),
+			encodeQuantity(tx.S),
+		), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedTxType, tx.Type)
+	}
+}
+
+// EncodeWithdrawal returns RLP encoded withdrawal, which is a value of withdrawals trie
+func EncodeWithdrawal(w *types.Withdrawal) []byte {
+	return rlp.EncodeList(
+		encodeQuantity(w.Index),
+		encodeQuantity(w.ValidatorIndex),
+		encodeAddress(w.Address),
+		encodeQuantity(w.Amount),
+	)
+}
+
+// typedEnvelope returns type byte followed by RLP encoded list of fields
+func typedEnvelope(txType uint64, fields ...[]byte) []byte {
+	return append([]byte{byte(txType)}, rlp.EncodeList(fields...)...)
+}
+
+// yParity returns y parity of signature, some nodes return it only as v
+func yParity(tx *types.Transaction) types.Quantity {
+	if !tx.YParity.IsEmpty() {
+		return tx.YParity
+	}
+
+	return tx.V
+}
+
+func encodeQuantity(q types.Quantity) []byte {
+	return rlp.EncodeBig(q.Big())
+}
+
+func encodeHash(h types.Hash) []byte {
+	return rlp.EncodeBytes(h.Bytes())
+}
+
+func encodeAddress(a types.Address) []byte {
+	return rlp.EncodeBytes(a.Bytes())
+}
+
+func encodeData(d types.Data) []byte {
+	return rlp.EncodeBytes(d.Bytes())
+}
+
+func encodeHashes(hashes []types.Hash) []byte {
+	items := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		items[i] = encodeHash(h)
+	}
+
+	return rlp.EncodeList(items...)
+}
+
+func encodeAccessList(accessList []types.AccessListElement) []byte {
+	items := make([][]byte, len(accessList))
+	for i, element := range accessList {
+		items[i] = rlp.EncodeList(
+			encodeAddress(element.Address),
+			encodeHashes(element.StorageKeys),
+		)
+	}
+
+	return rlp.EncodeList(items...)
+}
+
+func encodeAuthorizationList(authorizations []types.Authorization) []byte {
+	items := make([][]byte, len(authorizations))
+	for i, auth := range authorizations {
+		items[i] = rlp.EncodeList(
+			encodeQuantity(auth.ChainId),
+			encodeAddress(auth.Address),
+			encodeQuantity(auth.Nonce),
+			encodeQuantity(auth.YParity),
+			encodeQuantity(auth.R),
+			encodeQuantity(auth.S),
+		)
+	}
+
+	return rlp.EncodeList(items...)
+}
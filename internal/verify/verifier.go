@@ -0,0 +1,169 @@
+package verify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/crypto"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/trie"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+var (
+	ErrBlockHashMismatch        = errors.New("block hash mismatch")
+	ErrTransactionHashMismatch  = errors.New("transaction hash mismatch")
+	ErrTransactionsRootMismatch = errors.New("transactions root mismatch")
+	ErrWithdrawalsRootMismatch  = errors.New("withdrawals root mismatch")
+)
+
+// BlockVerifier checks that a block returned by JSON-RPC server is consistent with its hash
+// It recomputes block hash from header fields, and roots of transactions and withdrawals tries from the bodies
+// Transactions of chain-specific types given by WithSkippedTxTypes, such as deposit transactions of OP Stack chains,
+// are reported as unverifiable and skipped, transactions root of such block can't be computed and isn't checked either
+// Transactions of other unknown types fail the verification
+type BlockVerifier struct {
+	logger         *log.Logger
+	skippedTxTypes map[uint64]struct{}
+}
+
+type Option func(*BlockVerifier)
+
+// WithLogger sets logger reporting unverifiable transactions
+func WithLogger(logger *log.Logger) Option {
+	return func(v *BlockVerifier) {
+		v.logger = logger
+	}
+}
+
+// WithSkippedTxTypes sets chain-specific transaction types which can't be encoded, e.g. 0x7e of OP Stack chains
+func WithSkippedTxTypes(txTypes ...uint64) Option {
+	return func(v *BlockVerifier) {
+		for _, txType := range txTypes {
+			v.skippedTxTypes[txType] = struct{}{}
+		}
+	}
+}
+
+func New(opts ...Option) *BlockVerifier {
+	v := &BlockVerifier{
+		logger:         log.Default(),
+		skippedTxTypes: make(map[uint64]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// VerifyBlock returns error if any of computed hashes doesn't match with the one in the block
+// The block must include full transaction objects
+func (v *BlockVerifier) VerifyBlock(block *types.Block) error {
+	if err := v.verifyHeader(block); err != nil {
+		return err
+	}
+
+	if err := v.verifyTransactions(block); err != nil {
+		return err
+	}
+
+	if err := v.verifyWithdrawals(block); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// verifyHeader checks that keccak256 hash of RLP encoded header equals to block hash
+func (v *BlockVerifier) verifyHeader(block *types.Block) error {
+	header, err := EncodeHeader(block)
+	if err != nil {
+		return fmt.Errorf("failed to encode header of block %s: %w", block.Number, err)
+	}
+
+	if computed := crypto.Keccak256(header); !bytes.Equal(computed, block.Hash.Bytes()) {
+		return fmt.Errorf("%w, block=%s, expected=%s, computed=0x%x", ErrBlockHashMismatch, block.Number, block.Hash, computed)
+	}
+
+	return nil
+}
+
+// verifyTransactions checks hash of each transaction and transactions root in the header
+// Transactions of skipped types are not checked, and then transactions root is not checked either
+func (v *BlockVerifier) verifyTransactions(block *types.Block) error {
+	encoded := make([][]byte, len(block.Transactions))
+	unverifiable := make([]types.Hash, 0)
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+
+		envelope, err := EncodeTransaction(tx)
+		if errors.Is(err, ErrUnsupportedTxType) && v.isSkipped(tx.Type) {
+			unverifiable = append(unverifiable, tx.Hash)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to encode transaction %s: %w", tx.Hash, err)
+		}
+
+		if computed := crypto.Keccak256(envelope); !bytes.Equal(computed, tx.Hash.Bytes()) {
+			return fmt.Errorf("%w, expected=%s, computed=0x%x", ErrTransactionHashMismatch, tx.Hash, computed)
+		}
+
+		encoded[i] = envelope
+	}
+
+	// transactions root needs envelopes of all transactions in the block
+	if len(unverifiable) > 0 {
+		v.logger.Printf(
+			"transactions of skipped types are unverifiable, block=%s, transactions=%v, transactions root is not checked",
+			block.Number, unverifiable,
+		)
+
+		return nil
+	}
+
+	if computed := trie.ListRoot(encoded); !bytes.Equal(computed, block.TransactionsRoot.Bytes()) {
+		return fmt.Errorf(
+			"%w, block=%s, expected=%s, computed=0x%x",
+			ErrTransactionsRootMismatch, block.Number, block.TransactionsRoot, computed,
+		)
+	}
+
+	return nil
+}
+
+// isSkipped returns true if transactions of the type are configured to be skipped
+func (v *BlockVerifier) isSkipped(txType types.Quantity) bool {
+	value := txType.Big()
+	if value == nil || !value.IsUint64() {
+		return false
+	}
+
+	_, ok := v.skippedTxTypes[value.Uint64()]
+
+	return ok
+}
+
+// verifyWithdrawals checks withdrawals root in the header, it's skipped for blocks before Shanghai
+func (v *BlockVerifier) verifyWithdrawals(block *types.Block) error {
+	if block.WithdrawalsRoot == "" {
+		return nil
+	}
+
+	encoded := make([][]byte, len(block.Withdrawals))
+	for i := range block.Withdrawals {
+		encoded[i] = EncodeWithdrawal(&block.Withdrawals[i])
+	}
+
+	if computed := trie.ListRoot(encoded); !bytes.Equal(computed, block.WithdrawalsRoot.Bytes()) {
+		return fmt.Errorf(
+			"%w, block=%s, expected=%s, computed=0x%x",
+			ErrWithdrawalsRootMismatch, block.Number, block.WithdrawalsRoot, computed,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package verify_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/crypto"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/testchain"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/trie"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/verify"
+)
+
+const (
+	zeroHash   = types.Hash("0x0000000000000000000000000000000000000000000000000000000000000000")
+	emptyRoot  = types.Hash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+	emptyUncle = types.Hash("0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347")
+)
+
+var emptyBloom = types.Data("0x" + strings.Repeat("00", 256))
+
+// mainnetBlocks are headers of Ethereum mainnet as returned by eth_getBlockByNumber
+var mainnetBlocks = []types.Block{
+	{
+		Hash:             "0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3",
+		ParentHash:       zeroHash,
+		Sha3Uncles:       emptyUncle,
+		Miner:            "0x0000000000000000000000000000000000000000",
+		StateRoot:        "0xd7f8974fb5ac78d9ac099b9ad5018bedc2ce0a72dad1827a1709da30580f0544",
+		TransactionsRoot: emptyRoot,
+		ReceiptsRoot:     emptyRoot,
+		LogsBloom:        emptyBloom,
+		Difficulty:       "0x400000000",
+		Number:           "0x0",
+		GasLimit:         "0x1388",
+		GasUsed:          "0x0",
+		Timestamp:        "0x0",
+		ExtraData:        "0x11bbe8db4e347b4e8c937c1c8370e4b5ed33adb3db69cbdb7a38e1e50b1b82fa",
+		MixHash:          zeroHash,
+		Nonce:            "0x0000000000000042",
+	},
+	{
+		Hash:             "0x88e96d4537bea4d9c05d12549907b32561d3bf31f45aae734cdc119f13406cb6",
+		ParentHash:       "0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3",
+		Sha3Uncles:       emptyUncle,
+		Miner:            "0x05a56e2d52c817161883f50c441c3228cfe54d9f",
+		StateRoot:        "0xd67e4d450343046425ae4271474353857ab860dbc0a1dde64b41b5cd3a532bf3",
+		TransactionsRoot: emptyRoot,
+		ReceiptsRoot:     emptyRoot,
+		LogsBloom:        emptyBloom,
+		Difficulty:       "0x3ff800000",
+		Number:           "0x1",
+		GasLimit:         "0x1388",
+		GasUsed:          "0x0",
+		Timestamp:        "0x55ba4224",
+		ExtraData:        "0x476574682f76312e302e302f6c696e75782f676f312e342e32",
+		MixHash:          "0x969b900de27b6ac6a67742365dd65f55a0526c41fd18e1b16f1a1215c2e66f59",
+		Nonce:            "0x539bd4979fef1ec4",
+	},
+}
+
+// mainnetTransactions are legacy transactions of Ethereum mainnet as returned by eth_getTransactionByHash
+var mainnetTransactions = []types.Transaction{
+	// the first transaction of mainnet, which is the only one in block 46147
+	{
+		Hash:     "0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060",
+		Type:     "0x0",
+		Nonce:    "0x0",
+		GasPrice: "0x2d79883d2000",
+		Gas:      "0x5208",
+		To:       "0x5df9b87991262f6ba471f09758cde1c0fc1de734",
+		Value:    "0x7a69",
+		Input:    "0x",
+		V:        "0x1c",
+		R:        "0x88ff6cf0fefd94db46111149ae4bfc179e9b94721fffd821d38d16464b3f71d0",
+		S:        "0x45e0aff800961cfce805daef7016b9b675c137a6a41a548f7b60a3484c06a33a",
+	},
+	// replay protected by EIP-155 in block 6139707
+	{
+		Hash:     "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b",
+		Type:     "0x0",
+		Nonce:    "0x15",
+		GasPrice: "0x4a817c800",
+		Gas:      "0xc350",
+		To:       "0xf02c1c8e6114b1dbe8937a39260b5b0a374432bb",
+		Value:    "0xf3dbb76162000",
+		Input:    "0x68656c6c6f21",
+		V:        "0x25",
+		R:        "0x1b5e176d927f8e9ab405058b2d2457392da3e20f328b16ddabcebc33eaac5fea",
+		S:        "0x4ba69724e8f69de52f0125ad8b3c5c2cef33019bac3249e2c0a2192766d1721c",
+	},
+}
+
+// transactionsRootOfBlock46147 is the transactions root of mainnet block 46147 with the first transaction
+const transactionsRootOfBlock46147 = "0x4513310fcb9f6f616972a3b948dc5d547f280849a87ebb5af0191f98b87be598"
+
+// minedBlock returns a copy of block of the fake node which has a transfer, its transactions can be modified
+func minedBlock(t *testing.T) *types.Block {
+	t.Helper()
+
+	chain := testchain.NewChain(testchain.Config{TxsPerBlock: -1, WithdrawalsPerBlock: -1})
+	alice, bob := chain.Accounts()[0], chain.Accounts()[1]
+
+	if _, err := chain.Send(alice, bob, big.NewInt(1), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	block := *chain.Mine()
+	block.Transactions = append([]types.Transaction(nil), block.Transactions...)
+
+	return &block
+}
+
+func TestVerifyBlocksOfMainnet(t *testing.T) {
+	verifier := verify.New()
+
+	for _, block := range mainnetBlocks {
+		if err := verifier.VerifyBlock(&block); err != nil {
+			t.Errorf("failed to verify block %s: %v", block.Number, err)
+		}
+	}
+}
+
+func TestEncodeTransactionsOfMainnet(t *testing.T) {
+	for _, tx := range mainnetTransactions {
+		envelope, err := verify.EncodeTransaction(&tx)
+		if err != nil {
+			t.Fatalf("failed to encode transaction %s: %v", tx.Hash, err)
+		}
+
+		if computed := crypto.Keccak256(envelope); !bytes.Equal(computed, tx.Hash.Bytes()) {
+			t.Errorf("expected hash %s, but got 0x%x", tx.Hash, computed)
+		}
+	}
+
+	envelope, err := verify.EncodeTransaction(&mainnetTransactions[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := trie.ListRoot([][]byte{envelope})
+	if expected := types.Hash(transactionsRootOfBlock46147); !bytes.Equal(root, expected.Bytes()) {
+		t.Fatalf("expected transactions root %s, but got 0x%x", expected, root)
+	}
+}
+
+func TestVerifyTamperedBlock(t *testing.T) {
+	verifier := verify.New()
+
+	header := mainnetBlocks[1]
+	header.Timestamp = "0x55ba4225"
+	if err := verifier.VerifyBlock(&header); !errors.Is(err, verify.ErrBlockHashMismatch) {
+		t.Fatalf("expected %v, but got %v", verify.ErrBlockHashMismatch, err)
+	}
+
+	block := minedBlock(t)
+	block.Transactions[0].Value = "0x2"
+	if err := verifier.VerifyBlock(block); !errors.Is(err, verify.ErrTransactionHashMismatch) {
+		t.Fatalf("expected %v, but got %v", verify.ErrTransactionHashMismatch, err)
+	}
+}
+
+// withTransaction returns a mined block whose first transaction is the one of given type which can't be encoded
+func withTransaction(t *testing.T, txType types.Quantity) *types.Block {
+	t.Helper()
+
+	block := minedBlock(t)
+	tx := types.Transaction{
+		Hash: types.Hash("0x" + strings.Repeat("7e", 32)),
+		Type: txType,
+		From: "0xdeaddeaddeaddeaddeaddeaddeaddeaddead0001",
+		To:   "0x4200000000000000000000000000000000000015",
+	}
+	block.Transactions = append([]types.Transaction{tx}, block.Transactions...)
+
+	return block
+}
+
+func TestVerifyBlockWithUnsupportedTransactions(t *testing.T) {
+	verifier := verify.New(verify.WithSkippedTxTypes(0x7e))
+
+	// unknown types are never skipped, otherwise a provider could hide any body behind one
+	for _, txType := range []types.Quantity{"0x7f", "0x5", "0x1000000000000007e"} {
+		if err := verifier.VerifyBlock(withTransaction(t, txType)); !errors.Is(err, verify.ErrUnsupportedTxType) {
+			t.Errorf("expected %v for type %s, but got %v", verify.ErrUnsupportedTxType, txType, err)
+		}
+	}
+
+	// skipped types must be configured for the chain
+	if err := verify.New().VerifyBlock(withTransaction(t, "0x7e")); !errors.Is(err, verify.ErrUnsupportedTxType) {
+		t.Fatalf("expected %v without skipped types, but got %v", verify.ErrUnsupportedTxType, err)
+	}
+}
+
+func TestVerifyBlockWithSkippedTransactions(t *testing.T) {
+	logs := &bytes.Buffer{}
+	verifier := verify.New(verify.WithLogger(log.New(logs, "", 0)), verify.WithSkippedTxTypes(0x7e))
+
+	// deposit transaction of OP Stack chains can't be encoded
+	block := withTransaction(t, "0x7e")
+	deposit := block.Transactions[0]
+
+	if err := verifier.VerifyBlock(block); err != nil {
+		t.Fatalf("expected block with deposit transaction not to fail, but got %v", err)
+	}
+
+	if !strings.Contains(logs.String(), "unverifiable") || !strings.Contains(logs.String(), string(deposit.Hash)) {
+		t.Fatalf("expected deposit transaction to be reported as unverifiable, but got logs %q", logs.String())
+	}
+
+	// other transactions are still verified
+	block.Transactions[1].Value = "0x2"
+	if err := verifier.VerifyBlock(block); !errors.Is(err, verify.ErrTransactionHashMismatch) {
+		t.Fatalf("expected %v, but got %v", verify.ErrTransactionHashMismatch, err)
+	}
+}
+
+func TestVerifyBlockOfNode(t *testing.T) {
+	logs := &bytes.Buffer{}
+	verifier := verify.New(verify.WithLogger(log.New(logs, "", 0)))
+
+	if err := verifier.VerifyBlock(minedBlock(t)); err != nil || logs.Len() != 0 {
+		t.Fatalf("expected block to be verified without report, but got err=%v, logs=%q", err, logs.String())
+	}
+}
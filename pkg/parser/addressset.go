@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	// DefaultAddressSetCapacity is the initial capacity of bloom filter, it's doubled when exceeded
+	DefaultAddressSetCapacity = 1 << 16
+	// AddressSetFalsePositiveRate is the rate of lookups passing bloom filter for unknown addresses
+	AddressSetFalsePositiveRate = 0.01
+)
+
+// AddressSet is a set of subscribed addresses optimized for lookups of unknown addresses
+// Most addresses in blocks are not subscribed, so a bloom filter rejects them before the map lookup
+type AddressSet struct {
+	bloom     atomic.Pointer[bloomFilter]
+	addresses *sync.Map
+	size      atomic.Int64
+	capacity  int
+
+	// serializes additions and rebuilds of bloom filter, lookups don't lock
+	mu sync.Mutex
+}
+
+// NewAddressSet creates a set whose bloom filter is sized for capacity addresses
+func NewAddressSet(capacity int) *AddressSet {
+	capacity = max(capacity, 1)
+
+	s := &AddressSet{
+		addresses: &sync.Map{},
+		capacity:  capacity,
+	}
+	s.bloom.Store(newBloomFilter(capacity, AddressSetFalsePositiveRate))
+
+	return s
+}
+
+// Add adds an address and returns true if it's new
+func (s *AddressSet) Add(address types.Address) bool {
+	key := address.Lower()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.addresses.Load(key); ok {
+		return false
+	}
+
+	// add to bloom first so that lookups never miss an address in map
+	s.bloom.Load().add(string(key))
+	s.addresses.Store(key, true)
+
+	if int(s.size.Add(1)) > s.capacity {
+		s.grow()
+	}
+
+	return true
+}
+
+// Remove removes an address and returns true if it existed
+// Bits of bloom filter are kept, which only causes false positives until it's rebuilt
+func (s *AddressSet) Remove(address types.Address) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.addresses.LoadAndDelete(address.Lower()); !ok {
+		return false
+	}
+
+	s.size.Add(-1)
+
+	return true
+}
+
+// AddMany adds addresses and returns the number of new ones
+func (s *AddressSet) AddMany(addresses []types.Address) int {
+	added := 0
+	for _, address := range addresses {
+		if s.Add(address) {
+			added++
+		}
+	}
+
+	return added
+}
+
+// Contains returns true if the address has been added
+func (s *AddressSet) Contains(address types.Address) bool {
+	if address.IsEmpty() {
+		return false
+	}
+
+	// bloom filter is case-insensitive, lowercase only addresses passing it
+	if !s.bloom.Load().mayContain(string(address)) {
+		return false
+	}
+
+	_, ok := s.addresses.Load(address.Lower())
+
+	return ok
+}
+
+// Len returns the number of addresses
+func (s *AddressSet) Len() int {
+	return int(s.size.Load())
+}
+
+// grow rebuilds bloom filter with doubled capacity to keep false positive rate
+// It must be called with lock
+func (s *AddressSet) grow() {
+	s.capacity *= 2
+
+	bloom := newBloomFilter(s.capacity, AddressSetFalsePositiveRate)
+	s.addresses.Range(func(key, _ any) bool {
+		bloom.add(string(key.(types.Address)))
+
+		return true
+	})
+
+	s.bloom.Store(bloom)
+}
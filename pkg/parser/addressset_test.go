@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	benchSubscribedAddresses = 500_000
+	benchTransactions        = 100_000
+	// ratio of transactions involving subscribed addresses
+	benchHitRate = 0.001
+)
+
+// upperHex returns the address whose hex letters are in upper case
+func upperHex(address types.Address) types.Address {
+	return types.Address("0x" + strings.ToUpper(string(address[2:])))
+}
+
+func TestAddressSetHasNoFalseNegatives(t *testing.T) {
+	// small capacity makes the bloom filter rebuilt many times
+	set := NewAddressSet(16)
+
+	addresses := randomAddresses(20_000)
+	// vanity addresses share long prefix
+	for i := 0; i < 1_000; i++ {
+		addresses = append(addresses, types.Address(fmt.Sprintf("0x%036x%04x", 0, i)))
+	}
+
+	for i, address := range addresses {
+		// subscriptions may be given in upper case
+		if i%2 == 1 {
+			address = upperHex(address)
+		}
+
+		if !set.Add(address) {
+			t.Fatalf("expected %s to be new", address)
+		}
+	}
+
+	if set.Len() != len(addresses) {
+		t.Fatalf("expected %d addresses, but got %d", len(addresses), set.Len())
+	}
+
+	for _, address := range addresses {
+		if !set.Contains(address) || !set.Contains(upperHex(address)) {
+			t.Fatalf("expected %s to be contained in any case", address)
+		}
+
+		if !set.bloom.Load().mayContain(string(address)) {
+			t.Fatalf("expected bloom filter to pass %s", address)
+		}
+	}
+
+	// removed addresses may pass bloom filter, but are not contained
+	for _, address := range addresses[:len(addresses)/2] {
+		set.Remove(address)
+	}
+
+	for i, address := range addresses {
+		if contained := set.Contains(address); contained != (i >= len(addresses)/2) {
+			t.Fatalf("expected Contains(%s) to be %t after removal", address, !contained)
+		}
+	}
+}
+
+func TestAddressSetFalsePositiveRate(t *testing.T) {
+	const num = 10_000
+
+	set := NewAddressSet(num)
+	set.AddMany(randomAddresses(num))
+
+	passed := 0
+	for _, address := range randomAddresses(10 * num) {
+		if set.bloom.Load().mayContain(string(address)) {
+			passed++
+		}
+	}
+
+	// the rate has some margin as addresses are random
+	if rate := float64(passed) / (10 * num); rate > 3*AddressSetFalsePositiveRate {
+		t.Fatalf("expected false positive rate around %v, but got %v", AddressSetFalsePositiveRate, rate)
+	}
+}
+
+func TestAddressSetIsReadWhileGrowing(t *testing.T) {
+	set := NewAddressSet(1)
+	addresses := randomAddresses(5_000)
+
+	// added is the number of addresses whose Add has returned
+	var added atomic.Int64
+	go func() {
+		for _, address := range addresses {
+			set.Add(address)
+			added.Add(1)
+		}
+	}()
+
+	for int(added.Load()) < len(addresses) {
+		n := int(added.Load())
+		for _, address := range addresses[:n] {
+			if !set.Contains(address) {
+				t.Fatalf("expected %s to be contained while the bloom filter grows", address)
+			}
+		}
+	}
+}
+
+// BenchmarkAddressSetMatch compares matching transactions against subscribed addresses by the bloom filtered
+// AddressSet used by parser with a plain sync.Map
+func BenchmarkAddressSetMatch(b *testing.B) {
+	subscribed := randomAddresses(benchSubscribedAddresses)
+
+	set := NewAddressSet(DefaultAddressSetCapacity)
+	set.AddMany(subscribed)
+
+	plain := &sync.Map{}
+	for _, address := range subscribed {
+		plain.Store(address, true)
+	}
+
+	// some transactions are sent to subscribed addresses
+	froms := randomAddresses(benchTransactions)
+	tos := randomAddresses(benchTransactions)
+	hits := int(benchTransactions * benchHitRate)
+	for i := 0; i < hits; i++ {
+		tos[i*(benchTransactions/hits)] = subscribed[i]
+	}
+
+	b.Run("AddressSet", func(b *testing.B) {
+		benchmarkMatch(b, froms, tos, func(from, to types.Address) bool {
+			return set.Contains(from) || set.Contains(to)
+		})
+	})
+
+	b.Run("sync.Map", func(b *testing.B) {
+		benchmarkMatch(b, froms, tos, func(from, to types.Address) bool {
+			_, fromOk := plain.Load(from)
+			_, toOk := plain.Load(to)
+
+			return fromOk || toOk
+		})
+	})
+}
+
+// benchmarkMatch runs match function over transactions and reports the number of matched ones
+func benchmarkMatch(b *testing.B, froms, tos []types.Address, match func(from, to types.Address) bool) {
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	matched := 0
+	for i := 0; i < b.N; i++ {
+		idx := i % len(froms)
+		if match(froms[idx], tos[idx]) {
+			matched++
+		}
+	}
+
+	b.ReportMetric(float64(matched)/float64(b.N), "matched/op")
+}
+
+// randomAddresses generates random lower case addresses
+func randomAddresses(num int) []types.Address {
+	addresses := make([]types.Address, num)
+	buf := make([]byte, 20)
+	for i := range addresses {
+		_, _ = rand.Read(buf)
+		addresses[i] = types.Address("0x" + hex.EncodeToString(buf))
+	}
+
+	return addresses
+}
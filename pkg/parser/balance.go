@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// WithBalanceTracking makes Parser refresh balance and nonce of subscribed addresses touched by processed blocks
+func WithBalanceTracking(client BalanceClient) Option {
+	return func(p *Parser) {
+		p.balanceClient = client
+	}
+}
+
+// GetAccountState returns balance and nonce of an address at or before given height, or the latest one if height is nil
+func (p *Parser) GetAccountState(address types.Address, height *uint64) (*types.AccountState, bool) {
+	return p.storage.GetAccountState(address, height)
+}
+
+// touchedAddresses returns subscribed addresses whose balance may be changed by the block
+// Note that value transfers by internal calls can't be detected without tracing
+func (p *Parser) touchedAddresses(block *types.Block, txs []*types.IndexedTransaction) []types.Address {
+	touched := make(map[types.Address]bool)
+	add := func(address types.Address) {
+		if !address.IsEmpty() && p.isSubscribingTo(address) {
+			touched[address.Lower()] = true
+		}
+	}
+
+	for _, tx := range txs {
+		for _, address := range tx.MatchedAddresses {
+			add(address)
+		}
+	}
+
+	for _, w := range block.Withdrawals {
+		add(w.Address)
+	}
+
+	add(block.Miner)
+
+	// addresses subscribed since last block don't have state yet
+	p.accountsToRefresh.Range(func(key, _ any) bool {
+		touched[key.(types.Address)] = true
+		p.accountsToRefresh.Delete(key)
+
+		return true
+	})
+
+	addresses := make([]types.Address, 0, len(touched))
+	for address := range touched {
+		addresses = append(addresses, address)
+	}
+
+	return addresses
+}
+
+// refreshAccountStates fetches balance and nonce of addresses at the block and saves them
+// Failed addresses are retried at next block because balance tracking must not stop block processing
+func (p *Parser) refreshAccountStates(block *types.Block, addresses []types.Address) {
+	for _, address := range addresses {
+		state, err := p.fetchAccountState(address, block.Number)
+		if err == nil {
+			err = p.storage.InsertAccountState(state)
+		}
+
+		if err != nil {
+			p.logger.Printf("failed to refresh balance, address=%s, height=%d: %v", address, block.Number.Uint64(), err)
+			p.accountsToRefresh.Store(address, true)
+		}
+	}
+}
+
+// fetchAccountState fetches balance and nonce of an address at given height
+func (p *Parser) fetchAccountState(address types.Address, height types.Quantity) (*types.AccountState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultFetchTimeout)
+	defer cancel()
+
+	balance, err := p.balanceClient.GetBalance(ctx, address, height.Big())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch balance: %w", err)
+	}
+
+	nonce, err := p.balanceClient.GetTransactionCount(ctx, address, height.Big())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch nonce: %w", err)
+	}
+
+	return &types.AccountState{
+		Address:     address,
+		BlockNumber: height,
+		Balance:     types.NewQuantity(balance),
+		Nonce:       types.NewQuantity(nonce),
+	}, nil
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// failingBalanceClient fails to fetch balances while failing is true
+type failingBalanceClient struct {
+	BalanceClient
+	failing atomic.Bool
+}
+
+func (c *failingBalanceClient) GetBalance(ctx context.Context, address types.Address, height *big.Int) (*big.Int, error) {
+	if c.failing.Load() {
+		return nil, errors.New("node is unavailable")
+	}
+
+	return c.BalanceClient.GetBalance(ctx, address, height)
+}
+
+func TestFailedBalanceRefreshIsRetriedAtNextBlock(t *testing.T) {
+	p, chain, _ := newTestParser(t, quietChain)
+
+	client := &failingBalanceClient{BalanceClient: p.ethClient.(BalanceClient)}
+	client.failing.Store(true)
+	p.balanceClient = client
+
+	alice := chain.Accounts()[0]
+	p.Subscribe(alice)
+
+	if err := p.Start(big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := chain.Mine()
+	waitForHeight(t, p, failed.Number.Uint64())
+
+	if _, queued := p.accountsToRefresh.Load(alice.Lower()); !queued {
+		t.Fatal("expected alice to be queued after failed refresh")
+	}
+
+	if state, ok := p.GetAccountState(alice, nil); ok {
+		t.Fatalf("expected no balance of alice, but got %+v", state)
+	}
+
+	// the next block doesn't touch alice, but the queued refresh is retried
+	client.failing.Store(false)
+
+	block := chain.Mine()
+	waitForHeight(t, p, block.Number.Uint64())
+
+	state, ok := p.GetAccountState(alice, nil)
+	if !ok || state.BlockNumber.Uint64() != block.Number.Uint64() {
+		t.Fatalf("expected balance of alice at block %d, but got %+v", block.Number.Uint64(), state)
+	}
+
+	if _, queued := p.accountsToRefresh.Load(alice.Lower()); queued {
+		t.Fatal("expected alice not to be queued after successful refresh")
+	}
+
+	// balance before the refreshed block is unknown
+	height := failed.Number.Uint64()
+	if state, ok := p.GetAccountState(alice, &height); ok {
+		t.Fatalf("expected no balance at failed block %d, but got %+v", height, state)
+	}
+}
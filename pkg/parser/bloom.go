@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+// bloomFilter is a lock-free bloom filter of hex strings
+// It may return false positive, but never returns false negative
+// Hex letters are hashed case-insensitively, so lookups don't need to lowercase addresses
+type bloomFilter struct {
+	bits   []atomic.Uint64
+	size   uint64 // number of bits
+	hashes uint64 // number of hash functions
+}
+
+// newBloomFilter creates a filter holding capacity items with falsePositiveRate
+func newBloomFilter(capacity int, falsePositiveRate float64) *bloomFilter {
+	// optimal number of bits and hash functions
+	size := uint64(math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	size = max((size+63)/64*64, 64)
+	hashes := uint64(max(math.Round(float64(size)/float64(capacity)*math.Ln2), 1))
+
+	return &bloomFilter{
+		bits:   make([]atomic.Uint64, size/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// add adds an item to the filter
+func (f *bloomFilter) add(item string) {
+	h1, h2 := f.hash(item)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := f.position(h1 + i*h2)
+		word := &f.bits[bit/64]
+		mask := uint64(1) << (bit % 64)
+
+		for {
+			old := word.Load()
+			if old&mask != 0 || word.CompareAndSwap(old, old|mask) {
+				break
+			}
+		}
+	}
+}
+
+// mayContain returns false if the item has never been added
+func (f *bloomFilter) mayContain(item string) bool {
+	h1, h2 := f.hash(item)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := f.position(h1 + i*h2)
+		if f.bits[bit/64].Load()&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// position maps a hash to a bit without division
+func (f *bloomFilter) position(hash uint64) uint64 {
+	hi, _ := bits.Mul64(hash, f.size)
+
+	return hi
+}
+
+// hash returns two hashes combined to derive positions of bits (Kirsch-Mitzenmacher)
+// Setting 0x20 bit of each byte folds hex letters to lower case and keeps digits as they are
+func (f *bloomFilter) hash(item string) (uint64, uint64) {
+	var h uint64
+	for i := 0; i < len(item); i += 8 {
+		var chunk uint64
+		for j := i; j < i+8 && j < len(item); j++ {
+			chunk = chunk<<8 | uint64(item[j]|0x20)
+		}
+
+		h = mix64(h ^ chunk)
+	}
+
+	return h, mix64(h) | 1
+}
+
+// mix64 is the finalizer of SplitMix64, which spreads bits of vanity addresses sharing prefix
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+
+	return x ^ (x >> 31)
+}
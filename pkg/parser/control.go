@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ErrInvalidHeight is returned by Seek for a height the parser can't process from
+var ErrInvalidHeight = errors.New("invalid height")
+
+// Pause stops fetching new blocks until Resume is called, blocks fetched already are still stored
+// It returns false if the parser is paused already
+func (p *Parser) Pause() bool {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+
+	if p.resumeCh != nil {
+		return false
+	}
+
+	p.resumeCh = make(chan struct{})
+	p.logger.Printf("parser has been paused")
+
+	return true
+}
+
+// Resume restarts fetching blocks paused by Pause, it returns false if the parser is not paused
+func (p *Parser) Resume() bool {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+
+	if p.resumeCh == nil {
+		return false
+	}
+
+	close(p.resumeCh)
+	p.resumeCh = nil
+	p.logger.Printf("parser has been resumed")
+
+	return true
+}
+
+// IsPaused returns true if fetching blocks is paused by Pause
+func (p *Parser) IsPaused() bool {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+
+	return p.resumeCh != nil
+}
+
+// NextHeight returns the height of the block to be processed next, ok is false until the parser starts or seeks
+func (p *Parser) NextHeight() (uint64, bool) {
+	next := p.nextHeight.Load()
+	if next == nil {
+		return 0, false
+	}
+
+	return next.Uint64(), true
+}
+
+// waitForResume blocks while the parser is paused, it returns false if closeCh is closed meanwhile
+func (p *Parser) waitForResume(closeCh <-chan struct{}) bool {
+	p.pauseMu.Lock()
+	resumeCh := p.resumeCh
+	p.pauseMu.Unlock()
+
+	if resumeCh == nil {
+		return true
+	}
+
+	p.logger.Printf("parser is paused, waiting for resume")
+
+	select {
+	case <-resumeCh:
+		return true
+	case <-closeCh:
+		return false
+	}
+}
+
+// Seek makes the parser process blocks from height next, either forward or rewind
+// On rewind, transactions and account states stored for blocks from the height are deleted so that they are
+// ingested again. Blocks fetched already are stored before seeking, and the checkpoint is saved at the block before
+// the height. It returns the number of deleted transactions
+// If the pipeline doesn't stop before ctx is done, the parser is left stopped and fails, so that Supervisor restarts it
+func (p *Parser) Seek(ctx context.Context, height uint64) (int, error) {
+	if height == 0 {
+		return 0, fmt.Errorf("%w: height must be positive", ErrInvalidHeight)
+	}
+
+	p.lifecycleMu.Lock()
+	defer p.lifecycleMu.Unlock()
+
+	if p.isReindexing() {
+		return 0, ErrReindexInProgress
+	}
+
+	// stop the pipeline so that no block is stored while data is deleted
+	running := p.isRunning()
+	if running {
+		r := p.active.Load()
+		r.stop()
+
+		select {
+		case <-ctx.Done():
+			err := fmt.Errorf("failed to stop parser before seeking: %w", ctx.Err())
+			p.logger.Printf("failed to seek, height=%d: %v", height, err)
+			p.health.setState(HealthStateFailed)
+			p.notifyError(err)
+
+			return 0, err
+		case <-r.notifyTerminatedCh:
+		}
+	}
+
+	deleted, err := p.seek(height)
+	if err != nil {
+		p.logger.Printf("failed to seek, height=%d: %v", height, err)
+	} else {
+		p.logger.Printf("next height has been changed, height=%d, deleted transactions=%d", height, deleted)
+	}
+
+	// resume from the height, or from where it was stopped if seeking failed
+	if running {
+		next, _ := p.NextHeight()
+		p.startRun(new(big.Int).SetUint64(next))
+	}
+
+	return deleted, err
+}
+
+// seek moves the checkpoint and deletes data to be ingested again, lifecycleMu must be held and the pipeline stopped
+// The checkpoint is moved first, because transactions ingested again without deletion are merged by storage
+func (p *Parser) seek(height uint64) (int, error) {
+	next, started := p.NextHeight()
+
+	if p.checkpoint != nil {
+		if err := p.checkpoint.Save(height - 1); err != nil {
+			return 0, fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+	}
+
+	p.currentBlockHeight.Store(height - 1)
+	p.nextHeight.Store(new(big.Int).SetUint64(height))
+
+	if !started || height >= next {
+		return 0, nil
+	}
+
+	deleted, err := p.storage.DeleteBlockRange(height, next-1, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete blocks from %d to %d: %w", height, next-1, err)
+	}
+
+	// states are fetched again when the blocks are processed
+	if err := p.storage.DeleteAccountStates(height, next-1); err != nil {
+		return 0, fmt.Errorf("failed to delete account states from %d to %d: %w", height, next-1, err)
+	}
+
+	return deleted, nil
+}
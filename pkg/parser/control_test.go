@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/txstorage"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// blockingStorage blocks inserting transactions from when block is called until release is called
+type blockingStorage struct {
+	*txstorage.InMemoryTransactionStorage
+
+	mu        sync.Mutex
+	releaseCh chan struct{} // nil unless blocking
+	enteredCh chan struct{} // receives when insert starts to block
+}
+
+func (s *blockingStorage) block() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.releaseCh = make(chan struct{})
+}
+
+func (s *blockingStorage) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.releaseCh != nil {
+		close(s.releaseCh)
+		s.releaseCh = nil
+	}
+}
+
+func (s *blockingStorage) InsertTransactions(txs []*types.IndexedTransaction) error {
+	s.mu.Lock()
+	releaseCh := s.releaseCh
+	s.mu.Unlock()
+
+	if releaseCh != nil {
+		s.enteredCh <- struct{}{}
+		<-releaseCh
+	}
+
+	return s.InMemoryTransactionStorage.InsertTransactions(txs)
+}
+
+func TestSeekTimesOutWhileStoringBlock(t *testing.T) {
+	storage := &blockingStorage{InMemoryTransactionStorage: txstorage.New(), enteredCh: make(chan struct{}, 1)}
+	p, node := newTestParserWithStorage(t, quietChain, storage)
+
+	// released before the parser is stopped at the end of the test
+	t.Cleanup(storage.release)
+
+	storage.block()
+
+	if err := p.Start(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	node.Chain().Mine()
+
+	select {
+	case <-storage.enteredCh:
+	case <-time.After(testWaitTimeout):
+		t.Fatal("timed out waiting for parser to store block")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Seek gives up waiting for the stuck pipeline instead of blocking Start and Stop
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Seek(ctx, 1)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected %v, but got %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(testWaitTimeout):
+		t.Fatal("Seek is blocked by stuck pipeline")
+	}
+
+	if health := p.Health(); health.State != HealthStateFailed {
+		t.Fatalf("expected failed parser after timeout of seek, but got %+v", health)
+	}
+
+	select {
+	case err := <-p.ErrCh():
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected error of seek, but got %v", err)
+		}
+	default:
+		t.Fatal("expected error of seek to be notified")
+	}
+
+	// the pipeline finishes once storage recovers
+	storage.release()
+
+	stopParser(t, p)
+}
@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/rpcfixture"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/txstorage"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// newReplayParser creates a parser of recorded responses in the fixture of testdata/rpc
+// It processes the block at height subscribing to address, and is stopped when the test finishes
+func newReplayParser(t *testing.T, fixture string, height uint64, address types.Address) (*Parser, *txstorage.InMemoryTransactionStorage) {
+	t.Helper()
+
+	replayer, err := rpcfixture.LoadReplayer(filepath.Join("..", "..", "testdata", "rpc", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := txstorage.New()
+	p := New(jsonrpc.New(&http.Client{Transport: replayer}, "http://replay.invalid"), storage,
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithPollingInterval(testPollingInterval),
+	)
+
+	p.Subscribe(address)
+
+	if err := p.Start(new(big.Int).SetUint64(height)); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		stopParser(t, p)
+	})
+
+	waitForHeight(t, p, height)
+
+	return p, storage
+}
+
+// expectTypes checks types of transactions of the address in block order
+func expectTypes(t *testing.T, p *Parser, address types.Address, expected ...types.Quantity) []types.Transaction {
+	t.Helper()
+
+	txs := p.GetTransactions(address)
+	if len(txs) != len(expected) {
+		t.Fatalf("expected %d transactions of %s, but got %+v", len(expected), address, txs)
+	}
+
+	for i, tx := range txs {
+		if tx.Type != expected[i] {
+			t.Fatalf("expected type %s of transaction %s, but got %s", expected[i], tx.Hash, tx.Type)
+		}
+	}
+
+	return txs
+}
+
+func TestParserStoresContractCreation(t *testing.T) {
+	for _, tt := range []struct {
+		fixture string
+		height  uint64
+		creator types.Address
+		types   []types.Quantity
+	}{
+		// recipient is null
+		{"mainnet/null-fields.ndjson", 16_000_000, "0x3333333333333333333333333333333333333333", []types.Quantity{"0x2"}},
+		// recipient is missing, the creator also sends a set code transaction to itself
+		{"mainnet/contract-creation.ndjson", 22_500_000, "0x5555555555555555555555555555555555555555", []types.Quantity{"0x2", "0x4"}},
+	} {
+		t.Run(tt.fixture, func(t *testing.T) {
+			p, storage := newReplayParser(t, tt.fixture, tt.height, tt.creator)
+
+			creation := expectTypes(t, p, tt.creator, tt.types...)[0]
+			if !creation.To.IsEmpty() {
+				t.Fatalf("expected contract creation without recipient, but got %+v", creation)
+			}
+
+			indexed, ok := storage.GetTransactionByHash(creation.Hash)
+			if !ok || len(indexed.MatchedAddresses) != 1 || !indexed.MatchedAddresses[0].Equal(tt.creator) {
+				t.Fatalf("expected %s matched only to the creator, but got %+v", creation.Hash, indexed)
+			}
+
+			analytics := p.GetAddressAnalytics(tt.creator, tt.height, tt.height, 0)
+			if analytics.TxCount != len(tt.types) || analytics.OutboundCount != len(tt.types) || len(analytics.Counterparties) != 0 {
+				t.Fatalf("expected only outbound transactions without counterparty, but got %+v", analytics)
+			}
+		})
+	}
+}
+
+func TestParserStoresDepositTransactionsOfOptimism(t *testing.T) {
+	user := types.Address("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	p, _ := newReplayParser(t, "optimism/deposit.ndjson", 130_000_000, user)
+
+	// the user receives a deposit from L1 and sends a transfer
+	expectTypes(t, p, user, "0x7e", "0x2")
+
+	analytics := p.GetAddressAnalytics(user, 0, 130_000_000, 0)
+	if analytics.InboundCount != 1 || analytics.OutboundCount != 1 || analytics.TotalIn.Uint64() != 0x2386f26fc10000 {
+		t.Fatalf("expected a deposit and a transfer, but got %+v", analytics)
+	}
+
+	header, ok := p.GetBlockHeader(130_000_000)
+	if !ok || header.BaseFeePerGas != "0x413" || !header.Miner.Equal("0x4200000000000000000000000000000000000011") {
+		t.Fatalf("expected header of the block, but got %+v", header)
+	}
+}
+
+func TestParserStoresTransactionsOfArbitrum(t *testing.T) {
+	user := types.Address("0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee")
+	p, _ := newReplayParser(t, "arbitrum/internal-tx.ndjson", 280_000_000, user)
+
+	// internal transaction of ArbOS is not associated with the user
+	expectTypes(t, p, user, "0x64", "0x2")
+}
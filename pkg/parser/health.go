@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"sync"
+	"time"
+)
+
+// HealthState is the state of block ingestion
+type HealthState string
+
+const (
+	// HealthStateHealthy means blocks are fetched, possibly with retries
+	HealthStateHealthy HealthState = "healthy"
+	// HealthStateDegraded means the circuit breaker is open and the parser is waiting for the node to recover
+	HealthStateDegraded HealthState = "degraded"
+	// HealthStateFailed means the parser has been terminated by an unrecoverable error
+	HealthStateFailed HealthState = "failed"
+)
+
+// Health is a snapshot of the parser state
+type Health struct {
+	State HealthState `json:"state"`
+	// CurrentHeight is the last processed block
+	CurrentHeight       uint64     `json:"currentHeight"`
+	LastBlockAt         *time.Time `json:"lastBlockAt,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastErrorAt         *time.Time `json:"lastErrorAt,omitempty"`
+	// Paused is true while fetching blocks is paused by admin
+	Paused bool `json:"paused"`
+}
+
+// healthTracker records results of block fetching
+type healthTracker struct {
+	now func() time.Time
+
+	mu                  sync.Mutex
+	state               HealthState
+	lastBlockAt         time.Time
+	consecutiveFailures int
+	lastError           error
+	lastErrorAt         time.Time
+}
+
+func newHealthTracker() *healthTracker {
+	return &healthTracker{
+		now:   time.Now,
+		state: HealthStateHealthy,
+	}
+}
+
+// success records that the node has responded
+func (h *healthTracker) success() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.consecutiveFailures = 0
+}
+
+// blockFetched records that a new block has been fetched
+func (h *healthTracker) blockFetched() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastBlockAt = h.now()
+}
+
+// failure records an error of block fetching
+func (h *healthTracker) failure(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.consecutiveFailures++
+	h.lastError = err
+	h.lastErrorAt = h.now()
+}
+
+// setState changes the state and returns true if it's changed
+func (h *healthTracker) setState(state HealthState) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	changed := h.state != state
+	h.state = state
+
+	return changed
+}
+
+// snapshot returns current health
+func (h *healthTracker) snapshot(currentHeight uint64) Health {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	health := Health{
+		State:               h.state,
+		CurrentHeight:       currentHeight,
+		ConsecutiveFailures: h.consecutiveFailures,
+	}
+
+	if !h.lastBlockAt.IsZero() {
+		lastBlockAt := h.lastBlockAt
+		health.LastBlockAt = &lastBlockAt
+	}
+
+	if h.lastError != nil {
+		lastErrorAt := h.lastErrorAt
+		health.LastError = h.lastError.Error()
+		health.LastErrorAt = &lastErrorAt
+	}
+
+	return health
+}
+
+// Health returns the state of block ingestion
+func (p *Parser) Health() Health {
+	health := p.health.snapshot(p.currentBlockHeight.Load())
+	health.Paused = p.IsPaused()
+
+	return health
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthTracker(t *testing.T) {
+	clock := newFakeClock()
+	h := newHealthTracker()
+	h.now = clock.Now
+
+	if health := h.snapshot(0); health.State != HealthStateHealthy || health.LastBlockAt != nil || health.LastErrorAt != nil {
+		t.Fatalf("expected healthy state without timestamps, but got %+v", health)
+	}
+
+	fetchedAt := clock.Now()
+	h.blockFetched()
+
+	clock.Advance(time.Minute)
+	h.failure(errors.New("first"))
+
+	clock.Advance(time.Minute)
+	failedAt := clock.Now()
+	h.failure(errors.New("second"))
+
+	if !h.setState(HealthStateDegraded) || h.setState(HealthStateDegraded) {
+		t.Fatal("expected state to be changed only once")
+	}
+
+	health := h.snapshot(10)
+	if health.State != HealthStateDegraded || health.CurrentHeight != 10 || health.ConsecutiveFailures != 2 {
+		t.Fatalf("expected degraded state with 2 failures at height 10, but got %+v", health)
+	}
+
+	if !health.LastBlockAt.Equal(fetchedAt) || !health.LastErrorAt.Equal(failedAt) || health.LastError != "second" {
+		t.Fatalf("expected block at %s and the second error at %s, but got %+v", fetchedAt, failedAt, health)
+	}
+
+	// a response of the node resets failures, but the last error is kept
+	clock.Advance(time.Minute)
+	h.success()
+	h.setState(HealthStateHealthy)
+
+	health = h.snapshot(10)
+	if health.State != HealthStateHealthy || health.ConsecutiveFailures != 0 || health.LastError != "second" || !health.LastErrorAt.Equal(failedAt) {
+		t.Fatalf("expected healthy state keeping the last error, but got %+v", health)
+	}
+
+	// snapshot doesn't share timestamps with the tracker
+	*health.LastBlockAt = time.Time{}
+	if h.snapshot(10).LastBlockAt.IsZero() {
+		t.Fatal("expected snapshot to copy timestamps")
+	}
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"context"
 	"math/big"
+	"time"
 
 	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
 )
@@ -12,7 +13,54 @@ type EthClient interface {
 	GetBlockByNumber(context.Context, big.Int, bool) (*types.Block, error)
 }
 
+type BalanceClient interface {
+	GetBalance(ctx context.Context, address types.Address, height *big.Int) (*big.Int, error)
+	GetTransactionCount(ctx context.Context, address types.Address, height *big.Int) (*big.Int, error)
+}
+
+type MempoolClient interface {
+	NewPendingTransactionFilter(ctx context.Context) (string, error)
+	GetPendingTransactionFilterChanges(ctx context.Context, filterId string) ([]types.Hash, error)
+	GetTransactionByHash(ctx context.Context, hash types.Hash) (*types.Transaction, error)
+}
+
+type BlockVerifier interface {
+	VerifyBlock(*types.Block) error
+}
+
+type CheckpointStore interface {
+	// Load returns the height of last processed block, ok is false if nothing has been saved
+	Load() (height uint64, ok bool, err error)
+	Save(height uint64) error
+}
+
 type EthTransactionStorage interface {
-	InsertTransactions([]*types.Transaction) error
-	GetTransactionsByAddress(string) []types.Transaction
+	InsertTransactions([]*types.IndexedTransaction) error
+	GetTransactionByHash(types.Hash) (*types.IndexedTransaction, bool)
+	GetTransactionsByBlock(height uint64) []types.IndexedTransaction
+	GetTransactionsByAddress(types.Address) []types.Transaction
+	IterateTransactionsByAddress(types.Address, func(*types.Transaction) error) error
+	GetPendingTransactionsByAddress(types.Address) []types.PendingTransaction
+	InsertAccountState(*types.AccountState) error
+	GetAccountState(address types.Address, height *uint64) (*types.AccountState, bool)
+	InsertBlockHeader(*types.BlockHeader) error
+	GetBlockHeader(height uint64) (*types.BlockHeader, bool)
+	// GetTransactionsByTimeRange returns transactions of the address in blocks whose timestamp is in [from, to)
+	GetTransactionsByTimeRange(address types.Address, from, to time.Time) []types.Transaction
+	// GetBlockRangeByTime returns the first and last blocks having transactions whose timestamp is in [since, until)
+	GetBlockRangeByTime(since, until time.Time) (from uint64, to uint64, ok bool)
+	// GetAddressAnalytics aggregates transactions of the address in blocks from..to inclusive
+	GetAddressAnalytics(address types.Address, from, to uint64, maxCounterparties int) *types.AddressAnalytics
+	// DeleteBlockRange deletes data in blocks from..to inclusive, only for the address if given
+	DeleteBlockRange(from, to uint64, address *types.Address) (int, error)
+	// DeleteAccountStates deletes states of all accounts at blocks from..to inclusive
+	DeleteAccountStates(from, to uint64) error
+}
+
+type PendingTransactionStorage interface {
+	InsertPendingTransaction(*types.Transaction) error
+	GetPendingTransactionHashes(seenBefore time.Time) []types.Hash
+	MarkPendingTransactionDropped(types.Hash) error
+	ConfirmPendingTransaction(*types.Transaction) error
+	PrunePendingTransactions(seenBefore time.Time) int
 }
@@ -0,0 +1,248 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+const (
+	DefaultMempoolPollingInterval = 2 * time.Second
+	// pending transactions older than this are checked whether they still exist in mempool
+	DefaultDroppedCheckAge = 5 * time.Minute
+	// dropped and replaced transactions are removed after this period
+	DefaultPendingRetention = 1 * time.Hour
+	// maximum number of transactions fetched in parallel
+	mempoolFetchConcurrency = 8
+)
+
+// MempoolWatcher polls pending transactions and stores the ones involving addresses subscribed to Parser
+// Stored transactions are promoted to confirmed by Parser when they are included in a block
+type MempoolWatcher struct {
+	client  MempoolClient
+	parser  *Parser
+	storage PendingTransactionStorage
+	logger  *log.Logger
+
+	pollingInterval time.Duration
+	droppedCheckAge time.Duration
+	retention       time.Duration
+
+	notifyCloseCh      chan struct{}
+	notifyTerminatedCh chan struct{}
+	stopOnce           sync.Once
+}
+
+func NewMempoolWatcher(
+	client MempoolClient,
+	parser *Parser,
+	storage PendingTransactionStorage,
+) *MempoolWatcher {
+	return &MempoolWatcher{
+		client:  client,
+		parser:  parser,
+		storage: storage,
+		logger:  parser.logger,
+
+		pollingInterval: DefaultMempoolPollingInterval,
+		droppedCheckAge: DefaultDroppedCheckAge,
+		retention:       DefaultPendingRetention,
+
+		notifyCloseCh:      make(chan struct{}),
+		notifyTerminatedCh: make(chan struct{}),
+	}
+}
+
+// Start starts polling in background
+func (w *MempoolWatcher) Start() {
+	go w.run()
+}
+
+// Stop terminates polling, it can be called more than once
+func (w *MempoolWatcher) Stop(ctx context.Context) error {
+	w.stopOnce.Do(func() {
+		close(w.notifyCloseCh)
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.notifyTerminatedCh:
+		return nil
+	}
+}
+
+// run polls filter changes until Stop is called
+// Errors are logged and never stop the watcher because mempool tracking is best effort
+func (w *MempoolWatcher) run() {
+	defer func() {
+		w.logger.Printf("mempool watcher has been finished")
+		close(w.notifyTerminatedCh)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		<-w.notifyCloseCh
+		cancel()
+	}()
+
+	filterId := ""
+	lastCheckedAt := time.Now()
+
+	for {
+		select {
+		case <-w.notifyCloseCh:
+			return
+		case <-time.After(w.pollingInterval):
+		}
+
+		// filter is created again when it's expired in the server
+		if filterId == "" {
+			id, err := w.newFilter(ctx)
+			if err != nil {
+				w.logger.Printf("failed to create pending transaction filter: %v", err)
+				continue
+			}
+
+			filterId = id
+		}
+
+		if err := w.poll(ctx, filterId); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+
+			w.logger.Printf("failed to poll pending transactions, filter will be recreated: %v", err)
+			filterId = ""
+		}
+
+		if time.Since(lastCheckedAt) >= w.droppedCheckAge {
+			w.checkPending(ctx)
+			w.storage.PrunePendingTransactions(time.Now().Add(-w.retention))
+			lastCheckedAt = time.Now()
+		}
+	}
+}
+
+// newFilter creates a pending transaction filter
+func (w *MempoolWatcher) newFilter(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultFetchTimeout)
+	defer cancel()
+
+	return w.client.NewPendingTransactionFilter(ctx)
+}
+
+// poll fetches hashes of new pending transactions and stores concerned ones
+func (w *MempoolWatcher) poll(ctx context.Context, filterId string) error {
+	pollCtx, cancel := context.WithTimeout(ctx, DefaultFetchTimeout)
+	defer cancel()
+
+	hashes, err := w.client.GetPendingTransactionFilterChanges(pollCtx, filterId)
+	if err != nil {
+		return err
+	}
+
+	txs := w.fetchTransactions(ctx, hashes)
+	for _, tx := range txs {
+		// included already before we fetch it
+		if !tx.BlockNumber.IsEmpty() {
+			continue
+		}
+
+		if !w.parser.isSubscribingTo(tx.From) && !w.parser.isSubscribingTo(tx.To) {
+			continue
+		}
+
+		w.logger.Printf("found a concerned pending transaction, hash=%s, from=%s, to=%s", tx.Hash, tx.From, tx.To)
+
+		if err := w.storage.InsertPendingTransaction(tx); err != nil {
+			return fmt.Errorf("failed to save pending transaction: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// checkPending marks pending transactions which the server doesn't know anymore as dropped
+// and resolves the ones included in blocks processed by Parser without being stored
+func (w *MempoolWatcher) checkPending(ctx context.Context) {
+	for _, hash := range w.storage.GetPendingTransactionHashes(time.Now().Add(-w.droppedCheckAge)) {
+		fetchCtx, cancel := context.WithTimeout(ctx, DefaultFetchTimeout)
+		tx, err := w.client.GetTransactionByHash(fetchCtx, hash)
+		cancel()
+
+		if err != nil {
+			w.logger.Printf("failed to check pending transaction, hash=%s: %v", hash, err)
+			continue
+		}
+
+		if tx == nil {
+			w.logger.Printf("pending transaction has been dropped, hash=%s", hash)
+
+			if err := w.storage.MarkPendingTransactionDropped(hash); err != nil {
+				w.logger.Printf("failed to mark transaction as dropped: %v", err)
+			}
+
+			continue
+		}
+
+		// the block will be stored by Parser if the transaction is still concerned
+		if tx.BlockNumber.IsEmpty() || tx.BlockNumber.Uint64() > uint64(w.parser.GetCurrentBlock()) {
+			continue
+		}
+
+		w.logger.Printf("pending transaction has been included, hash=%s, block=%d", hash, tx.BlockNumber.Uint64())
+
+		if err := w.storage.ConfirmPendingTransaction(tx); err != nil {
+			w.logger.Printf("failed to confirm pending transaction: %v", err)
+		}
+	}
+}
+
+// fetchTransactions fetches transactions by hashes in parallel, failed ones are skipped
+func (w *MempoolWatcher) fetchTransactions(ctx context.Context, hashes []types.Hash) []*types.Transaction {
+	var (
+		txs   = make([]*types.Transaction, 0, len(hashes))
+		mutex sync.Mutex
+		wg    sync.WaitGroup
+	)
+
+	semaphore := make(chan struct{}, mempoolFetchConcurrency)
+	for _, hash := range hashes {
+		hash := hash
+
+		semaphore <- struct{}{}
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			fetchCtx, cancel := context.WithTimeout(ctx, DefaultFetchTimeout)
+			defer cancel()
+
+			tx, err := w.client.GetTransactionByHash(fetchCtx, hash)
+			if err != nil || tx == nil {
+				// transaction is not available anymore or temporary error, it's fine to miss in mempool
+				return
+			}
+
+			mutex.Lock()
+			txs = append(txs, tx)
+			mutex.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	return txs
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"context"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/testchain"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/txstorage"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+func TestMempoolWatcherCanBeStoppedTwice(t *testing.T) {
+	api := httptest.NewServer(testchain.NewServer(testchain.NewChain(quietChain)))
+	t.Cleanup(api.Close)
+
+	storage := txstorage.New()
+	client := jsonrpc.New(&http.Client{}, api.URL)
+	w := NewMempoolWatcher(client, New(client, storage, WithLogger(log.New(io.Discard, "", 0))), storage)
+
+	w.Start()
+
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := w.Stop(ctx)
+		cancel()
+
+		if err != nil {
+			t.Fatalf("failed to stop mempool watcher at %d: %v", i, err)
+		}
+	}
+}
+
+func TestMempoolWatcherResolvesTransactionOfUnsubscribedAddress(t *testing.T) {
+	p, chain, storage := newTestParser(t, quietChain)
+	if err := p.Start(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	alice, bob := chain.Accounts()[0], chain.Accounts()[1]
+	p.Subscribe(alice)
+
+	w := NewMempoolWatcher(p.ethClient.(MempoolClient), p, storage)
+	// check transactions as soon as they are seen
+	w.droppedCheckAge = -time.Second
+
+	ctx := context.Background()
+	filterId, err := w.newFilter(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent, err := chain.Send(alice, bob, big.NewInt(1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.poll(ctx, filterId); err != nil {
+		t.Fatal(err)
+	}
+
+	if pending := storage.GetPendingTransactionsByAddress(alice); len(pending) != 1 || pending[0].Status != types.TxStatusPending {
+		t.Fatalf("expected %s to be pending, but got %+v", sent.Hash, pending)
+	}
+
+	// the transaction still in mempool is kept pending
+	w.checkPending(ctx)
+	if pending := storage.GetPendingTransactionsByAddress(alice); len(pending) != 1 || pending[0].Status != types.TxStatusPending {
+		t.Fatalf("expected %s to be kept pending, but got %+v", sent.Hash, pending)
+	}
+
+	// the block including the transaction is not stored after unsubscribing
+	p.Unsubscribe(alice)
+
+	block := chain.Mine()
+	waitForHeight(t, p, block.Number.Uint64())
+
+	if _, ok := storage.GetTransactionByHash(sent.Hash); ok {
+		t.Fatalf("expected %s not to be stored", sent.Hash)
+	}
+
+	w.checkPending(ctx)
+	if pending := storage.GetPendingTransactionsByAddress(alice); len(pending) != 0 {
+		t.Fatalf("expected included transaction not to be pending anymore, but got %+v", pending)
+	}
+}
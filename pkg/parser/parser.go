@@ -5,9 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"math"
 	"math/big"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,6 +13,9 @@ import (
 	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
 )
 
+// ErrAlreadyStarted is returned by Start while background jobs are running
+var ErrAlreadyStarted = errors.New("parser is already started")
+
 const (
 	MaxRetry                        = 10
 	DefaultFetchTimeout             = 10 * time.Second
@@ -23,36 +24,139 @@ const (
 )
 
 type Parser struct {
-	ethClient EthClient
-	storage   EthTransactionStorage
-
-	addressMap         *sync.Map
+	ethClient     EthClient
+	storage       EthTransactionStorage
+	verifier      BlockVerifier   // optional
+	checkpoint    CheckpointStore // optional
+	balanceClient BalanceClient   // optional
+	breaker       *CircuitBreaker // optional
+	retryPolicy   RetryPolicy
+	pollInterval  time.Duration // interval to poll the next block once the parser catches up
+	logger        *log.Logger
+	health        *healthTracker
+
+	addresses          *AddressSet
+	accountsToRefresh  *sync.Map // addresses whose balance should be fetched at next block
 	currentBlockHeight *atomic.Uint64
-
-	blockCh chan *types.Block
+	nextHeight         atomic.Pointer[big.Int] // height of the next block to process, restarts resume from it
 
 	// notification
-	notifyErrCh        chan error
+	notifyErrCh chan error
+	active      atomic.Pointer[run] // background jobs of the last Start, nil until started
+	lifecycleMu sync.Mutex          // serializes Start, Stop and Seek
+
+	// runtime control by admin
+	pauseMu   sync.Mutex
+	resumeCh  chan struct{} // closed on Resume, nil unless paused
+	reindexMu sync.Mutex
+	reindex   *reindexJob // the last reindex job, nil if never requested
+}
+
+// run is a set of channels of background jobs started by Start
+// A new run is created on every Start, so that the parser can be restarted after it stops
+type run struct {
+	blockCh            chan *types.Block
 	notifyCloseCh      chan struct{}
-	notifyTerminatedCh chan struct{}
+	notifyTerminatedCh chan struct{} // closed after both scraping and storing processes finish
+	stopOnce           sync.Once
+}
+
+func newRun() *run {
+	return &run{
+		blockCh:            make(chan *types.Block, 1),
+		notifyCloseCh:      make(chan struct{}),
+		notifyTerminatedCh: make(chan struct{}),
+	}
+}
+
+// stop emits close signal to background jobs once
+func (r *run) stop() {
+	r.stopOnce.Do(func() {
+		close(r.notifyCloseCh)
+	})
+}
+
+// isTerminated returns true if background jobs have finished
+func (r *run) isTerminated() bool {
+	select {
+	case <-r.notifyTerminatedCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// Option configures optional features of Parser
+type Option func(*Parser)
+
+// WithCheckpointStore makes Parser save the height of processed block and resume from it on Start
+func WithCheckpointStore(store CheckpointStore) Option {
+	return func(p *Parser) {
+		p.checkpoint = store
+	}
+}
+
+// WithLogger replaces default logger, which is useful to distinguish logs of parsers for multiple chains
+func WithLogger(logger *log.Logger) Option {
+	return func(p *Parser) {
+		p.logger = logger
+	}
+}
+
+// WithBlockVerifier makes Parser verify every fetched block before storing its transactions
+func WithBlockVerifier(verifier BlockVerifier) Option {
+	return func(p *Parser) {
+		p.verifier = verifier
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy used for fetching blocks
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(p *Parser) {
+		p.retryPolicy = policy
+	}
+}
+
+// WithPollingInterval sets the interval to poll the next block once the parser catches up with the chain
+func WithPollingInterval(interval time.Duration) Option {
+	return func(p *Parser) {
+		p.pollInterval = interval
+	}
+}
+
+// WithCircuitBreaker makes Parser degraded and keep probing the node instead of terminating with error
+// when the breaker opens or the retry policy gives up
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(p *Parser) {
+		p.breaker = breaker
+	}
 }
 
 func New(
 	ethClient EthClient,
 	storage EthTransactionStorage,
+	opts ...Option,
 ) *Parser {
-	return &Parser{
-		ethClient: ethClient,
-		storage:   storage,
-
-		addressMap:         &sync.Map{},
+	p := &Parser{
+		ethClient:    ethClient,
+		storage:      storage,
+		logger:       log.Default(),
+		retryPolicy:  DefaultRetryPolicy(),
+		pollInterval: DefaultNextBlockPollingInterval,
+		health:       newHealthTracker(),
+
+		addresses:          NewAddressSet(DefaultAddressSetCapacity),
+		accountsToRefresh:  &sync.Map{},
 		currentBlockHeight: &atomic.Uint64{},
 
-		blockCh:            make(chan *types.Block, 1),
-		notifyErrCh:        make(chan error, 1),
-		notifyCloseCh:      make(chan struct{}),
-		notifyTerminatedCh: make(chan struct{}),
+		notifyErrCh: make(chan error, 1),
+	}
+
+	for _, opt := range opts {
+		opt(p)
 	}
+
+	return p
 }
 
 // ErrCh returns channel of error which is sent from goroutine
@@ -68,21 +172,131 @@ func (p *Parser) GetCurrentBlock() int {
 }
 
 // Subscribe adds address to observer
-func (p *Parser) Subscribe(address string) bool {
-	address = strings.ToLower(address)
+func (p *Parser) Subscribe(address types.Address) bool {
+	added := p.addresses.Add(address)
 
-	_, subscribed := p.addressMap.LoadOrStore(address, true)
+	// fetch initial balance at next block
+	if added && p.balanceClient != nil {
+		p.accountsToRefresh.Store(address.Lower(), true)
+	}
 
-	return !subscribed
+	return added
+}
+
+// Unsubscribe removes address from observer, stored transactions are kept
+func (p *Parser) Unsubscribe(address types.Address) bool {
+	p.accountsToRefresh.Delete(address.Lower())
+
+	return p.addresses.Remove(address)
+}
+
+// IsSubscribed returns true if the address is observed
+func (p *Parser) IsSubscribed(address types.Address) bool {
+	return p.isSubscribingTo(address)
+}
+
+// SubscribeMany adds addresses to observer at once and returns the number of newly added ones
+func (p *Parser) SubscribeMany(addresses []types.Address) int {
+	added := 0
+	for _, address := range addresses {
+		if p.Subscribe(address) {
+			added++
+		}
+	}
+
+	return added
+}
+
+// NumSubscriptions returns the number of subscribed addresses
+func (p *Parser) NumSubscriptions() int {
+	return p.addresses.Len()
 }
 
 // GetTransactions returns list of inbound or outbound transactions for an address
-func (p *Parser) GetTransactions(address string) []types.Transaction {
+func (p *Parser) GetTransactions(address types.Address) []types.Transaction {
 	return p.storage.GetTransactionsByAddress(address)
 }
 
+// GetPendingTransactions returns transactions for an address seen in mempool but not confirmed
+// It's empty unless MempoolWatcher is running
+func (p *Parser) GetPendingTransactions(address types.Address) []types.PendingTransaction {
+	return p.storage.GetPendingTransactionsByAddress(address)
+}
+
+// IterateTransactions calls fn for each inbound or outbound transaction for an address without copying whole list
+func (p *Parser) IterateTransactions(address types.Address, fn func(*types.Transaction) error) error {
+	return p.storage.IterateTransactionsByAddress(address, fn)
+}
+
+// GetTransactionsByTimeRange returns transactions for an address in blocks whose timestamp is in [from, to)
+// Zero from or to means unbounded
+func (p *Parser) GetTransactionsByTimeRange(address types.Address, from, to time.Time) []types.Transaction {
+	return p.storage.GetTransactionsByTimeRange(address, from, to)
+}
+
+// GetAddressAnalytics returns values, fees and counterparties of transactions for an address in blocks from..to inclusive
+// Aggregates are maintained as transactions are stored, counterparties are limited to maxCounterparties if positive
+func (p *Parser) GetAddressAnalytics(address types.Address, from, to uint64, maxCounterparties int) *types.AddressAnalytics {
+	return p.storage.GetAddressAnalytics(address, from, to, maxCounterparties)
+}
+
+// GetBlockRangeByTime returns the first and last blocks whose timestamp is in [since, until) among blocks which have
+// transactions of subscribed addresses, ok is false if no block is in the range
+func (p *Parser) GetBlockRangeByTime(since, until time.Time) (uint64, uint64, bool) {
+	return p.storage.GetBlockRangeByTime(since, until)
+}
+
+// GetBlockHeader returns the summary of block at given height
+// Headers are kept only for blocks which have transactions of subscribed addresses
+func (p *Parser) GetBlockHeader(height uint64) (*types.BlockHeader, bool) {
+	return p.storage.GetBlockHeader(height)
+}
+
+// GetBlockTimestamp returns the timestamp of block at given height
+// Timestamps are kept only for blocks which have transactions of subscribed addresses
+func (p *Parser) GetBlockTimestamp(height uint64) (time.Time, bool) {
+	header, ok := p.storage.GetBlockHeader(height)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return header.Time(), true
+}
+
 // Start prepares required parameters and start background jobs
+// It resumes from the block next to the checkpoint if saved, otherwise starts from beginningHeight or latest block
+// It can be called again after the parser stops, e.g. by Supervisor, then it resumes from the block next to
+// the last processed one, or the beginning of the previous run, if no checkpoint is saved
 func (p *Parser) Start(beginningHeight *big.Int) error {
+	p.lifecycleMu.Lock()
+	defer p.lifecycleMu.Unlock()
+
+	if p.isRunning() {
+		return ErrAlreadyStarted
+	}
+
+	resumed := false
+	if p.checkpoint != nil {
+		height, ok, err := p.checkpoint.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+
+		if ok {
+			p.logger.Printf("resuming from checkpoint, height=%d", height)
+
+			p.currentBlockHeight.Store(height)
+			beginningHeight = new(big.Int).SetUint64(height + 1)
+			resumed = true
+		}
+	}
+
+	if next := p.nextHeight.Load(); !resumed && next != nil {
+		p.logger.Printf("resuming from previous run, height=%d", next.Uint64())
+
+		beginningHeight = next
+	}
+
 	if beginningHeight == nil {
 		height, err := p.fetchLatestHeight()
 		if err != nil {
@@ -92,70 +306,113 @@ func (p *Parser) Start(beginningHeight *big.Int) error {
 		beginningHeight = height
 	}
 
-	log.Printf("start fetching blocks from %d", beginningHeight.Uint64())
-
-	go p.runScrapingProcess(*beginningHeight)
-	go p.runStoringProcess()
+	p.startRun(beginningHeight)
 
 	return nil
 }
 
-// Stop tries to terminate background job
+// startRun starts background jobs from the height, lifecycleMu must be held
+func (p *Parser) startRun(beginningHeight *big.Int) {
+	p.logger.Printf("start fetching blocks from %d", beginningHeight.Uint64())
+
+	p.nextHeight.Store(new(big.Int).Set(beginningHeight))
+
+	// the parser may have failed in the previous run
+	p.health.setState(HealthStateHealthy)
+
+	r := newRun()
+	p.active.Store(r)
+
+	go p.runScrapingProcess(r, *beginningHeight)
+	go p.runStoringProcess(r)
+}
+
+// isRunning returns true if background jobs are running, lifecycleMu must be held
+func (p *Parser) isRunning() bool {
+	r := p.active.Load()
+
+	return r != nil && !r.isTerminated()
+}
+
+// Stop terminates background jobs gracefully
+// Fetching is cancelled, and blocks fetched already are stored with their checkpoints before it returns
+// Reindexing in progress is cancelled as well
 func (p *Parser) Stop(ctx context.Context) error {
+	if err := p.stopReindex(ctx); err != nil {
+		return err
+	}
+
+	p.lifecycleMu.Lock()
+	defer p.lifecycleMu.Unlock()
+
+	r := p.active.Load()
+	if r == nil {
+		return nil
+	}
+
 	// emits close signal
-	close(p.notifyCloseCh)
+	r.stop()
 
-	// wait until background routine to be done or timeout comes
+	// wait until background routines to be done or timeout comes
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case <-p.notifyTerminatedCh:
+	case <-r.notifyTerminatedCh:
 		return nil
 	}
 }
 
 // runScrapingProcess is a background job to fetch block in order and send it to channel
-func (p *Parser) runScrapingProcess(beginningHeight big.Int) {
+// The channel is closed when it finishes, so that storing process stores remaining blocks and finishes
+func (p *Parser) runScrapingProcess(r *run, beginningHeight big.Int) {
 	current := &beginningHeight
 
 	defer func() {
-		log.Printf("scrapingProcess has been finished")
-		close(p.notifyTerminatedCh)
+		p.logger.Printf("scrapingProcess has been finished")
+		close(r.blockCh)
 	}()
 
 	for {
+		// wait while paused by admin
+		if !p.waitForResume(r.notifyCloseCh) {
+			// Stop has been called, terminate process
+			return
+		}
+
 		// fetch block
-		block, err := p.fetchBlock(*current)
+		block, err := p.fetchBlock(r.notifyCloseCh, *current)
 		if errors.Is(err, context.Canceled) {
 			// Stop has been called, terminate process
 			return
 		} else if err != nil {
 			// unrecoverable error occurred
-			p.notifyErrCh <- err
+			p.health.setState(HealthStateFailed)
+			p.notifyError(err)
 
 			return
 		}
 
 		// next block is not created yet, wait certain time and retry
 		if block == nil {
-			log.Printf("next block is not created yet, retry in %d seconds...", uint(DefaultNextBlockPollingInterval.Seconds()))
+			p.logger.Printf("next block is not created yet, retry in %s...", p.pollInterval)
 
 			select {
-			case <-time.After(DefaultNextBlockPollingInterval):
+			case <-time.After(p.pollInterval):
 				continue
-			case <-p.notifyCloseCh:
+			case <-r.notifyCloseCh:
 				// Stop has been called, terminate process
 				return
 			}
 		}
 
-		log.Printf("fetched new block, height=%d", current.Uint64())
+		p.health.blockFetched()
+		p.logger.Printf("fetched new block, height=%d", current.Uint64())
 
 		select {
-		case <-p.notifyCloseCh:
+		case <-r.notifyCloseCh:
 			// Stop has been called, terminate process
 			return
-		case p.blockCh <- block:
+		case r.blockCh <- block:
 			// Emit the fetched block
 		}
 
@@ -165,41 +422,101 @@ func (p *Parser) runScrapingProcess(beginningHeight big.Int) {
 }
 
 // runStoringProcess process fetched block and save transactions to storage
-func (p *Parser) runStoringProcess() {
-	for {
-		// wait for new incoming block
-		var block *types.Block
-		select {
-		case <-p.notifyCloseCh:
-			// Stop has been called, terminate process
-			return
-		case block = <-p.blockCh:
-		}
+// It keeps storing blocks in channel after Stop is called, and finishes when scraping process closes the channel
+func (p *Parser) runStoringProcess(r *run) {
+	defer func() {
+		p.logger.Printf("storingProcess has been finished")
+		close(r.notifyTerminatedCh)
+	}()
 
-		// filter transactions by address
-		filtered := make([]*types.Transaction, 0, len(block.Transactions))
-		for _, tx := range block.Transactions {
-			tx := tx
+	for block := range r.blockCh {
+		if err := p.storeBlock(block); err != nil {
+			// unrecoverable error occurred, the block is processed again by the next run
+			p.logger.Printf("failed to store block, height=%d: %v", block.Number.Uint64(), err)
+			p.health.setState(HealthStateFailed)
+			p.notifyError(err)
+			r.stop()
 
-			if p.isSubscribingTo(tx.From) || p.isSubscribingTo(tx.To) {
-				log.Printf("found a concerned transaction, hash=%s, from=%s, to=%s", tx.Hash, tx.From, tx.To)
-				filtered = append(filtered, &tx)
+			// discard blocks fetched already so that scraping process finishes
+			for range r.blockCh {
 			}
+
+			return
 		}
+	}
+}
 
-		// insert transactions into storage
-		if err := p.storage.InsertTransactions(filtered); err != nil {
-			log.Printf("failed to save transactions to storage: %v", err)
-			p.notifyErrCh <- err
+// storeBlock saves transactions of subscribed addresses in the block, and saves the height as checkpoint
+// If transactions can't be saved, it returns error without updating the height and the checkpoint
+func (p *Parser) storeBlock(block *types.Block) error {
+	// filter transactions by address
+	// Block.LogsBloom doesn't include senders and recipients of transactions,
+	// so every transaction is checked by bloom filter of subscribed addresses instead
+	filtered := p.filterTransactions(block, p.matchedAddresses)
+
+	// keep block header for transactions to be stored
+	if len(filtered) > 0 {
+		if err := p.storeBlockHeader(block); err != nil {
+			p.logger.Printf("failed to store block header: %v", err)
 		}
+	}
+
+	// insert transactions into storage
+	if err := p.storage.InsertTransactions(filtered); err != nil {
+		return fmt.Errorf("failed to save transactions to storage: %w", err)
+	}
+
+	// refresh balances changed by the block
+	if p.balanceClient != nil {
+		p.refreshAccountStates(block, p.touchedAddresses(block, filtered))
+	}
+
+	// update current height
+	if err := p.updateCurrentHeight(block.Number); err != nil {
+		p.logger.Printf("failed to store current block height: %v", err)
+		p.notifyError(err)
+	}
+
+	// save progress
+	if p.checkpoint != nil {
+		if err := p.checkpoint.Save(p.currentBlockHeight.Load()); err != nil {
+			p.logger.Printf("failed to save checkpoint: %v", err)
+			p.notifyError(err)
+		}
+	}
+
+	p.logger.Printf("saved transactions of block, block height=%d", p.currentBlockHeight.Load())
+
+	return nil
+}
 
-		// update current height
-		if err := p.updateCurrentHeight(block.Number); err != nil {
-			log.Printf("failed to store current block height: %v", err)
-			p.notifyErrCh <- err
+// filterTransactions returns transactions in the block matching some addresses by match
+func (p *Parser) filterTransactions(
+	block *types.Block,
+	match func(*types.Transaction) []types.Address,
+) []*types.IndexedTransaction {
+	filtered := make([]*types.IndexedTransaction, 0, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		matched := match(&tx)
+		if len(matched) > 0 {
+			p.logger.Printf("found a concerned transaction, hash=%s, from=%s, to=%s", tx.Hash, tx.From, tx.To)
+			filtered = append(filtered, &types.IndexedTransaction{
+				Transaction:      tx,
+				MatchedAddresses: matched,
+			})
 		}
+	}
+
+	return filtered
+}
 
-		log.Printf("saved transactions of block, block height=%d", p.currentBlockHeight.Load())
+// notifyError sends error to ErrCh, it's dropped if an error is waiting to be received already
+// so that background jobs never block on it, e.g. while storing remaining blocks after Stop
+func (p *Parser) notifyError(err error) {
+	select {
+	case p.notifyErrCh <- err:
+	default:
+		p.logger.Printf("error is not notified because another error is pending: %v", err)
 	}
 }
 
@@ -217,21 +534,27 @@ func (p *Parser) fetchLatestHeight() (*big.Int, error) {
 }
 
 // fetchBlock fetches a block by given height with retry and backoff mechanisms
-// It keeps attempting until it either succeeds, exceeds the maximum number of retries, or is cancelled
-func (p *Parser) fetchBlock(height big.Int) (*types.Block, error) {
+// It keeps attempting until it either succeeds, the retry policy gives up, or is cancelled by closing closeCh
+// If circuit breaker is set, the parser gets degraded and keeps probing instead of giving up
+func (p *Parser) fetchBlock(closeCh <-chan struct{}, height big.Int) (*types.Block, error) {
 	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	go func() {
-		<-p.notifyCloseCh
-		cancel()
+		select {
+		case <-closeCh:
+			cancel()
+		case <-ctx.Done():
+		}
 	}()
 
-	retryTime := 0 // number of attempt
+	// number of consecutive failures for each class of error
+	attempts := make(map[ErrorClass]int)
 
 	for {
-		ctx, _ := context.WithTimeout(ctx, DefaultFetchTimeout) // nolint:govet
-
-		block, err := p.ethClient.GetBlockByNumber(ctx, height, true)
+		block, err := p.fetchBlockOnce(ctx, height)
 		if err == nil {
+			p.recoverHealth()
+
 			return block, nil
 		}
 
@@ -241,42 +564,115 @@ func (p *Parser) fetchBlock(height big.Int) (*types.Block, error) {
 			return nil, err
 		}
 
-		// return error if retry times exceeds threshold, otherwise go to next loop for retry
-		retryTime++
-		if retryTime >= MaxRetry {
-			return nil, fmt.Errorf("failed to acquire block after %d attempts: %w", MaxRetry, err)
-		}
+		p.health.failure(err)
 
-		// exponential backoff
-		multiplier := math.Pow(2, float64(retryTime-1))
-		delay := time.Duration(multiplier) * DefaultBackoffTime
+		class := ClassifyError(err)
+		attempts[class]++
 
-		log.Printf("failed to fetch block, retry in %d seconds", uint(delay.Seconds()))
+		delay, retry := p.retryPolicy.NextDelay(attempts[class], err)
+
+		switch {
+		case p.breaker != nil && (p.breaker.Failure() || !retry):
+			// wait for the node to recover instead of terminating
+			p.breaker.Trip()
+			if p.health.setState(HealthStateDegraded) {
+				p.logger.Printf("parser is degraded, probing every %s: %v", p.breaker.Cooldown(), err)
+			}
+
+			delay = p.breaker.Cooldown()
+			clear(attempts)
+		case !retry:
+			return nil, fmt.Errorf("failed to acquire block after %d attempts (%s error): %w", attempts[class], class, err)
+		default:
+			p.logger.Printf("failed to fetch block (%s error), retry in %s: %v", class, delay.Round(time.Millisecond), err)
+		}
 
 		select {
 		case <-time.After(delay):
-		case <-p.notifyCloseCh:
-			return nil, nil
+		case <-closeCh:
+			return nil, context.Canceled
 		}
 	}
 }
 
-// isSubscribingTo is a helper function to read given address from map
-func (p *Parser) isSubscribingTo(address string) bool {
-	// just check the target address is stored or not
-	_, existing := p.addressMap.Load(strings.ToLower(address))
+// recoverHealth closes circuit breaker and marks the parser healthy after successful request
+func (p *Parser) recoverHealth() {
+	if p.breaker != nil {
+		p.breaker.Success()
+	}
 
-	return existing
+	if p.health.setState(HealthStateHealthy) {
+		p.logger.Printf("parser has recovered from degraded state")
+	}
+
+	p.health.success()
 }
 
-// updateCurrentHeight updates current maximum fetched block height
-func (p *Parser) updateCurrentHeight(blockHeightHex string) error {
-	height, ok := (&big.Int{}).SetString(blockHeightHex, 0)
-	if !ok {
-		return fmt.Errorf("failed to parse block height, %s", blockHeightHex)
+// fetchBlockOnce tries to fetch a block by given height within DefaultFetchTimeout
+// The block is verified if verifier is set, so that inconsistent response is retried as well
+func (p *Parser) fetchBlockOnce(ctx context.Context, height big.Int) (*types.Block, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultFetchTimeout)
+	defer cancel()
+
+	block, err := p.ethClient.GetBlockByNumber(ctx, height, true)
+	if err != nil || block == nil || p.verifier == nil {
+		return block, err
 	}
 
-	p.currentBlockHeight.Store(height.Uint64())
+	if err := p.verifier.VerifyBlock(block); err != nil {
+		return nil, fmt.Errorf("failed to verify block: %w", err)
+	}
+
+	return block, nil
+}
+
+// matchedAddresses returns subscribed addresses among sender and recipient of the transaction
+func (p *Parser) matchedAddresses(tx *types.Transaction) []types.Address {
+	var matched []types.Address
+	if p.isSubscribingTo(tx.From) {
+		matched = append(matched, tx.From.Lower())
+	}
+
+	// self transfer is matched once
+	if p.isSubscribingTo(tx.To) && tx.To.Lower() != tx.From.Lower() {
+		matched = append(matched, tx.To.Lower())
+	}
+
+	return matched
+}
+
+// GetTransactionByHash returns the stored transaction of given hash
+func (p *Parser) GetTransactionByHash(hash types.Hash) (*types.IndexedTransaction, bool) {
+	return p.storage.GetTransactionByHash(hash)
+}
+
+// GetTransactionsByBlock returns stored transactions in the block of given height
+func (p *Parser) GetTransactionsByBlock(height uint64) []types.IndexedTransaction {
+	return p.storage.GetTransactionsByBlock(height)
+}
+
+// isSubscribingTo is a helper function to check given address is subscribed
+func (p *Parser) isSubscribingTo(address types.Address) bool {
+	return p.addresses.Contains(address)
+}
+
+// updateCurrentHeight updates current maximum fetched block height and the height restarts resume from
+func (p *Parser) updateCurrentHeight(blockHeight types.Quantity) error {
+	if blockHeight.IsEmpty() {
+		return errors.New("block height is empty")
+	}
+
+	p.currentBlockHeight.Store(blockHeight.Uint64())
+	p.nextHeight.Store(new(big.Int).SetUint64(blockHeight.Uint64() + 1))
 
 	return nil
 }
+
+// storeBlockHeader saves the summary of given block, such as timestamp and base fee
+func (p *Parser) storeBlockHeader(block *types.Block) error {
+	if block.Number.IsEmpty() || block.Timestamp.IsEmpty() {
+		return fmt.Errorf("block number or timestamp is empty, number=%s, timestamp=%s", block.Number, block.Timestamp)
+	}
+
+	return p.storage.InsertBlockHeader(types.NewBlockHeader(block))
+}
@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/checkpoint"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/testchain"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/txstorage"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/verify"
+)
+
+const (
+	testPollingInterval = 20 * time.Millisecond
+	testWaitTimeout     = 10 * time.Second
+)
+
+// quietChain has no random transactions so that tests control every transaction
+var quietChain = testchain.Config{TxsPerBlock: -1, WithdrawalsPerBlock: -1}
+
+// newTestParser creates a parser of a fake node serving chain, it's stopped when the test finishes
+func newTestParser(t *testing.T, config testchain.Config, opts ...Option) (*Parser, *testchain.Chain, *txstorage.InMemoryTransactionStorage) {
+	t.Helper()
+
+	storage := txstorage.New()
+	p, node := newTestParserWithStorage(t, config, storage, opts...)
+
+	return p, node.Chain(), storage
+}
+
+// newTestParserWithStorage creates a parser of a fake node storing transactions to storage
+// The node is returned so that tests can inject failures
+func newTestParserWithStorage(t *testing.T, config testchain.Config, storage EthTransactionStorage, opts ...Option) (*Parser, *testchain.Server) {
+	t.Helper()
+
+	node := testchain.NewServer(testchain.NewChain(config))
+	api := httptest.NewServer(node)
+	t.Cleanup(api.Close)
+
+	p := New(jsonrpc.New(&http.Client{}, api.URL), storage, append([]Option{
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithPollingInterval(testPollingInterval),
+	}, opts...)...)
+
+	t.Cleanup(func() {
+		stopParser(t, p)
+	})
+
+	return p, node
+}
+
+// failingStorage fails to insert transactions while failing is true
+type failingStorage struct {
+	*txstorage.InMemoryTransactionStorage
+	failing atomic.Bool
+}
+
+func (s *failingStorage) InsertTransactions(txs []*types.IndexedTransaction) error {
+	if s.failing.Load() {
+		return errors.New("storage is unavailable")
+	}
+
+	return s.InMemoryTransactionStorage.InsertTransactions(txs)
+}
+
+// stopParser stops the parser within a second
+func stopParser(t *testing.T, p *Parser) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := p.Stop(ctx); err != nil {
+		t.Errorf("failed to stop parser: %v", err)
+	}
+}
+
+// waitForHeight waits until the parser processes the block at height
+func waitForHeight(t *testing.T, p *Parser, height uint64) {
+	t.Helper()
+
+	deadline := time.Now().Add(testWaitTimeout)
+	for uint64(p.GetCurrentBlock()) < height {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for parser to process block %d, current block is %d", height, p.GetCurrentBlock())
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParserStoresTransactionsOfSubscribedAddresses(t *testing.T) {
+	p, chain, storage := newTestParser(t, quietChain)
+
+	alice, bob, carol := chain.Accounts()[0], chain.Accounts()[1], chain.Accounts()[2]
+	p.Subscribe(alice)
+
+	if err := p.Start(big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	sent, err := chain.Send(alice, bob, big.NewInt(1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := chain.Send(bob, carol, big.NewInt(1), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	block := chain.Mine()
+	waitForHeight(t, p, block.Number.Uint64())
+
+	txs := p.GetTransactions(alice)
+	if len(txs) != 1 || !txs[0].Hash.Equal(sent.Hash) {
+		t.Fatalf("expected only %s of alice, but got %+v", sent.Hash, txs)
+	}
+
+	indexed, ok := storage.GetTransactionByHash(sent.Hash)
+	if !ok || len(indexed.MatchedAddresses) != 1 || !indexed.MatchedAddresses[0].Equal(alice) {
+		t.Fatalf("expected %s matched to alice, but got %+v", sent.Hash, indexed)
+	}
+
+	header, ok := p.GetBlockHeader(block.Number.Uint64())
+	if !ok || !header.Hash.Equal(block.Hash) {
+		t.Fatalf("expected header of block %s, but got %+v", block.Hash, header)
+	}
+}
+
+func TestParserVerifiesBlocksOfChain(t *testing.T) {
+	// random transactions and withdrawals of every kind the fake node generates are verified
+	p, chain, _ := newTestParser(t, testchain.Config{InitialHeight: 5}, WithBlockVerifier(verify.New()))
+
+	p.Subscribe(chain.Accounts()[0])
+
+	if err := p.Start(big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForHeight(t, p, chain.Height())
+
+	if health := p.Health(); health.State != HealthStateHealthy {
+		t.Fatalf("expected healthy parser after verifying blocks, but got %+v", health)
+	}
+}
+
+func TestParserSavesCheckpoint(t *testing.T) {
+	store := checkpoint.NewMemoryStore()
+	p, chain, _ := newTestParser(t, testchain.Config{InitialHeight: 3}, WithCheckpointStore(store))
+
+	if err := p.Start(big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForHeight(t, p, chain.Height())
+
+	// checkpoint of the stored block is saved by Stop at the latest
+	stopParser(t, p)
+
+	height, ok, err := store.Load()
+	if err != nil || !ok || height != chain.Height() {
+		t.Fatalf("expected checkpoint at %d, but got height=%d, ok=%t, err=%v", chain.Height(), height, ok, err)
+	}
+}
+
+func TestParserKeepsCheckpointOnStorageFailure(t *testing.T) {
+	storage := &failingStorage{InMemoryTransactionStorage: txstorage.New()}
+	store := checkpoint.NewMemoryStore()
+	p, node := newTestParserWithStorage(t, quietChain, storage, WithCheckpointStore(store))
+	chain := node.Chain()
+
+	alice, bob := chain.Accounts()[0], chain.Accounts()[1]
+	p.Subscribe(alice)
+
+	sent, err := chain.Send(alice, bob, big.NewInt(1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := chain.Mine()
+
+	storage.failing.Store(true)
+
+	if err := p.Start(big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-p.ErrCh():
+		if err == nil {
+			t.Fatal("expected error of storage, but got nil")
+		}
+	case <-time.After(testWaitTimeout):
+		t.Fatal("timed out waiting for error of storage")
+	}
+
+	if height, ok, _ := store.Load(); ok || p.GetCurrentBlock() != 0 {
+		t.Fatalf("expected no progress after storage failure, but got checkpoint=%d, current block=%d", height, p.GetCurrentBlock())
+	}
+
+	if health := p.Health(); health.State != HealthStateFailed {
+		t.Fatalf("expected failed parser, but got %+v", health)
+	}
+
+	// the restarted parser stores the block again
+	storage.failing.Store(false)
+
+	deadline := time.Now().Add(testWaitTimeout)
+	for err := p.Start(big.NewInt(1)); err != nil; err = p.Start(big.NewInt(1)) {
+		if !errors.Is(err, ErrAlreadyStarted) || time.Now().After(deadline) {
+			t.Fatalf("failed to restart parser: %v", err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	waitForHeight(t, p, block.Number.Uint64())
+
+	if txs := p.GetTransactions(alice); len(txs) != 1 || !txs[0].Hash.Equal(sent.Hash) {
+		t.Fatalf("expected %s stored after restart, but got %+v", sent.Hash, txs)
+	}
+}
@@ -0,0 +1,340 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// MaxReindexAttempts is the number of failures to give up fetching a block for reindex at
+const MaxReindexAttempts = MaxRetry
+
+var (
+	// ErrInvalidRange is returned by Reindex for a range of blocks which can't be reindexed
+	ErrInvalidRange = errors.New("invalid range")
+	// ErrReindexInProgress is returned while another reindex job is running
+	ErrReindexInProgress = errors.New("reindex is in progress")
+)
+
+// ReindexState is the state of a reindex job
+type ReindexState string
+
+const (
+	ReindexStateRunning   ReindexState = "running"
+	ReindexStateCompleted ReindexState = "completed"
+	ReindexStateFailed    ReindexState = "failed"
+	ReindexStateCancelled ReindexState = "cancelled"
+)
+
+// ReindexStatus is a snapshot of a reindex job
+type ReindexStatus struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+	// Address is the only address reindexed, all subscribed addresses are reindexed if empty
+	Address types.Address `json:"address,omitempty"`
+	State   ReindexState  `json:"state"`
+	// NextHeight is the height of the block to be reindexed next
+	NextHeight uint64 `json:"nextHeight"`
+	// Deleted is the number of transactions deleted or unmatched before reindexing
+	Deleted int `json:"deleted"`
+	// Indexed is the number of transactions stored by reindexing
+	Indexed    int        `json:"indexed"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// reindexJob is a background job reindexing a range of blocks
+type reindexJob struct {
+	address *types.Address
+
+	mu     sync.Mutex
+	status ReindexStatus
+
+	cancelCh   chan struct{}
+	cancelOnce sync.Once
+	doneCh     chan struct{}
+}
+
+// Reindex deletes transactions in blocks from..to inclusive and ingests them again in background
+// Only the address is reindexed if given, otherwise all subscribed addresses are. Blocks must have been processed
+// already, so that reindexing doesn't race with ingestion of new blocks. Account states are not reindexed
+func (p *Parser) Reindex(from, to uint64, address *types.Address) (*ReindexStatus, error) {
+	if from > to {
+		return nil, fmt.Errorf("%w: from %d is greater than to %d", ErrInvalidRange, from, to)
+	}
+
+	p.lifecycleMu.Lock()
+	defer p.lifecycleMu.Unlock()
+
+	if next, ok := p.NextHeight(); !ok || to >= next {
+		return nil, fmt.Errorf("%w: block %d has not been processed yet", ErrInvalidRange, to)
+	}
+
+	p.reindexMu.Lock()
+	defer p.reindexMu.Unlock()
+
+	if p.reindex != nil && !p.reindex.isDone() {
+		return nil, ErrReindexInProgress
+	}
+
+	job := &reindexJob{
+		address: address,
+		status: ReindexStatus{
+			From:       from,
+			To:         to,
+			State:      ReindexStateRunning,
+			NextHeight: from,
+			StartedAt:  time.Now().UTC(),
+		},
+		cancelCh: make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+
+	if address != nil {
+		job.status.Address = address.Lower()
+	}
+
+	p.reindex = job
+
+	p.logger.Printf("start reindexing blocks, from=%d, to=%d, address=%s", from, to, job.status.Address)
+
+	go p.runReindex(job)
+
+	status := job.snapshot()
+
+	return &status, nil
+}
+
+// ReindexStatus returns the status of the last reindex job, ok is false if reindex has never been requested
+func (p *Parser) ReindexStatus() (*ReindexStatus, bool) {
+	p.reindexMu.Lock()
+	defer p.reindexMu.Unlock()
+
+	if p.reindex == nil {
+		return nil, false
+	}
+
+	status := p.reindex.snapshot()
+
+	return &status, true
+}
+
+// CancelReindex cancels the running reindex job, it returns false if no job is running
+// Transactions reindexed already are kept
+func (p *Parser) CancelReindex() bool {
+	p.reindexMu.Lock()
+	defer p.reindexMu.Unlock()
+
+	if p.reindex == nil || p.reindex.isDone() {
+		return false
+	}
+
+	p.reindex.cancel()
+
+	return true
+}
+
+// isReindexing returns true if a reindex job is running
+func (p *Parser) isReindexing() bool {
+	p.reindexMu.Lock()
+	defer p.reindexMu.Unlock()
+
+	return p.reindex != nil && !p.reindex.isDone()
+}
+
+// stopReindex cancels the running reindex job and waits for it
+func (p *Parser) stopReindex(ctx context.Context) error {
+	p.reindexMu.Lock()
+	job := p.reindex
+	p.reindexMu.Unlock()
+
+	if job == nil {
+		return nil
+	}
+
+	job.cancel()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-job.doneCh:
+		return nil
+	}
+}
+
+// runReindex deletes transactions in the range and stores transactions of blocks in order
+func (p *Parser) runReindex(job *reindexJob) {
+	defer close(job.doneCh)
+
+	from, to := job.status.From, job.status.To
+
+	deleted, err := p.storage.DeleteBlockRange(from, to, job.address)
+	if err != nil {
+		p.finishReindex(job, fmt.Errorf("failed to delete blocks from %d to %d: %w", from, to, err))
+		return
+	}
+
+	job.update(func(status *ReindexStatus) {
+		status.Deleted = deleted
+	})
+
+	match := p.matchedAddresses
+	if job.address != nil {
+		match = func(tx *types.Transaction) []types.Address {
+			return matchAddress(tx, *job.address)
+		}
+	}
+
+	for height := from; ; height++ {
+		block, err := p.fetchReindexBlock(job.cancelCh, height)
+		if err != nil {
+			p.finishReindex(job, err)
+			return
+		}
+
+		if block == nil {
+			p.finishReindex(job, fmt.Errorf("block %d is not found", height))
+			return
+		}
+
+		filtered := p.filterTransactions(block, match)
+		if len(filtered) > 0 {
+			if err := p.storeBlockHeader(block); err != nil {
+				p.logger.Printf("failed to store block header: %v", err)
+			}
+
+			if err := p.storage.InsertTransactions(filtered); err != nil {
+				p.finishReindex(job, fmt.Errorf("failed to save transactions of block %d: %w", height, err))
+				return
+			}
+		}
+
+		job.update(func(status *ReindexStatus) {
+			status.NextHeight = height + 1
+			status.Indexed += len(filtered)
+		})
+
+		// checked here so that height doesn't overflow
+		if height == to {
+			break
+		}
+	}
+
+	p.finishReindex(job, nil)
+}
+
+// fetchReindexBlock fetches a block for reindex job, retrying up to MaxReindexAttempts times by the retry policy
+// Unlike fetchBlock, failures don't change health of the parser nor circuit breaker, they fail the job instead
+func (p *Parser) fetchReindexBlock(cancelCh <-chan struct{}, height uint64) (*types.Block, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-cancelCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for attempt := 1; ; attempt++ {
+		block, err := p.fetchBlockOnce(ctx, *new(big.Int).SetUint64(height))
+		if err == nil {
+			return block, nil
+		}
+
+		// cancelled by outside, exit function
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+
+		class := ClassifyError(err)
+
+		delay, retry := p.retryPolicy.NextDelay(attempt, err)
+		if !retry || attempt >= MaxReindexAttempts {
+			return nil, fmt.Errorf("failed to fetch block %d after %d attempts (%s error): %w", height, attempt, class, err)
+		}
+
+		p.logger.Printf("failed to fetch block for reindex (%s error), height=%d, retry in %s: %v", class, height, delay.Round(time.Millisecond), err)
+
+		select {
+		case <-time.After(delay):
+		case <-cancelCh:
+			return nil, context.Canceled
+		}
+	}
+}
+
+// finishReindex records the result of reindex job
+func (p *Parser) finishReindex(job *reindexJob, err error) {
+	job.update(func(status *ReindexStatus) {
+		now := time.Now().UTC()
+		status.FinishedAt = &now
+
+		switch {
+		case err == nil:
+			status.State = ReindexStateCompleted
+		case errors.Is(err, context.Canceled):
+			status.State = ReindexStateCancelled
+		default:
+			status.State = ReindexStateFailed
+			status.Error = err.Error()
+		}
+	})
+
+	status := job.snapshot()
+	p.logger.Printf("reindexing has been finished, state=%s, indexed=%d, error=%s", status.State, status.Indexed, status.Error)
+}
+
+// matchAddress returns the address if it's sender or recipient of the transaction
+func matchAddress(tx *types.Transaction, address types.Address) []types.Address {
+	if tx.From.Equal(address) || tx.To.Equal(address) {
+		return []types.Address{address.Lower()}
+	}
+
+	return nil
+}
+
+// update changes the status under lock
+func (j *reindexJob) update(fn func(*ReindexStatus)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	fn(&j.status)
+}
+
+// snapshot returns a copy of the status
+func (j *reindexJob) snapshot() ReindexStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := j.status
+	if status.FinishedAt != nil {
+		finishedAt := *status.FinishedAt
+		status.FinishedAt = &finishedAt
+	}
+
+	return status
+}
+
+// cancel stops the job, fetching in progress is cancelled
+func (j *reindexJob) cancel() {
+	j.cancelOnce.Do(func() {
+		close(j.cancelCh)
+	})
+}
+
+// isDone returns true if the job has finished
+func (j *reindexJob) isDone() bool {
+	select {
+	case <-j.doneCh:
+		return true
+	default:
+		return false
+	}
+}
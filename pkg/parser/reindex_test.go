@@ -0,0 +1,160 @@
+package parser
+
+import (
+	"context"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/testchain"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/txstorage"
+)
+
+// waitForReindex waits until the last reindex job finishes
+func waitForReindex(t *testing.T, p *Parser) *ReindexStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(testWaitTimeout)
+	for {
+		status, ok := p.ReindexStatus()
+		if ok && status.State != ReindexStateRunning {
+			return status
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for reindex, status=%+v", status)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReindexFailureDoesNotAffectHealth(t *testing.T) {
+	breaker := NewCircuitBreaker(1, time.Minute)
+	p, node := newTestParserWithStorage(t, quietChain, txstorage.New(),
+		WithRetryPolicy(&ExponentialBackoff{Base: time.Millisecond}),
+		WithCircuitBreaker(breaker),
+	)
+
+	chain := node.Chain()
+	alice, bob := chain.Accounts()[0], chain.Accounts()[1]
+	p.Subscribe(alice)
+
+	if err := p.Start(big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := chain.Send(alice, bob, big.NewInt(1), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	block := chain.Mine()
+	waitForHeight(t, p, block.Number.Uint64())
+
+	// failures are consumed only by reindexing while ingestion is paused
+	p.Pause()
+	time.Sleep(5 * testPollingInterval)
+
+	node.InjectFailures(MaxReindexAttempts, testchain.InternalError())
+
+	if _, err := p.Reindex(1, block.Number.Uint64(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	status := waitForReindex(t, p)
+	if status.State != ReindexStateFailed || status.Error == "" {
+		t.Fatalf("expected failed reindex job, but got %+v", status)
+	}
+
+	if health := p.Health(); health.State != HealthStateHealthy || breaker.IsOpen() {
+		t.Fatalf("expected healthy parser after reindex failure, but got %+v, breaker open=%t", health, breaker.IsOpen())
+	}
+
+	// reindexing again restores deleted transactions
+	if _, err := p.Reindex(1, block.Number.Uint64(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := waitForReindex(t, p); status.State != ReindexStateCompleted || status.Indexed != 1 {
+		t.Fatalf("expected completed reindex job, but got %+v", status)
+	}
+
+	if txs := p.GetTransactions(alice); len(txs) != 1 {
+		t.Fatalf("expected transaction of alice after reindex, but got %+v", txs)
+	}
+}
+
+func TestReindexKeepsAccountStates(t *testing.T) {
+	p, node := newTestParserWithStorage(t, quietChain, txstorage.New())
+	// the fake node serves balances as well
+	p.balanceClient = p.ethClient.(BalanceClient)
+
+	chain := node.Chain()
+	alice, bob := chain.Accounts()[0], chain.Accounts()[1]
+	p.Subscribe(alice)
+
+	if err := p.Start(big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	heights := make([]uint64, 0, 3)
+	for i := 0; i < 3; i++ {
+		if _, err := chain.Send(alice, bob, big.NewInt(1), ""); err != nil {
+			t.Fatal(err)
+		}
+
+		block := chain.Mine()
+		heights = append(heights, block.Number.Uint64())
+		waitForHeight(t, p, block.Number.Uint64())
+	}
+
+	// history returns balances of alice at the heights
+	history := func() []string {
+		balances := make([]string, len(heights))
+		for i, height := range heights {
+			if state, ok := p.GetAccountState(alice, &height); ok && state.BlockNumber.Uint64() == height {
+				balances[i] = state.Balance.Decimal()
+			}
+		}
+
+		return balances
+	}
+
+	before := history()
+	for i, balance := range before {
+		if balance == "" || (i > 0 && balance == before[i-1]) {
+			t.Fatalf("expected balance of alice to change at every block, but got %v", before)
+		}
+	}
+
+	// states aren't fetched by reindex, so they must be kept
+	p.Pause()
+	if _, err := p.Reindex(1, heights[2], nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := waitForReindex(t, p); status.State != ReindexStateCompleted || status.Indexed != 3 {
+		t.Fatalf("expected completed reindex job, but got %+v", status)
+	}
+
+	if after := history(); !slices.Equal(before, after) {
+		t.Fatalf("expected balance history %v to be kept after reindex, but got %v", before, after)
+	}
+
+	// rewind deletes states of blocks to be processed again
+	if _, err := p.Seek(context.Background(), heights[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if rewound := history(); rewound[0] != before[0] || rewound[1] != "" || rewound[2] != "" {
+		t.Fatalf("expected states from block %d to be deleted, but got %v", heights[1], rewound)
+	}
+
+	p.Resume()
+	waitForHeight(t, p, heights[2])
+
+	if after := history(); !slices.Equal(before, after) {
+		t.Fatalf("expected balance history %v to be fetched again, but got %v", before, after)
+	}
+}
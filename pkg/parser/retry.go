@@ -0,0 +1,217 @@
+package parser
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+)
+
+// ErrorClass is a category of errors to decide how to retry
+type ErrorClass int
+
+const (
+	// ErrorClassTransient is an error which may be resolved by retrying, e.g. timeout or server error
+	ErrorClassTransient ErrorClass = iota
+	// ErrorClassRateLimited is throttling by provider
+	ErrorClassRateLimited
+	// ErrorClassPermanent is an error which retrying never resolves, e.g. invalid request
+	ErrorClassPermanent
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassTransient:
+		return "transient"
+	case ErrorClassRateLimited:
+		return "rate limited"
+	case ErrorClassPermanent:
+		return "permanent"
+	}
+
+	return "unknown"
+}
+
+// ClassifyError returns the class of an error returned by fetching block
+func ClassifyError(err error) ErrorClass {
+	switch {
+	case errors.Is(err, jsonrpc.ErrRateLimited):
+		return ErrorClassRateLimited
+	case !jsonrpc.IsRetryable(err):
+		return ErrorClassPermanent
+	}
+
+	return ErrorClassTransient
+}
+
+// RetryPolicy decides whether and when to retry a failed request
+type RetryPolicy interface {
+	// NextDelay returns the delay before next attempt after attempt-th consecutive failure, or false to give up
+	NextDelay(attempt int, err error) (time.Duration, bool)
+}
+
+// ExponentialBackoff waits Base * Multiplier^(attempt-1) with optional jitter and cap
+type ExponentialBackoff struct {
+	Base       time.Duration
+	Multiplier float64 // 2 if zero
+	// Max caps the delay, no cap if zero
+	Max time.Duration
+	// Jitter is the ratio of delay randomly subtracted in [0, 1], which avoids retries of many clients at once
+	Jitter float64
+	// MaxAttempts is the number of failures to give up at, retries infinitely if zero
+	MaxAttempts int
+}
+
+// NextDelay implements RetryPolicy
+func (b *ExponentialBackoff) NextDelay(attempt int, _ error) (time.Duration, bool) {
+	if b.MaxAttempts > 0 && attempt >= b.MaxAttempts {
+		return 0, false
+	}
+
+	multiplier := b.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	delay := float64(b.Base) * math.Pow(multiplier, float64(attempt-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+
+	if b.Jitter > 0 {
+		delay -= delay * math.Min(b.Jitter, 1) * rand.Float64()
+	}
+
+	return time.Duration(delay), true
+}
+
+// PerClassPolicy delegates decisions to the policy for the class of error
+// It gives up on errors of class without policy
+type PerClassPolicy map[ErrorClass]RetryPolicy
+
+// NextDelay implements RetryPolicy
+func (p PerClassPolicy) NextDelay(attempt int, err error) (time.Duration, bool) {
+	policy, ok := p[ClassifyError(err)]
+	if !ok {
+		return 0, false
+	}
+
+	return policy.NextDelay(attempt, err)
+}
+
+// DefaultRetryPolicy retries transient errors with exponential backoff up to MaxRetry times,
+// keeps retrying throttled requests, and gives up on permanent errors
+func DefaultRetryPolicy() RetryPolicy {
+	return PerClassPolicy{
+		ErrorClassTransient: &ExponentialBackoff{
+			Base:        DefaultBackoffTime,
+			MaxAttempts: MaxRetry,
+		},
+		// client has waited for the endpoint already
+		ErrorClassRateLimited: &ExponentialBackoff{
+			Base: DefaultBackoffTime,
+			Max:  DefaultBackoffTime,
+		},
+	}
+}
+
+// BreakerState is the state of CircuitBreaker
+type BreakerState string
+
+const (
+	// BreakerStateClosed means requests are sent as usual
+	BreakerStateClosed BreakerState = "closed"
+	// BreakerStateOpen means the node is failing and the next probe waits for the cooldown
+	BreakerStateOpen BreakerState = "open"
+	// BreakerStateHalfOpen means the cooldown has passed and the next request probes the node
+	BreakerStateHalfOpen BreakerState = "half-open"
+)
+
+// CircuitBreaker opens after consecutive failures and makes the parser degraded instead of terminating it
+// While it's open, the parser probes the node once per cooldown
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+}
+
+// NewCircuitBreaker creates a breaker which opens after threshold consecutive failures
+// It opens only when the retry policy gives up if threshold is zero
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Failure records a failure and returns true if the breaker is open
+// A failed probe in half-open state opens the breaker for another cooldown
+func (b *CircuitBreaker) Failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.open || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.open = true
+		b.openedAt = b.now()
+	}
+
+	return b.open
+}
+
+// Trip opens the breaker regardless of the number of failures
+func (b *CircuitBreaker) Trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		b.open = true
+		b.openedAt = b.now()
+	}
+}
+
+// Success closes the breaker
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.open = false
+}
+
+// IsOpen returns true if the breaker is open or half-open
+func (b *CircuitBreaker) IsOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.open
+}
+
+// State returns the state of the breaker, it becomes half-open once the cooldown has passed since it opened
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case !b.open:
+		return BreakerStateClosed
+	case b.now().Sub(b.openedAt) >= b.cooldown:
+		return BreakerStateHalfOpen
+	default:
+		return BreakerStateOpen
+	}
+}
+
+// Cooldown returns the interval of probes while the breaker is open
+func (b *CircuitBreaker) Cooldown() time.Duration {
+	return b.cooldown
+}
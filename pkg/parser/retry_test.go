@@ -0,0 +1,332 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/jsonrpc"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/txstorage"
+	"github.com/Kourin1996/simple-go-eth-block-aggregator/internal/types"
+)
+
+// fakeClock is a clock advanced by tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+var (
+	transientError   = &jsonrpc.JsonRpcError{Code: jsonrpc.CodeInternalError, Message: "internal error"}
+	permanentError   = &jsonrpc.JsonRpcError{Code: jsonrpc.CodeInvalidParams, Message: "invalid params"}
+	rateLimitedError = fmt.Errorf("%w: gave up waiting", jsonrpc.ErrRateLimited)
+)
+
+func TestClassifyError(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		err      error
+		expected ErrorClass
+	}{
+		{"rate limited", rateLimitedError, ErrorClassRateLimited},
+		{"internal error", transientError, ErrorClassTransient},
+		{"wrapped internal error", fmt.Errorf("failed to fetch block: %w", transientError), ErrorClassTransient},
+		{"timeout", context.DeadlineExceeded, ErrorClassTransient},
+		{"5xx status", &jsonrpc.HTTPStatusError{StatusCode: http.StatusBadGateway}, ErrorClassTransient},
+		{"408 status", &jsonrpc.HTTPStatusError{StatusCode: http.StatusRequestTimeout}, ErrorClassTransient},
+		{"invalid params", permanentError, ErrorClassPermanent},
+		{"method not found", &jsonrpc.JsonRpcError{Code: jsonrpc.CodeMethodNotFound}, ErrorClassPermanent},
+		{"4xx status", &jsonrpc.HTTPStatusError{StatusCode: http.StatusUnauthorized}, ErrorClassPermanent},
+		{"malformed response", &jsonrpc.DecodeError{Method: "eth_getBlockByNumber", Err: errors.New("unexpected EOF")}, ErrorClassPermanent},
+		{"canceled", context.Canceled, ErrorClassPermanent},
+		{"any endpoint may recover", errors.Join(permanentError, transientError), ErrorClassTransient},
+	} {
+		if actual := ClassifyError(tt.err); actual != tt.expected {
+			t.Errorf("expected %s to be %s, but got %s", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := &ExponentialBackoff{Base: 100 * time.Millisecond, Max: time.Second, MaxAttempts: 6}
+
+	for i, expected := range []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+	} {
+		attempt := i + 1
+		if delay, retry := backoff.NextDelay(attempt, transientError); !retry || delay != expected {
+			t.Fatalf("expected delay %s at attempt %d, but got delay=%s, retry=%t", expected, attempt, delay, retry)
+		}
+	}
+
+	if _, retry := backoff.NextDelay(6, transientError); retry {
+		t.Fatal("expected to give up at MaxAttempts")
+	}
+
+	// jitter subtracts up to the ratio of delay
+	jittered := &ExponentialBackoff{Base: time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if delay, _ := jittered.NextDelay(1, transientError); delay < 500*time.Millisecond || delay > time.Second {
+			t.Fatalf("expected delay between 500ms and 1s, but got %s", delay)
+		}
+	}
+}
+
+// scriptedClient returns errors in order and then the block for GetBlockByNumber
+type scriptedClient struct {
+	EthClient
+
+	mu     sync.Mutex
+	errs   []error
+	blocks int
+}
+
+func (c *scriptedClient) GetBlockByNumber(_ context.Context, height big.Int, _ bool) (*types.Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+
+		return nil, err
+	}
+
+	c.blocks++
+
+	return &types.Block{Number: types.NewQuantity(&height)}, nil
+}
+
+// recordingPolicy records attempts given to the policy by class of error
+type recordingPolicy struct {
+	RetryPolicy
+	attempts map[ErrorClass][]int
+}
+
+func (p *recordingPolicy) NextDelay(attempt int, err error) (time.Duration, bool) {
+	class := ClassifyError(err)
+	p.attempts[class] = append(p.attempts[class], attempt)
+
+	_, retry := p.RetryPolicy.NextDelay(attempt, err)
+
+	return 0, retry
+}
+
+// newScriptedParser creates a parser fetching blocks from a client failing with errs
+func newScriptedParser(errs []error, opts ...Option) (*Parser, *scriptedClient, *recordingPolicy, *bytes.Buffer) {
+	client := &scriptedClient{errs: errs}
+	policy := &recordingPolicy{
+		RetryPolicy: PerClassPolicy{
+			ErrorClassTransient:   &ExponentialBackoff{MaxAttempts: 3},
+			ErrorClassRateLimited: &ExponentialBackoff{},
+		},
+		attempts: make(map[ErrorClass][]int),
+	}
+
+	logs := &bytes.Buffer{}
+	p := New(client, txstorage.New(), append([]Option{
+		WithLogger(log.New(logs, "", 0)),
+		WithRetryPolicy(policy),
+	}, opts...)...)
+
+	return p, client, policy, logs
+}
+
+func TestFetchBlockCountsAttemptsPerClass(t *testing.T) {
+	// failures of each class are counted separately, so rate limiting doesn't consume retries of transient errors
+	p, client, policy, _ := newScriptedParser([]error{
+		transientError, rateLimitedError, transientError, rateLimitedError, rateLimitedError, rateLimitedError,
+	})
+
+	if _, err := p.fetchBlock(make(chan struct{}), *big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(policy.attempts[ErrorClassTransient]) != "[1 2]" || fmt.Sprint(policy.attempts[ErrorClassRateLimited]) != "[1 2 3 4]" {
+		t.Fatalf("expected attempts [1 2] of transient and [1 2 3 4] of rate limited errors, but got %v", policy.attempts)
+	}
+
+	if client.blocks != 1 {
+		t.Fatalf("expected block to be fetched once, but got %d", client.blocks)
+	}
+
+	// attempts are reset by a successful fetch
+	client.errs = []error{transientError, transientError}
+	policy.attempts = make(map[ErrorClass][]int)
+
+	if _, err := p.fetchBlock(make(chan struct{}), *big.NewInt(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(policy.attempts[ErrorClassTransient]) != "[1 2]" {
+		t.Fatalf("expected attempts to start from 1 again, but got %v", policy.attempts)
+	}
+}
+
+func TestFetchBlockGivesUp(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		errs     []error
+		attempts int
+		class    ErrorClass
+	}{
+		{"transient", []error{transientError, transientError, transientError, transientError}, 3, ErrorClassTransient},
+		{"permanent", []error{permanentError}, 1, ErrorClassPermanent},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p, client, policy, _ := newScriptedParser(tt.errs)
+
+			_, err := p.fetchBlock(make(chan struct{}), *big.NewInt(1))
+			if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("after %d attempts (%s error)", tt.attempts, tt.class)) {
+				t.Fatalf("expected to give up after %d attempts of %s error, but got %v", tt.attempts, tt.class, err)
+			}
+
+			if len(policy.attempts[tt.class]) != tt.attempts || client.blocks != 0 {
+				t.Fatalf("expected %d attempts, but got %v and %d blocks", tt.attempts, policy.attempts, client.blocks)
+			}
+
+			if health := p.Health(); health.ConsecutiveFailures != tt.attempts || health.LastError == "" {
+				t.Fatalf("expected %d failures in health, but got %+v", tt.attempts, health)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	clock := newFakeClock()
+	breaker := NewCircuitBreaker(3, time.Minute)
+	breaker.now = clock.Now
+
+	expectState := func(expected BreakerState) {
+		t.Helper()
+
+		if actual := breaker.State(); actual != expected {
+			t.Fatalf("expected breaker to be %s, but got %s", expected, actual)
+		}
+	}
+
+	// closed until threshold
+	for i := 0; i < 2; i++ {
+		if breaker.Failure() {
+			t.Fatalf("expected breaker to be closed at failure %d", i+1)
+		}
+	}
+	expectState(BreakerStateClosed)
+
+	if !breaker.Failure() {
+		t.Fatal("expected breaker to open at threshold")
+	}
+	expectState(BreakerStateOpen)
+
+	clock.Advance(time.Minute - time.Second)
+	expectState(BreakerStateOpen)
+
+	// the next request probes the node after cooldown
+	clock.Advance(time.Second)
+	expectState(BreakerStateHalfOpen)
+
+	// a failed probe opens the breaker for another cooldown
+	if !breaker.Failure() {
+		t.Fatal("expected failed probe to keep breaker open")
+	}
+	expectState(BreakerStateOpen)
+
+	clock.Advance(time.Minute)
+	expectState(BreakerStateHalfOpen)
+
+	// a successful probe closes the breaker and resets failures
+	breaker.Success()
+	expectState(BreakerStateClosed)
+
+	if breaker.Failure() || breaker.Failure() || breaker.IsOpen() {
+		t.Fatal("expected failures to be counted from zero after closing")
+	}
+
+	// tripping an open breaker doesn't extend its cooldown
+	breaker.Trip()
+	clock.Advance(30 * time.Second)
+	breaker.Trip()
+	clock.Advance(30 * time.Second)
+	expectState(BreakerStateHalfOpen)
+}
+
+func TestCircuitBreakerWithoutThreshold(t *testing.T) {
+	clock := newFakeClock()
+	breaker := NewCircuitBreaker(0, time.Minute)
+	breaker.now = clock.Now
+
+	for i := 0; i < 100; i++ {
+		if breaker.Failure() {
+			t.Fatal("expected breaker without threshold not to open by failures")
+		}
+	}
+
+	breaker.Trip()
+	if breaker.State() != BreakerStateOpen {
+		t.Fatalf("expected breaker to be opened by Trip, but got %s", breaker.State())
+	}
+}
+
+func TestFetchBlockIsDegradedWhileBreakerIsOpen(t *testing.T) {
+	clock := newFakeClock()
+	breaker := NewCircuitBreaker(2, time.Millisecond)
+	breaker.now = clock.Now
+
+	p, client, _, logs := newScriptedParser(
+		[]error{transientError, transientError, transientError, permanentError},
+		WithCircuitBreaker(breaker),
+	)
+	p.health.now = clock.Now
+
+	// the breaker opens at the second failure, and the failed probes keep it open instead of giving up
+	if _, err := p.fetchBlock(make(chan struct{}), *big.NewInt(1)); err != nil {
+		t.Fatalf("expected parser to keep probing, but got %v", err)
+	}
+
+	if !strings.Contains(logs.String(), "parser is degraded") || !strings.Contains(logs.String(), "parser has recovered") {
+		t.Fatalf("expected parser to be degraded and recovered, but got logs:\n%s", logs)
+	}
+
+	if breaker.State() != BreakerStateClosed || client.blocks != 1 {
+		t.Fatalf("expected closed breaker after the block is fetched, but got %s", breaker.State())
+	}
+
+	health := p.Health()
+	if health.State != HealthStateHealthy || health.ConsecutiveFailures != 0 {
+		t.Fatalf("expected healthy parser, but got %+v", health)
+	}
+
+	if health.LastError != permanentError.Error() || health.LastErrorAt == nil || !health.LastErrorAt.Equal(clock.Now()) {
+		t.Fatalf("expected the last error at %s, but got %+v", clock.Now(), health)
+	}
+}
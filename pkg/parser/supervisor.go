@@ -0,0 +1,408 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRestartDelay       = 1 * time.Second
+	DefaultMaxRestartDelay    = 1 * time.Minute
+	DefaultCrashLoopThreshold = 5
+	DefaultCrashLoopWindow    = 10 * time.Minute
+	// DefaultRestartStopTimeout is the timeout to stop a crashed component before restarting it
+	DefaultRestartStopTimeout = 10 * time.Second
+	// MaxCrashHistory is the number of recent crashes kept for each component
+	MaxCrashHistory = 20
+)
+
+// Component is a background service which reports fatal errors by ErrCh and can be started again after Stop
+type Component interface {
+	Start() error
+	Stop(ctx context.Context) error
+	ErrCh() <-chan error
+}
+
+// ComponentState is the state of a component in Supervisor
+type ComponentState string
+
+const (
+	// ComponentStateRunning means the component has been started
+	ComponentStateRunning ComponentState = "running"
+	// ComponentStateRestarting means the component has crashed and is waiting for restart
+	ComponentStateRestarting ComponentState = "restarting"
+	// ComponentStateFailed means the component has crashed too often and is not restarted anymore
+	ComponentStateFailed ComponentState = "failed"
+	// ComponentStateStopped means the component has been stopped by Supervisor.Stop, or not started yet
+	ComponentStateStopped ComponentState = "stopped"
+)
+
+// Crash is an error which made a component restart
+type Crash struct {
+	At    time.Time `json:"at"`
+	Error string    `json:"error"`
+}
+
+// ComponentStatus is a snapshot of a component in Supervisor
+type ComponentStatus struct {
+	Name     string         `json:"name"`
+	State    ComponentState `json:"state"`
+	Restarts int            `json:"restarts"`
+	// StartedAt is the time of the last successful start
+	StartedAt     *time.Time `json:"startedAt,omitempty"`
+	NextRestartAt *time.Time `json:"nextRestartAt,omitempty"`
+	// Crashes are recent crashes in chronological order, up to MaxCrashHistory
+	Crashes []Crash `json:"crashes"`
+}
+
+// SupervisorOption configures Supervisor
+type SupervisorOption func(*Supervisor)
+
+// WithRestartDelay sets the delay before the first restart, it's doubled for every crash in crash loop window up to max
+func WithRestartDelay(delay, max time.Duration) SupervisorOption {
+	return func(s *Supervisor) {
+		s.backoff.Base = delay
+		s.backoff.Max = max
+	}
+}
+
+// WithCrashLoopThreshold makes Supervisor give up when a component crashes threshold times within window
+// Components are restarted forever if threshold is zero
+func WithCrashLoopThreshold(threshold int, window time.Duration) SupervisorOption {
+	return func(s *Supervisor) {
+		s.crashLoopThreshold = threshold
+		s.crashLoopWindow = window
+	}
+}
+
+// WithSupervisorLogger replaces default logger
+func WithSupervisorLogger(logger *log.Logger) SupervisorOption {
+	return func(s *Supervisor) {
+		s.logger = logger
+	}
+}
+
+// Supervisor restarts components when they report errors, with escalating delays
+// It reports an error by ErrCh only when a component crashes too often, which means a crash loop
+type Supervisor struct {
+	backoff            ExponentialBackoff
+	crashLoopThreshold int
+	crashLoopWindow    time.Duration
+	stopTimeout        time.Duration
+	logger             *log.Logger
+
+	components []*supervisedComponent
+
+	notifyErrCh   chan error
+	notifyCloseCh chan struct{}
+	stopOnce      sync.Once
+	wg            sync.WaitGroup
+}
+
+// supervisedComponent is a component with its restart history
+type supervisedComponent struct {
+	name      string
+	component Component
+
+	mu            sync.Mutex
+	state         ComponentState
+	restarts      int
+	startedAt     time.Time
+	nextRestartAt time.Time
+	crashes       []Crash
+	recentCrashes []time.Time // times of crashes within crash loop window, not limited by MaxCrashHistory
+}
+
+func NewSupervisor(opts ...SupervisorOption) *Supervisor {
+	s := &Supervisor{
+		backoff: ExponentialBackoff{
+			Base: DefaultRestartDelay,
+			Max:  DefaultMaxRestartDelay,
+		},
+		crashLoopThreshold: DefaultCrashLoopThreshold,
+		crashLoopWindow:    DefaultCrashLoopWindow,
+		stopTimeout:        DefaultRestartStopTimeout,
+		logger:             log.Default(),
+
+		notifyErrCh:   make(chan error, 1),
+		notifyCloseCh: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Add registers a component, it must be called before Start
+func (s *Supervisor) Add(name string, component Component) {
+	s.components = append(s.components, &supervisedComponent{
+		name:      name,
+		component: component,
+		state:     ComponentStateStopped,
+	})
+}
+
+// ErrCh returns channel of error which is sent when a component gives up restarting
+func (s *Supervisor) ErrCh() <-chan error {
+	return s.notifyErrCh
+}
+
+// Start starts components in order and watches their errors in background
+// It fails if any component fails to start, components started already keep running until Stop is called
+func (s *Supervisor) Start() error {
+	for _, c := range s.components {
+		if err := c.component.Start(); err != nil {
+			return fmt.Errorf("failed to start %s: %w", c.name, err)
+		}
+
+		c.started()
+
+		s.wg.Add(1)
+		go s.supervise(c)
+	}
+
+	return nil
+}
+
+// Stop stops restarting and then stops all components concurrently
+func (s *Supervisor) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		close(s.notifyCloseCh)
+	})
+
+	// wait for restarts in progress
+	doneCh := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(doneCh)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-doneCh:
+	}
+
+	errCh := make(chan error, len(s.components))
+	for _, c := range s.components {
+		c := c
+		go func() {
+			err := c.component.Stop(ctx)
+			if err != nil {
+				err = fmt.Errorf("failed to stop %s: %w", c.name, err)
+			}
+
+			c.setState(ComponentStateStopped)
+			errCh <- err
+		}()
+	}
+
+	errs := make([]error, 0, len(s.components))
+	for range s.components {
+		errs = append(errs, <-errCh)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Components returns states and crash histories of components in the order they were added
+func (s *Supervisor) Components() []ComponentStatus {
+	statuses := make([]ComponentStatus, len(s.components))
+	for i, c := range s.components {
+		statuses[i] = c.snapshot()
+	}
+
+	return statuses
+}
+
+// supervise restarts the component whenever it reports an error until Stop is called or it gives up
+func (s *Supervisor) supervise(c *supervisedComponent) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.notifyCloseCh:
+			return
+		case err, ok := <-c.component.ErrCh():
+			if !ok {
+				return
+			}
+
+			if !s.restart(c, err) {
+				return
+			}
+		}
+	}
+}
+
+// restart stops the crashed component and starts it again after delay, start failures are handled as crashes
+// It returns false if the component gives up or Stop is called
+func (s *Supervisor) restart(c *supervisedComponent, err error) bool {
+	for {
+		crashes := c.crashed(err, s.crashLoopWindow)
+
+		// stop the crashed run so that its remaining work is finished before restart
+		ctx, cancel := context.WithTimeout(context.Background(), s.stopTimeout)
+		if stopErr := c.component.Stop(ctx); stopErr != nil {
+			s.logger.Printf("failed to stop crashed component, name=%s: %v", c.name, stopErr)
+		}
+		cancel()
+
+		if s.crashLoopThreshold > 0 && crashes >= s.crashLoopThreshold {
+			c.setState(ComponentStateFailed)
+			s.logger.Printf("component is in crash loop, giving up restart, name=%s, crashes=%d", c.name, crashes)
+			s.notifyError(fmt.Errorf("%s crashed %d times in %s: %w", c.name, crashes, s.crashLoopWindow, err))
+
+			return false
+		}
+
+		delay, _ := s.backoff.NextDelay(crashes, err)
+		c.scheduleRestart(time.Now().Add(delay))
+		s.logger.Printf("component has crashed, restart in %s, name=%s: %v", delay.Round(time.Millisecond), c.name, err)
+
+		select {
+		case <-time.After(delay):
+		case <-s.notifyCloseCh:
+			return false
+		}
+
+		// errors reported while stopping belong to the crashed run
+		drainErrors(c.component.ErrCh())
+
+		if err = c.component.Start(); err == nil {
+			c.restarted()
+			s.logger.Printf("component has been restarted, name=%s", c.name)
+
+			return true
+		}
+
+		err = fmt.Errorf("failed to restart: %w", err)
+	}
+}
+
+// notifyError sends error to ErrCh, it's dropped if an error is waiting to be received already
+func (s *Supervisor) notifyError(err error) {
+	select {
+	case s.notifyErrCh <- err:
+	default:
+		s.logger.Printf("error is not notified because another error is pending: %v", err)
+	}
+}
+
+// drainErrors discards errors waiting in the channel
+func drainErrors(errCh <-chan error) {
+	for {
+		select {
+		case <-errCh:
+		default:
+			return
+		}
+	}
+}
+
+// started records a successful start
+func (c *supervisedComponent) started() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state = ComponentStateRunning
+	c.startedAt = time.Now()
+	c.nextRestartAt = time.Time{}
+}
+
+// restarted records a successful restart
+func (c *supervisedComponent) restarted() {
+	c.started()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.restarts++
+}
+
+// crashed records a crash and returns the number of crashes within window including it
+func (c *supervisedComponent) crashed(err error, window time.Duration) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	c.crashes = append(c.crashes, Crash{At: now, Error: err.Error()})
+	if len(c.crashes) > MaxCrashHistory {
+		c.crashes = c.crashes[len(c.crashes)-MaxCrashHistory:]
+	}
+
+	// crashes are in chronological order, drop the ones out of window
+	c.recentCrashes = append(c.recentCrashes, now)
+	for len(c.recentCrashes) > 0 && now.Sub(c.recentCrashes[0]) > window {
+		c.recentCrashes = c.recentCrashes[1:]
+	}
+
+	return len(c.recentCrashes)
+}
+
+// scheduleRestart marks the component restarting at the time
+func (c *supervisedComponent) scheduleRestart(at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state = ComponentStateRestarting
+	c.nextRestartAt = at
+}
+
+// setState changes the state
+func (c *supervisedComponent) setState(state ComponentState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state = state
+	c.nextRestartAt = time.Time{}
+}
+
+// snapshot returns current status
+func (c *supervisedComponent) snapshot() ComponentStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := ComponentStatus{
+		Name:     c.name,
+		State:    c.state,
+		Restarts: c.restarts,
+		Crashes:  append(make([]Crash, 0, len(c.crashes)), c.crashes...),
+	}
+
+	if !c.startedAt.IsZero() {
+		startedAt := c.startedAt
+		status.StartedAt = &startedAt
+	}
+
+	if !c.nextRestartAt.IsZero() {
+		nextRestartAt := c.nextRestartAt
+		status.NextRestartAt = &nextRestartAt
+	}
+
+	return status
+}
+
+// supervisedParser adapts Parser to Component
+type supervisedParser struct {
+	*Parser
+	beginningHeight *big.Int
+}
+
+// Start implements Component, restarts resume from the checkpoint or the block next to the last processed one
+func (p *supervisedParser) Start() error {
+	return p.Parser.Start(p.beginningHeight)
+}
+
+// Supervised returns the parser as Component started from beginningHeight
+func (p *Parser) Supervised(beginningHeight *big.Int) Component {
+	return &supervisedParser{Parser: p, beginningHeight: beginningHeight}
+}